@@ -1,27 +1,67 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/djsam/mcp-gateway-envoy/internal/clients"
 	"github.com/djsam/mcp-gateway-envoy/internal/config"
 	"github.com/djsam/mcp-gateway-envoy/internal/controller"
+	"github.com/djsam/mcp-gateway-envoy/internal/doctor"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/mock"
+	"github.com/djsam/mcp-gateway-envoy/internal/recording"
 	"github.com/djsam/mcp-gateway-envoy/internal/runtime"
+	"github.com/djsam/mcp-gateway-envoy/internal/sandbox"
 )
 
 func main() {
+	// Sandboxed stdio servers start as this binary; see internal/sandbox.
+	sandbox.Init()
 	if err := run(os.Args[1:]); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			if exit.err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", exit.err)
+			}
+			os.Exit(exit.code)
+		}
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// exitError makes the process exit with a specific status; err, if set, is
+// printed first.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 func run(args []string) error {
 	if len(args) == 0 {
 		printUsage()
@@ -33,14 +73,36 @@ func run(args []string) error {
 		return runInit(args[1:])
 	case "validate":
 		return runValidate(args[1:])
+	case "schema":
+		return runSchema(args[1:])
+	case "migrate":
+		return runMigrate(args[1:])
 	case "plan":
 		return runPlan(args[1:])
 	case "render":
 		return runRender(args[1:])
+	case "diff":
+		return runDiff(args[1:])
 	case "apply":
 		return runApply(args[1:])
+	case "reconcile":
+		return runReconcile(args[1:])
 	case "serve":
 		return runServe(args[1:])
+	case "adapt":
+		return runAdapt(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "doctor":
+		return runDoctor(args[1:])
+	case "call":
+		return runCall(args[1:])
+	case "mock":
+		return runMock(args[1:])
+	case "replay":
+		return runReplay(args[1:])
 	case "help", "-h", "--help":
 		printUsage()
 		return nil
@@ -82,18 +144,134 @@ func runInit(args []string) error {
 func runValidate(args []string) error {
 	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
 	file := fs.String("file", "gateway.yaml", "path to config file")
+	output := fs.String("output", "text", "output format: text or json")
+	showResolved := fs.Bool("show-resolved", false, "print the config with ${...} references resolved and secrets masked")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
 
-	cfg, err := config.LoadFile(*file)
+	cfg, issues := config.CheckFile(*file)
+	errs := issues.Errors()
+	var resolved []byte
+	if *showResolved {
+		// Read and interpolation problems are already among issues.
+		resolved, _ = config.ResolvedFile(*file)
+	}
+
+	if *output == "json" {
+		if issues == nil {
+			issues = config.Issues{}
+		}
+		report := struct {
+			File     string        `json:"file"`
+			Valid    bool          `json:"valid"`
+			Issues   config.Issues `json:"issues"`
+			Resolved string        `json:"resolved,omitempty"`
+		}{*file, len(errs) == 0, issues, string(resolved)}
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			pos := issue.File
+			if pos == "" {
+				pos = *file
+			}
+			if issue.Line > 0 {
+				pos += fmt.Sprintf(":%d", issue.Line)
+				if issue.Column > 0 {
+					pos += fmt.Sprintf(":%d", issue.Column)
+				}
+			}
+			msg := issue.Message
+			if issue.Path != "" {
+				msg += " (" + issue.Path + ")"
+			}
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", pos, issue.Severity, msg)
+			if issue.Hint != "" {
+				fmt.Fprintf(os.Stderr, "    hint: %s\n", issue.Hint)
+			}
+		}
+		if resolved != nil {
+			os.Stdout.Write(resolved)
+		}
+		if len(errs) == 0 && resolved == nil {
+			fmt.Printf("config is valid: %s\n", *file)
+			fmt.Printf("gateway=%s servers=%d routes=%d secureDefault=%t\n",
+				cfg.Gateway.Name, len(cfg.Servers), len(cfg.Routes), cfg.Auth.RequireAuth)
+		} else if len(errs) > 0 {
+			fmt.Fprintf(os.Stderr, "%s, %s\n", plural(len(errs), "error"), plural(len(issues)-len(errs), "warning"))
+		}
+	}
+	if len(errs) > 0 {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := fs.String("output", "", "write the schema to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	b, err := config.Schema()
 	if err != nil {
 		return err
 	}
+	if *output == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	if err := os.WriteFile(*output, b, 0o644); err != nil {
+		return fmt.Errorf("write schema: %w", err)
+	}
+	fmt.Printf("wrote %s\n", *output)
+	return nil
+}
+
+// runMigrate rewrites config files, and the files they include, to the
+// latest apiVersion.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "path to config file or directory")
+	dryRun := fs.Bool("dry-run", false, "print the changes and migrated files instead of writing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
 
-	fmt.Printf("config is valid: %s\n", *file)
-	fmt.Printf("gateway=%s servers=%d routes=%d secureDefault=%t\n",
-		cfg.Gateway.Name, len(cfg.Servers), len(cfg.Routes), cfg.Auth.RequireAuth)
+	files, err := config.MigrateFiles(*file)
+	if err != nil {
+		return err
+	}
+	changed := 0
+	for _, f := range files {
+		if len(f.Changes) == 0 {
+			continue
+		}
+		changed++
+		for _, c := range f.Changes {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", f.Path, c.Line, c.Message)
+		}
+		if *dryRun {
+			fmt.Printf("# %s\n%s", f.Path, f.Data)
+			continue
+		}
+		if err := os.WriteFile(f.Path, f.Data, 0o644); err != nil {
+			return fmt.Errorf("write config: %w", err)
+		}
+	}
+	if changed == 0 {
+		fmt.Printf("%s is already at %s\n", *file, config.LatestAPIVersion)
+		return nil
+	}
+	if !*dryRun {
+		fmt.Printf("migrated %s to %s\n", plural(changed, "file"), config.LatestAPIVersion)
+		fmt.Println("next: gateway validate --file", *file)
+	}
 	return nil
 }
 
@@ -130,7 +308,8 @@ func runRender(args []string) error {
 	file := fs.String("file", "gateway.yaml", "path to config file")
 	namespace := fs.String("namespace", "mcp-gateway", "kubernetes namespace")
 	image := fs.String("image", "ghcr.io/dsampath/mcp-gateway-envoy:latest", "gateway container image")
-	output := fs.String("output", "", "optional output path (default stdout)")
+	format := fs.String("format", "yaml", "output format: yaml (flat manifests) or helm (chart directory)")
+	output := fs.String("output", "", "optional output path (default stdout; required for helm)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -139,6 +318,23 @@ func runRender(args []string) error {
 	if err != nil {
 		return err
 	}
+
+	switch *format {
+	case "yaml":
+	case "helm":
+		if *output == "" {
+			return errors.New("render --format helm requires --output DIR")
+		}
+		if err := controller.WriteHelmChart(cfg, *image, *output); err != nil {
+			return err
+		}
+		fmt.Printf("wrote helm chart: %s\n", *output)
+		fmt.Printf("next: helm install %s %s --namespace %s --create-namespace\n", cfg.Gateway.Name, *output, *namespace)
+		return nil
+	default:
+		return fmt.Errorf("unknown render format %q", *format)
+	}
+
 	manifest, err := controller.RenderManifests(cfg, *namespace, *image)
 	if err != nil {
 		return err
@@ -155,6 +351,202 @@ func runRender(args []string) error {
 	return nil
 }
 
+// runDiff exits 0 when there are no differences, 1 when there are and 2 on
+// error, so CI can gate on it.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "path to config file")
+	namespace := fs.String("namespace", "mcp-gateway", "kubernetes namespace")
+	image := fs.String("image", "ghcr.io/dsampath/mcp-gateway-envoy:latest", "gateway container image")
+	against := fs.String("against", "", "previously rendered manifest file to compare with")
+	rev := fs.String("rev", "", "git revision of the config file to compare with")
+	live := fs.Bool("live", false, "compare with the objects in the cluster")
+	kubeconfig := fs.String("kubeconfig", "", "path to kubeconfig for --live")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return &exitError{code: 2, err: err}
+	}
+	diffs, err := diffManifests(*file, *namespace, *image, *against, *rev, *live, *kubeconfig)
+	if err != nil {
+		return &exitError{code: 2, err: err}
+	}
+
+	switch *output {
+	case "text":
+		controller.WriteDiff(os.Stdout, diffs)
+	case "json":
+		if diffs == nil {
+			diffs = []controller.ResourceDiff{}
+		}
+		b, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return &exitError{code: 2, err: err}
+		}
+		fmt.Println(string(b))
+	default:
+		return &exitError{code: 2, err: fmt.Errorf("unknown output format %q", *output)}
+	}
+	if len(diffs) > 0 {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+func diffManifests(file, namespace, image, against, rev string, live bool, kubeconfig string) ([]controller.ResourceDiff, error) {
+	sources := 0
+	for _, set := range []bool{against != "", rev != "", live} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, errors.New("diff needs exactly one of --against, --rev or --live")
+	}
+
+	cfg, err := config.LoadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if live {
+		kubeCfg, err := kube.LoadConfig(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		client, err := kube.NewClient(kubeCfg)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return controller.DiffLive(ctx, client, cfg, namespace, image)
+	}
+
+	manifest, err := controller.RenderManifests(cfg, namespace, image)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := controller.ParseManifests(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []byte
+	if against != "" {
+		if previous, err = os.ReadFile(against); err != nil {
+			return nil, fmt.Errorf("read manifest: %w", err)
+		}
+	} else {
+		oldCfg, err := loadRevision(file, rev)
+		if err != nil {
+			return nil, err
+		}
+		if previous, err = controller.RenderManifests(oldCfg, namespace, image); err != nil {
+			return nil, err
+		}
+	}
+	current, err := controller.ParseManifests(previous)
+	if err != nil {
+		return nil, err
+	}
+	return controller.DiffObjects(current, desired), nil
+}
+
+// loadRevision loads the config at file as of a git revision. The
+// revision's tree is checked out into a temporary directory so includes,
+// config directories and ${file:} references resolve as they did then.
+func loadRevision(file, rev string) (*config.Config, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(filepath.Dir(abs), "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	prefix := ""
+	if len(lines) > 1 {
+		prefix = lines[1]
+	}
+	archive, err := git(lines[0], "archive", "--format=tar", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "gateway-diff-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := extractTar(archive, tmp); err != nil {
+		return nil, fmt.Errorf("check out %s: %w", rev, err)
+	}
+	cfg, err := config.LoadFile(filepath.Join(tmp, filepath.FromSlash(prefix), filepath.Base(abs)))
+	if err != nil {
+		return nil, fmt.Errorf("config at %s: %w", rev, err)
+	}
+	return cfg, nil
+}
+
+// git runs a git command in dir and returns its output, with git's own
+// message as the error.
+func git(dir string, args ...string) ([]byte, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exit.Stderr)))
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// extractTar writes the files and symlinks of a tar archive under dir.
+func extractTar(archive []byte, dir string) error {
+	r := tar.NewReader(bytes.NewReader(archive))
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(h.Name))
+		if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s is outside the tree", h.Name)
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o755)
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+				err = writeTarFile(r, target, h.FileInfo().Mode())
+			}
+		case tar.TypeSymlink:
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+				err = os.Symlink(h.Linkname, target)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeTarFile(r io.Reader, path string, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func runApply(args []string) error {
 	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
 	file := fs.String("file", "gateway.yaml", "path to config file")
@@ -188,9 +580,63 @@ func runApply(args []string) error {
 	return nil
 }
 
+func runReconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "path to config file")
+	namespace := fs.String("namespace", "mcp-gateway", "kubernetes namespace")
+	image := fs.String("image", "ghcr.io/dsampath/mcp-gateway-envoy:latest", "gateway container image")
+	kubeconfig := fs.String("kubeconfig", "", "path to kubeconfig (default $KUBECONFIG, in-cluster, then ~/.kube/config)")
+	fieldManager := fs.String("field-manager", controller.DefaultFieldManager, "server-side apply field manager")
+	interval := fs.Duration("interval", 30*time.Second, "time between reconcile passes")
+	once := fs.Bool("once", false, "reconcile a single time and exit")
+	prune := fs.Bool("prune", false, "delete owned objects that are no longer rendered")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	kubeCfg, err := kube.LoadConfig(*kubeconfig)
+	if err != nil {
+		return err
+	}
+	client, err := kube.NewClient(kubeCfg)
+	if err != nil {
+		return err
+	}
+	r := &controller.Reconciler{
+		Client:       client,
+		FieldManager: *fieldManager,
+		Namespace:    *namespace,
+		Image:        *image,
+		Prune:        *prune,
+	}
+	load := func() (*config.Config, error) { return config.LoadFile(*file) }
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if !*once {
+		return r.Run(ctx, load, *interval)
+	}
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+	result, err := r.Reconcile(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
 func runServe(args []string) error {
 	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
-	file := fs.String("file", "gateway.yaml", "path to config file")
+	file := fs.String("file", "gateway.yaml", "path to config file or directory")
+	reloadInterval := fs.Duration("reload-interval", 5*time.Second, "how often to check config files for changes; 0 disables reload")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -199,18 +645,631 @@ func runServe(args []string) error {
 	if err != nil {
 		return err
 	}
+	logConfigWarnings(cfg)
+	if err := cfg.ReadAPIKeyFiles(); err != nil {
+		return err
+	}
+	s := runtime.NewServer(cfg)
+	if *reloadInterval > 0 {
+		go config.Watch(context.Background(), *file, *reloadInterval, func(cfg *config.Config, err error) {
+			if err == nil {
+				err = cfg.ReadAPIKeyFiles()
+			}
+			if err != nil {
+				log.Printf("config_reload_failed file=%s err=%q", *file, err)
+				return
+			}
+			logConfigWarnings(cfg)
+			s.Reload(cfg)
+		})
+	}
+	return s.ListenAndServe()
+}
+
+func logConfigWarnings(cfg *config.Config) {
+	for _, w := range cfg.Check().Warnings() {
+		log.Printf("config_warning path=%s msg=%q", w.Path, w.Message)
+	}
+}
+
+// runAdapt serves a single stdio MCP server over streamable HTTP. Rendered
+// Kubernetes manifests run it inside the stdio server's own image, after an
+// init container has copied this binary in with --install.
+func runAdapt(args []string) error {
+	fs := flag.NewFlagSet("adapt", flag.ContinueOnError)
+	listen := fs.String("listen", ":8080", "address to serve streamable HTTP on")
+	name := fs.String("name", "stdio", "server name used in logs and metrics")
+	install := fs.String("install", "", "copy the gateway binary into this directory and exit")
+	probe := fs.String("probe", "", "GET this URL and exit non-zero unless it answers 200 (readiness probe for localhost adapters)")
+	var credentials []config.Credential
+	fs.Func("credential", "pass a request value to each session's process, e.g. header:X-Token=env:TOKEN (repeatable)", func(v string) error {
+		c, err := config.ParseCredential(v)
+		credentials = append(credentials, c)
+		return err
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *install != "" {
+		return installBinary(*install)
+	}
+	if *probe != "" {
+		return probeURL(*probe)
+	}
+	if fs.NArg() == 0 {
+		return errors.New("adapt needs the stdio server command after --")
+	}
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: *name + "-adapter", ListenAddr: *listen},
+		Servers: []config.Server{{Name: *name, Transport: "stdio", Command: fs.Arg(0), Args: fs.Args()[1:], Credentials: credentials}},
+		// The gateway in front of the adapter enforces route auth and policy;
+		// "/" accepts whatever route path it forwards.
+		Routes: []config.Route{{Name: *name, Path: "/", Server: *name}},
+	}
 	return runtime.NewServer(cfg).ListenAndServe()
 }
 
+// runImport converts a desktop client's MCP server config into gateway
+// servers and routes, merged into --file (created if missing).
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	from := fs.String("from", "", "client config: claude_desktop_config.json, .vscode/mcp.json or .cursor/mcp.json")
+	file := fs.String("file", "gateway.yaml", "gateway config to merge into (created if missing)")
+	dryRun := fs.Bool("dry-run", false, "print the resulting config instead of writing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return errors.New("import requires --from FILE")
+	}
+
+	b, err := os.ReadFile(*from)
+	if err != nil {
+		return fmt.Errorf("read client config: %w", err)
+	}
+	imported, err := clients.Import(b)
+	if err != nil {
+		return err
+	}
+	base, err := os.ReadFile(*file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read config: %w", err)
+	}
+	out, added, warnings, err := clients.Merge(base, imported)
+	if err != nil {
+		return err
+	}
+	for _, w := range append(imported.Warnings, warnings...) {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	if _, err := config.Load(out); err != nil {
+		return fmt.Errorf("imported config does not validate: %w", err)
+	}
+
+	if *dryRun {
+		fmt.Print(string(out))
+		return nil
+	}
+	if err := os.WriteFile(*file, out, 0o644); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	fmt.Printf("imported %d servers into %s\n", added, *file)
+	fmt.Println("next: gateway validate --file", *file)
+	return nil
+}
+
+// runExport writes client config for every route, pointing at the gateway's
+// public address with placeholder secrets.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "path to config file")
+	client := fs.String("client", "", "client format: "+strings.Join(clients.Clients, ", "))
+	baseURL := fs.String("base-url", "", "public gateway URL (default http://localhost:<listen port>)")
+	output := fs.String("output", "", "optional output path (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *client == "" {
+		return fmt.Errorf("export requires --client (%s)", strings.Join(clients.Clients, ", "))
+	}
+
+	cfg, err := config.LoadFile(*file)
+	if err != nil {
+		return err
+	}
+	if *baseURL == "" {
+		if *baseURL, err = localGatewayURL(cfg); err != nil {
+			return fmt.Errorf("export needs --base-url: %w", err)
+		}
+	}
+	out, warnings, err := clients.Export(cfg, *client, *baseURL)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+
+	if *output == "" {
+		fmt.Print(string(out))
+		return nil
+	}
+	if err := os.WriteFile(*output, out, 0o644); err != nil {
+		return fmt.Errorf("write client config: %w", err)
+	}
+	fmt.Printf("wrote %s client config: %s\n", *client, *output)
+	return nil
+}
+
+// runDoctor checks each server of the config and each route through a
+// running gateway, exiting 1 when a check fails.
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "path to config file")
+	gatewayURL := fs.String("gateway-url", "", "running gateway to check routes through (default http://localhost:<listen port>)")
+	skipRoutes := fs.Bool("skip-routes", false, "only check servers")
+	apiKey := fs.String("api-key", os.Getenv("MCP_GATEWAY_API_KEY"), "API key for apiKey routes (default $MCP_GATEWAY_API_KEY, else the route's first key)")
+	token := fs.String("token", os.Getenv("MCP_GATEWAY_TOKEN"), "bearer token for jwt routes (default $MCP_GATEWAY_TOKEN)")
+	timeout := fs.Duration("timeout", 10*time.Second, "time allowed for each server and route")
+	output := fs.String("output", "text", "output format: text or json")
+	header := headerFlag(fs, "header sent on route checks, e.g. X-GitHub-Token:VALUE (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unsupported output %q (use text or json)", *output)
+	}
+
+	cfg, err := config.LoadFile(*file)
+	if err != nil {
+		return err
+	}
+	if err := cfg.ReadAPIKeyFiles(); err != nil {
+		return err
+	}
+	if *skipRoutes {
+		*gatewayURL = ""
+	} else if *gatewayURL == "" {
+		if *gatewayURL, err = localGatewayURL(cfg); err != nil {
+			return fmt.Errorf("doctor needs --gateway-url: %w", err)
+		}
+	}
+
+	report := doctor.Run(context.Background(), cfg, doctor.Options{
+		GatewayURL: *gatewayURL,
+		APIKey:     *apiKey,
+		Token:      *token,
+		Header:     header,
+		Timeout:    *timeout,
+	})
+	if *output == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+// runMock serves a mock MCP server from a YAML definition, over streamable
+// HTTP or stdio.
+func runMock(args []string) error {
+	fs := flag.NewFlagSet("mock", flag.ContinueOnError)
+	file := fs.String("file", "", "mock definition (default: a server with an echo tool)")
+	listen := fs.String("listen", ":8000", "address to serve streamable HTTP on")
+	stdio := fs.Bool("stdio", false, "serve on stdin and stdout instead of HTTP")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := mock.Default
+	if *file != "" {
+		loaded, err := mock.LoadFile(*file)
+		if err != nil {
+			return err
+		}
+		cfg = *loaded
+	}
+	server, err := mock.New(cfg)
+	if err != nil {
+		return err
+	}
+	if *stdio {
+		return server.ServeStdio(context.Background(), os.Stdin, os.Stdout)
+	}
+	log.Printf("mock %q listening on %s", cfg.Name, *listen)
+	httpServer := &http.Server{Addr: *listen, Handler: server, ReadHeaderTimeout: 5 * time.Second}
+	return httpServer.ListenAndServe()
+}
+
+// runReplay resends a route recording against an upstream and diffs the
+// responses, or serves it as a fake upstream.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "config holding --server")
+	recordingFile := fs.String("recording", "", "recording written by a route's record block")
+	routeName := fs.String("route", "", "only replay this route's entries")
+	upstreamURL := fs.String("url", "", "upstream to replay against: a streamable HTTP or ws:// endpoint")
+	serverName := fs.String("server", "", "upstream to replay against: a server of --file")
+	serve := fs.String("serve", "", "serve the recording as a fake upstream on this address instead")
+	timing := fs.Bool("timing", false, "with --serve, delay responses by their recorded latency")
+	timeout := fs.Duration("timeout", time.Minute, "time allowed for the whole replay")
+	output := fs.String("output", "text", "output format: text or json")
+	var ignore []string
+	fs.Func("ignore", "object key to leave out of the comparison, e.g. timestamp (repeatable)", func(v string) error {
+		ignore = append(ignore, v)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *recordingFile == "" {
+		return errors.New("replay requires --recording FILE")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unsupported output %q (use text or json)", *output)
+	}
+	entries, err := recording.ReadFile(*recordingFile)
+	if err != nil {
+		return err
+	}
+	if *routeName != "" {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.Route == *routeName {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("%s has no entries to replay", *recordingFile)
+	}
+
+	if *serve != "" {
+		upstream := recording.NewUpstream(entries)
+		upstream.Timing = *timing
+		log.Printf("replaying %s as an upstream on %s", *recordingFile, *serve)
+		httpServer := &http.Server{Addr: *serve, Handler: upstream, ReadHeaderTimeout: 5 * time.Second}
+		return httpServer.ListenAndServe()
+	}
+
+	var dial func(context.Context) (mcp.Transport, error)
+	switch {
+	case *upstreamURL != "" && *serverName != "":
+		return errors.New("replay takes --url or --server, not both")
+	case *upstreamURL != "":
+		dial = func(ctx context.Context) (mcp.Transport, error) {
+			if !strings.HasPrefix(*upstreamURL, "ws://") && !strings.HasPrefix(*upstreamURL, "wss://") {
+				return mcp.NewHTTPTransport(*upstreamURL, nil, nil), nil
+			}
+			t, err := mcp.DialWebSocket(ctx, *upstreamURL, nil, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			return t, nil
+		}
+	case *serverName != "":
+		cfg, err := config.LoadFile(*file)
+		if err != nil {
+			return err
+		}
+		var server *config.Server
+		for i := range cfg.Servers {
+			if cfg.Servers[i].Name == *serverName {
+				server = &cfg.Servers[i]
+			}
+		}
+		if server == nil {
+			return fmt.Errorf("unknown server %q", *serverName)
+		}
+		// http upstreams are reached at their URL plus the recorded route's
+		// upstream path.
+		routePath := ""
+		if route, err := clients.FindRoute(cfg, entries[0].Route); err == nil {
+			routePath = route.UpstreamPath(route.Path)
+		}
+		dial = func(ctx context.Context) (mcp.Transport, error) {
+			return runtime.DialUpstream(ctx, cfg.Gateway, *server, routePath, nil)
+		}
+	default:
+		return errors.New("replay needs --url, --server or --serve")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	result, err := recording.Replay(ctx, entries, dial, ignore)
+	if err != nil {
+		return err
+	}
+	if *output == "json" {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	} else {
+		for _, d := range result.Differences {
+			fmt.Println(d)
+		}
+		fmt.Printf("replayed %d sessions, %d requests: %d differences\n", result.Sessions, result.Requests, len(result.Differences))
+	}
+	if len(result.Differences) > 0 {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+// runCall invokes a method, or a tool, through a route the way an agent
+// would: initialize, then the request, printing the result.
+func runCall(args []string) error {
+	fs := flag.NewFlagSet("call", flag.ContinueOnError)
+	file := fs.String("file", "gateway.yaml", "path to config file")
+	routeName := fs.String("route", "", "route to call through")
+	gatewayURL := fs.String("gateway-url", "", "running gateway (default http://localhost:<listen port>)")
+	transport := fs.String("transport", "", "client transport: http, sse or websocket (default: the first the route accepts)")
+	apiKey := fs.String("api-key", os.Getenv("MCP_GATEWAY_API_KEY"), "API key for apiKey routes (default $MCP_GATEWAY_API_KEY, else the route's first key)")
+	token := fs.String("token", os.Getenv("MCP_GATEWAY_TOKEN"), "bearer token for jwt routes (default $MCP_GATEWAY_TOKEN)")
+	header := headerFlag(fs, "extra request header, e.g. X-GitHub-Token:VALUE (repeatable)")
+	params := fs.String("params", "", "JSON params for METHOD")
+	argsJSON := fs.String("args", "", "JSON object of tool arguments")
+	arguments := map[string]any{}
+	fs.Func("arg", "tool argument KEY=VALUE; VALUE is parsed as JSON when it can be (repeatable)", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("arg %q must be KEY=VALUE", v)
+		}
+		var parsed any
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			parsed = value
+		}
+		arguments[key] = parsed
+		return nil
+	})
+	timeout := fs.Duration("timeout", 30*time.Second, "time allowed for the whole exchange")
+	output := fs.String("output", "pretty", "output format: pretty or json")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *routeName == "" || len(positional) == 0 {
+		return errors.New("usage: gateway call --route NAME METHOD [--params JSON] | tool NAME [--arg KEY=VALUE ...]")
+	}
+	method := positional[0]
+	if method == "tool" {
+		if len(positional) != 2 {
+			return errors.New("call tool needs exactly one tool name")
+		}
+		if *argsJSON != "" {
+			if err := json.Unmarshal([]byte(*argsJSON), &arguments); err != nil {
+				return fmt.Errorf("--args must be a JSON object: %w", err)
+			}
+		}
+	} else if len(positional) != 1 {
+		return fmt.Errorf("unexpected arguments after %s: %s", method, strings.Join(positional[1:], " "))
+	}
+	if *output != "pretty" && *output != "json" {
+		return fmt.Errorf("unsupported output %q (use pretty or json)", *output)
+	}
+
+	cfg, err := config.LoadFile(*file)
+	if err != nil {
+		return err
+	}
+	if err := cfg.ReadAPIKeyFiles(); err != nil {
+		return err
+	}
+	route, err := clients.FindRoute(cfg, *routeName)
+	if err != nil {
+		return err
+	}
+	if *gatewayURL == "" {
+		if *gatewayURL, err = localGatewayURL(cfg); err != nil {
+			return fmt.Errorf("call needs --gateway-url: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := clients.DialRoute(ctx, cfg, route, *gatewayURL, *transport,
+		clients.Credentials{APIKey: *apiKey, Token: *token, Header: header}, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := mcp.NewClient(conn)
+	// Progress and log notifications stream in while the call runs.
+	client.OnMessage = func(m mcp.Message) {
+		if m.IsNotification() {
+			fmt.Fprintf(os.Stderr, "%s %s\n", m.Method, m.Params)
+		}
+	}
+	initResult, err := client.Initialize(ctx, "mcp-gateway-call", "")
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	var result any
+	switch method {
+	case "initialize":
+		result = initResult
+	case "tool":
+		res, err := client.CallTool(ctx, positional[1], arguments)
+		if err != nil {
+			return err
+		}
+		if *output == "pretty" {
+			printToolResult(res)
+		} else if err := printJSON(res); err != nil {
+			return err
+		}
+		if res.IsError {
+			return &exitError{code: 1}
+		}
+		return nil
+	default:
+		var p any
+		if *params != "" {
+			if err := json.Unmarshal([]byte(*params), &p); err != nil {
+				return fmt.Errorf("--params must be JSON: %w", err)
+			}
+		}
+		raw, err := client.Call(ctx, method, p)
+		if err != nil {
+			return err
+		}
+		result = raw
+	}
+	return printJSON(result)
+}
+
+// printToolResult prints text content as-is and other content as JSON.
+func printToolResult(res mcp.ToolResult) {
+	for _, raw := range res.Content {
+		var block struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			MimeType string `json:"mimeType"`
+			Data     string `json:"data"`
+		}
+		_ = json.Unmarshal(raw, &block)
+		switch block.Type {
+		case "text":
+			fmt.Println(block.Text)
+		case "image", "audio":
+			fmt.Printf("[%s %s, %d base64 bytes]\n", block.Type, block.MimeType, len(block.Data))
+		default:
+			_ = printJSON(raw)
+		}
+	}
+	if len(res.StructuredContent) > 0 {
+		_ = printJSON(res.StructuredContent)
+	}
+	if res.IsError {
+		fmt.Fprintln(os.Stderr, "tool reported an error")
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func printJSON(v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// parseInterspersed parses flags that may follow positional arguments, as
+// in "call --route fs tool read_file --arg path=/tmp/x", and returns the
+// positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// headerFlag registers a repeatable NAME:VALUE header flag.
+func headerFlag(fs *flag.FlagSet, usage string) http.Header {
+	header := http.Header{}
+	fs.Func("header", usage, func(v string) error {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("header %q must be NAME:VALUE", v)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		return nil
+	})
+	return header
+}
+
+// localGatewayURL is the gateway's address on this machine.
+func localGatewayURL(cfg *config.Config) (string, error) {
+	_, port, err := net.SplitHostPort(cfg.Gateway.ListenAddr)
+	if err != nil {
+		return "", err
+	}
+	return "http://localhost:" + port, nil
+}
+
+func installBinary(dir string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate gateway binary: %w", err)
+	}
+	b, err := os.ReadFile(self)
+	if err != nil {
+		return fmt.Errorf("read gateway binary: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create install directory: %w", err)
+	}
+	target := filepath.Join(dir, "gateway")
+	if err := os.WriteFile(target, b, 0o755); err != nil {
+		return fmt.Errorf("install gateway binary: %w", err)
+	}
+	fmt.Printf("installed %s\n", target)
+	return nil
+}
+
+// probeURL reports an error unless url answers 200 within two seconds.
+func probeURL(url string) error {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("probe: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("probe: %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
 func printUsage() {
 	fmt.Print(`mcp-gateway-envoy
 
 Usage:
   gateway init [--output gateway.yaml] [--force]
-  gateway validate [--file gateway.yaml]
+  gateway validate [--file gateway.yaml|DIR] [--output text|json] [--show-resolved]
+  gateway schema [--output gateway.schema.json]
+  gateway migrate [--file gateway.yaml|DIR] [--dry-run]
   gateway plan [--file gateway.yaml]
-  gateway render [--file gateway.yaml] [--namespace mcp-gateway] [--image IMAGE] [--output manifests.yaml]
+  gateway render [--file gateway.yaml] [--namespace mcp-gateway] [--image IMAGE] [--format yaml|helm] [--output manifests.yaml|DIR]
+  gateway diff [--file gateway.yaml] (--against manifests.yaml | --rev REV | --live [--kubeconfig PATH]) [--output text|json]
   gateway apply [--file gateway.yaml] [--namespace mcp-gateway] [--image IMAGE] [--dry-run]
-  gateway serve [--file gateway.yaml]
+  gateway reconcile [--file gateway.yaml] [--namespace mcp-gateway] [--image IMAGE] [--kubeconfig PATH] [--field-manager NAME] [--interval 30s] [--once] [--prune]
+  gateway serve [--file gateway.yaml|DIR] [--reload-interval 5s]
+  gateway import --from CLIENT_CONFIG [--file gateway.yaml] [--dry-run]
+  gateway export --client claude|vscode|cursor|codex [--file gateway.yaml] [--base-url URL] [--output FILE]
+  gateway doctor [--file gateway.yaml] [--gateway-url URL | --skip-routes] [--api-key KEY] [--token TOKEN] [--header NAME:VALUE ...] [--timeout 10s] [--output text|json]
+  gateway call --route NAME [--file gateway.yaml] [--gateway-url URL] [--transport http|sse|websocket] [--api-key KEY] [--token TOKEN] [--header NAME:VALUE ...] [--output pretty|json] (METHOD [--params JSON] | tool NAME [--arg KEY=VALUE ...] [--args JSON])
+  gateway mock [--file mock.yaml] [--listen :8000 | --stdio]
+  gateway replay --recording FILE (--url URL | --server NAME [--file gateway.yaml]) [--route NAME] [--ignore KEY ...] [--output text|json]
+  gateway replay --recording FILE --serve :8000 [--timing]
+  gateway adapt [--listen :8080] [--name NAME] [--credential FROM=TO ...] -- COMMAND [ARGS...]
+  gateway adapt --probe URL
 `)
 }
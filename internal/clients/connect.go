@@ -0,0 +1,135 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+// ErrNoCredential is returned by DialRoute when the route's auth needs a
+// credential that was not supplied.
+var ErrNoCredential = errors.New("no credential supplied")
+
+// Credentials authenticate a connection to a gateway route.
+type Credentials struct {
+	// APIKey is sent on apiKey routes. The route's first configured key is
+	// used when it is empty.
+	APIKey string
+	// Token is sent as a bearer token on jwt routes.
+	Token string
+	// Header is sent as-is, for example values a stdio server reads from
+	// the session's request.
+	Header http.Header
+}
+
+// Connection is an MCP transport to a route through the gateway.
+type Connection struct {
+	mcp.Transport
+	// ClientTransport is the transport used: http, sse or websocket.
+	ClientTransport string
+	// Auth is the route's auth type.
+	Auth string
+}
+
+// DialRoute connects to route r of cfg through the gateway at baseURL,
+// the way a client configured by Export would. transport picks the client
+// transport; empty uses the first the route accepts of http, sse and
+// websocket. When baseURL's host is not one of r's hosts, requests ask for
+// r's ClientHost instead.
+func DialRoute(ctx context.Context, cfg *config.Config, r config.Route, baseURL, transport string, creds Credentials, client *http.Client) (*Connection, error) {
+	header := creds.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	auth := cfg.EffectiveAuth(r)
+	switch auth.Type {
+	case "apiKey":
+		key := creds.APIKey
+		if key == "" && len(auth.APIKeys) > 0 {
+			key = auth.APIKeys[0]
+		}
+		if key == "" {
+			return nil, fmt.Errorf("route %s needs an API key: %w", r.Name, ErrNoCredential)
+		}
+		header.Set(auth.HeaderName, key)
+	case "jwt":
+		if creds.Token == "" {
+			return nil, fmt.Errorf("route %s needs a bearer token: %w", r.Name, ErrNoCredential)
+		}
+		header.Set("Authorization", "Bearer "+creds.Token)
+	}
+
+	if transport == "" {
+		for _, t := range []string{"http", "sse", "websocket"} {
+			if r.AcceptsClientTransport(t) {
+				transport = t
+				break
+			}
+		}
+	} else if !r.AcceptsClientTransport(transport) {
+		return nil, fmt.Errorf("route %s does not accept %s clients", r.Name, transport)
+	}
+
+	if host, ok := routeHost(r, baseURL); ok {
+		header.Set("Host", host)
+	}
+	base := strings.TrimSuffix(baseURL, "/")
+	conn := &Connection{ClientTransport: transport, Auth: auth.Type}
+	var err error
+	switch transport {
+	case "http":
+		conn.Transport = mcp.NewHTTPTransport(base+r.Path, client, header)
+	case "sse":
+		conn.Transport, err = mcp.DialSSE(ctx, base+strings.TrimSuffix(r.Path, "/")+"/sse", client, header)
+	case "websocket":
+		conn.Transport, err = mcp.DialWebSocket(ctx, "ws"+strings.TrimPrefix(base, "http")+r.Path, header, 0, 0)
+	default:
+		return nil, fmt.Errorf("unsupported client transport %q", transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// routeHost returns the host to ask for r at baseURL when baseURL's own
+// host is not one r serves: r's ClientHost, with baseURL's port.
+func routeHost(r config.Route, baseURL string) (string, bool) {
+	u, err := url.Parse(baseURL)
+	if err != nil || r.MatchHost(u.Host) >= 0 {
+		return "", false
+	}
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(r.ClientHost(), port), true
+	}
+	return r.ClientHost(), true
+}
+
+// withHost returns baseURL with its host replaced.
+func withHost(baseURL, host string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	u.Host = host
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// FindRoute returns the route of cfg named name.
+func FindRoute(cfg *config.Config, name string) (config.Route, error) {
+	names := make([]string, 0, len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		if r.Name == name {
+			return r, nil
+		}
+		names = append(names, r.Name)
+	}
+	return config.Route{}, fmt.Errorf("unknown route %q (routes: %s)", name, strings.Join(names, ", "))
+}
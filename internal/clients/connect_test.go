@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/runtime"
+)
+
+func TestDialRouteCallsToolThroughGateway(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		msg, err := mcp.Parse(b)
+		if err != nil || !msg.IsRequest() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if msg.Method != "tools/call" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":%q,"capabilities":{"tools":{}}}}`, msg.ID, mcp.ProtocolVersion)
+			return
+		}
+		// Stream a progress notification ahead of the result.
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progress\":1}}\n\n")
+		w.(http.Flusher).Flush()
+		fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"content\":[{\"type\":\"text\",\"text\":\"echo %s\"}]}}\n\n",
+			msg.ID, r.Header.Get("X-Team-Key"))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "echo", Transport: "http", URL: upstream.URL}},
+		Routes: []config.Route{
+			{Name: "echo", Path: "/mcp/echo", Server: "echo", Auth: &config.RouteAuth{Type: "apiKey", HeaderName: "X-Team-Key", APIKeys: []string{"k1"}}},
+			{Name: "private", Path: "/mcp/private", Server: "echo", Auth: &config.RouteAuth{Type: "jwt", Issuer: "https://issuer", Audience: "mcp"}},
+			{Name: "hosted", Path: "/mcp/echo", Server: "echo", Hosts: []string{"*.echo.example.com"}, Auth: &config.RouteAuth{Type: "apiKey", HeaderName: "X-Team-Key", APIKeys: []string{"k2"}}},
+		},
+	}
+	gw := httptest.NewServer(runtime.NewServer(cfg).Handler())
+	defer gw.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := DialRoute(ctx, cfg, cfg.Routes[1], gw.URL, "", Credentials{}, nil); !errors.Is(err, ErrNoCredential) {
+		t.Fatalf("expected ErrNoCredential for a jwt route without a token, got %v", err)
+	}
+
+	conn, err := DialRoute(ctx, cfg, cfg.Routes[0], gw.URL, "", Credentials{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := mcp.NewClient(conn)
+	var notified []string
+	client.OnMessage = func(m mcp.Message) { notified = append(notified, m.Method) }
+	if _, err := client.Initialize(ctx, "test", ""); err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.CallTool(ctx, "echo", map[string]any{"text": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Content) != 1 || string(res.Content[0]) != `{"type":"text","text":"echo k1"}` {
+		t.Fatalf("unexpected tool result: %s", res.Content)
+	}
+	if len(notified) != 1 || notified[0] != "notifications/progress" {
+		t.Fatalf("expected the streamed progress notification, got %v", notified)
+	}
+
+	// A route on its own hosts is reached through the same address.
+	hosted, err := DialRoute(ctx, cfg, cfg.Routes[2], gw.URL, "", Credentials{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hosted.Close()
+	client = mcp.NewClient(hosted)
+	if _, err := client.Initialize(ctx, "test", ""); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := client.CallTool(ctx, "echo", nil); err != nil || string(res.Content[0]) != `{"type":"text","text":"echo k2"}` {
+		t.Fatalf("expected the hosted route, got %v err=%v", res, err)
+	}
+}
@@ -0,0 +1,241 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+// Clients lists the formats Export writes.
+var Clients = []string{"claude", "vscode", "cursor", "codex"}
+
+// Placeholder variables for the secrets a client must supply.
+const (
+	apiKeyVariable = "MCP_GATEWAY_API_KEY"
+	tokenVariable  = "MCP_GATEWAY_TOKEN"
+)
+
+// exportHeader is a header the client sends, with its value taken from a
+// variable: the route's API key or bearer token, or a per-user credential
+// forwarded to a stdio server.
+type exportHeader struct {
+	name     string
+	variable string
+	bearer   bool
+}
+
+type exportRoute struct {
+	name      string
+	url       string
+	transport string // http or sse
+	headers   []exportHeader
+}
+
+// Export renders client config that connects to every route of cfg through
+// baseURL, the gateway's public address, or through one of a route's own
+// hosts when baseURL's host is not among them. Secrets are placeholders the
+// user fills in; routes the client cannot reach are reported as warnings.
+func Export(cfg *config.Config, client, baseURL string) ([]byte, []string, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	var routes []exportRoute
+	var warnings []string
+	for _, r := range cfg.Routes {
+		base := baseURL
+		if host, ok := routeHost(r, baseURL); ok {
+			base = withHost(baseURL, host)
+			if r.MatchHost(host) == 1 {
+				warnings = append(warnings, fmt.Sprintf("%s: uses host %s; any name matching %s works", r.Name, host, strings.Join(r.Hosts, ", ")))
+			}
+		}
+		er := exportRoute{name: r.Name, url: base + r.Path, transport: "http"}
+		switch {
+		case r.AcceptsClientTransport("http"):
+		case r.AcceptsClientTransport("sse"):
+			er.url, er.transport = base+strings.TrimSuffix(r.Path, "/")+"/sse", "sse"
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s: skipped: the route only accepts websocket clients", r.Name))
+			continue
+		}
+		switch RouteAuth(cfg, r) {
+		case "apiKey":
+			er.headers = append(er.headers, exportHeader{name: cfg.EffectiveAuth(r).HeaderName, variable: apiKeyVariable})
+		case "jwt":
+			er.headers = append(er.headers, exportHeader{name: "Authorization", variable: tokenVariable, bearer: true})
+		}
+		for _, s := range cfg.Servers {
+			if s.Name != r.Server {
+				continue
+			}
+			for _, c := range s.Credentials {
+				if c.FromHeader != "" {
+					er.headers = append(er.headers, exportHeader{name: c.FromHeader, variable: headerVariable(c.FromHeader)})
+				}
+			}
+		}
+		routes = append(routes, er)
+	}
+
+	switch client {
+	case "claude":
+		return exportClaude(routes), warnings, nil
+	case "vscode":
+		return exportVSCode(routes), warnings, nil
+	case "cursor":
+		return exportCursor(routes), warnings, nil
+	case "codex":
+		b, more := exportCodex(routes)
+		return b, append(warnings, more...), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown client %q (want one of %s)", client, strings.Join(Clients, ", "))
+	}
+}
+
+// RouteAuth returns the auth type a route enforces; see
+// config.Config.EffectiveAuth.
+func RouteAuth(cfg *config.Config, r config.Route) string {
+	return cfg.EffectiveAuth(r).Type
+}
+
+var nonVariableChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// headerVariable names the placeholder for a forwarded header, such as
+// X_GITHUB_TOKEN for X-GitHub-Token.
+func headerVariable(header string) string {
+	return strings.Trim(nonVariableChars.ReplaceAllString(strings.ToUpper(header), "_"), "_")
+}
+
+func (h exportHeader) value(ref string) string {
+	if h.bearer {
+		return "Bearer " + ref
+	}
+	return ref
+}
+
+// exportClaude bridges each route with mcp-remote, since Claude Desktop's
+// config file only launches local processes. mcp-remote expands ${VAR} in
+// its arguments from the env block.
+func exportClaude(routes []exportRoute) []byte {
+	servers := map[string]any{}
+	for _, r := range routes {
+		args := []string{"-y", "mcp-remote", r.url}
+		if r.transport == "sse" {
+			args = append(args, "--transport", "sse-only")
+		}
+		env := map[string]string{}
+		for _, h := range r.headers {
+			// The whole value, "Bearer" included, lives in env: Claude
+			// Desktop on Windows splits arguments on spaces.
+			args = append(args, "--header", h.name+":${"+h.variable+"}")
+			env[h.variable] = h.value("REPLACE_WITH_" + h.variable)
+		}
+		entry := map[string]any{"command": "npx", "args": args}
+		if len(env) > 0 {
+			entry["env"] = env
+		}
+		servers[r.name] = entry
+	}
+	return marshalJSON(map[string]any{"mcpServers": servers})
+}
+
+// exportVSCode prompts for each secret once through an input variable.
+func exportVSCode(routes []exportRoute) []byte {
+	servers := map[string]any{}
+	inputs := []map[string]any{}
+	seen := map[string]bool{}
+	for _, r := range routes {
+		entry := map[string]any{"type": r.transport, "url": r.url}
+		headers := map[string]string{}
+		for _, h := range r.headers {
+			id := strings.ToLower(strings.ReplaceAll(h.variable, "_", "-"))
+			headers[h.name] = h.value("${input:" + id + "}")
+			if !seen[id] {
+				seen[id] = true
+				description := h.name + " for the MCP gateway"
+				if h.bearer {
+					description = "Bearer token for the MCP gateway"
+				}
+				inputs = append(inputs, map[string]any{
+					"type":        "promptString",
+					"id":          id,
+					"description": description,
+					"password":    true,
+				})
+			}
+		}
+		if len(headers) > 0 {
+			entry["headers"] = headers
+		}
+		servers[r.name] = entry
+	}
+	return marshalJSON(map[string]any{"inputs": inputs, "servers": servers})
+}
+
+// exportCursor reads secrets from the environment Cursor runs in.
+func exportCursor(routes []exportRoute) []byte {
+	servers := map[string]any{}
+	for _, r := range routes {
+		entry := map[string]any{"url": r.url}
+		headers := map[string]string{}
+		for _, h := range r.headers {
+			headers[h.name] = h.value("${env:" + h.variable + "}")
+		}
+		if len(headers) > 0 {
+			entry["headers"] = headers
+		}
+		servers[r.name] = entry
+	}
+	return marshalJSON(map[string]any{"mcpServers": servers})
+}
+
+// exportCodex writes ~/.codex/config.toml tables. Codex names the
+// environment variable holding each header value.
+func exportCodex(routes []exportRoute) ([]byte, []string) {
+	var b bytes.Buffer
+	var warnings []string
+	for _, r := range routes {
+		if r.transport != "http" {
+			warnings = append(warnings, fmt.Sprintf("%s: skipped: codex only speaks streamable HTTP", r.name))
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[mcp_servers.%s]\n", tomlKey(r.name))
+		fmt.Fprintf(&b, "url = %s\n", tomlString(r.url))
+		var envHeaders []string
+		for _, h := range r.headers {
+			if h.bearer {
+				fmt.Fprintf(&b, "bearer_token_env_var = %s\n", tomlString(h.variable))
+				continue
+			}
+			envHeaders = append(envHeaders, fmt.Sprintf("%s = %s", tomlString(h.name), tomlString(h.variable)))
+		}
+		if len(envHeaders) > 0 {
+			fmt.Fprintf(&b, "env_http_headers = { %s }\n", strings.Join(envHeaders, ", "))
+		}
+	}
+	return b.Bytes(), warnings
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(s string) string {
+	if bareKey.MatchString(s) {
+		return s
+	}
+	return tomlString(s)
+}
+
+func tomlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// marshalJSON indents like the clients' own files; map keys come out sorted.
+func marshalJSON(v any) []byte {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	return append(b, '\n')
+}
@@ -0,0 +1,60 @@
+package clients
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+func TestExportClientConfigs(t *testing.T) {
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":8080"},
+		Auth:    config.AuthDefaults{RequireAuth: true},
+		Servers: []config.Server{
+			{Name: "weather", Transport: "http", URL: "http://weather:8000"},
+			{Name: "git", Transport: "stdio", Command: "uvx", Credentials: []config.Credential{{FromHeader: "X-GitHub-Token", ToEnv: "GITHUB_TOKEN"}}},
+		},
+		Routes: []config.Route{
+			{Name: "weather", Path: "/mcp/weather", Server: "weather", Hosts: []string{"weather.example.com"}, Auth: &config.RouteAuth{Type: "jwt", Issuer: "https://issuer", Audience: "mcp"}},
+			{Name: "git", Path: "/mcp/git", Server: "git", Auth: &config.RouteAuth{Type: "apiKey", HeaderName: "X-Team-Key"}},
+			{Name: "legacy", Path: "/mcp/legacy", Server: "weather", ClientTransports: []string{"sse"}},
+		},
+	}
+
+	// What cursor gets back must import as the gateway's own routes.
+	out, _, err := Export(cfg, "cursor", "https://mcp.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(out)
+	for _, want := range []string{
+		`"Authorization": "Bearer ${env:MCP_GATEWAY_TOKEN}"`,
+		`"X-Team-Key": "${env:MCP_GATEWAY_API_KEY}"`,
+		`"X-GitHub-Token": "${env:X_GITHUB_TOKEN}"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("cursor export missing %s:\n%s", want, text)
+		}
+	}
+	imported, err := Import(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Routes) != 3 || imported.Routes[0].Path != "/mcp/git" || imported.Servers[1].Transport != "sse" {
+		t.Fatalf("exported config did not import back: %+v %+v", imported.Servers, imported.Routes)
+	}
+
+	out, warnings, err := Export(cfg, "codex", "https://mcp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "[mcp_servers.weather]\nurl = \"https://weather.example.com/mcp/weather\"\nbearer_token_env_var = \"MCP_GATEWAY_TOKEN\"\n") ||
+		len(warnings) != 1 || !strings.Contains(warnings[0], "legacy") {
+		t.Fatalf("unexpected codex export %v:\n%s", warnings, out)
+	}
+
+	if _, _, err := Export(cfg, "emacs", "https://mcp.example.com"); err == nil {
+		t.Fatal("expected an error for an unknown client")
+	}
+}
@@ -0,0 +1,345 @@
+// Package clients converts between the gateway config and the MCP server
+// configs of desktop clients (Claude Desktop, VS Code, Cursor, Codex).
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// clientServer is one entry of a client's mcpServers (Claude Desktop,
+// Cursor) or servers (VS Code) object.
+type clientServer struct {
+	Type     string            `json:"type"`
+	Command  string            `json:"command"`
+	Args     []string          `json:"args"`
+	Env      map[string]string `json:"env"`
+	EnvFile  string            `json:"envFile"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Disabled bool              `json:"disabled"`
+}
+
+// Imported holds servers and default routes converted from a client config.
+type Imported struct {
+	Servers  []config.Server
+	Routes   []config.Route
+	Warnings []string
+}
+
+var (
+	// variablePattern matches VS Code ${env:NAME} and ${input:id} references.
+	variablePattern  = regexp.MustCompile(`^\$\{(env|input):([^}]+)\}$`)
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidEnvChars  = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Import converts the servers of a Claude Desktop, VS Code or Cursor MCP
+// config into gateway servers, each with a default route at /mcp/<name>.
+func Import(b []byte) (*Imported, error) {
+	var doc struct {
+		MCPServers map[string]clientServer `json:"mcpServers"`
+		Servers    map[string]clientServer `json:"servers"`
+	}
+	if err := json.Unmarshal(stripJSONComments(b), &doc); err != nil {
+		return nil, fmt.Errorf("parse client config: %w", err)
+	}
+	entries := doc.MCPServers
+	if len(entries) == 0 {
+		entries = doc.Servers
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("client config has no mcpServers or servers entries")
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := &Imported{}
+	for _, clientName := range names {
+		entry := entries[clientName]
+		name := serverName(clientName)
+		warn := func(format string, args ...any) {
+			out.Warnings = append(out.Warnings, fmt.Sprintf("%s: ", name)+fmt.Sprintf(format, args...))
+		}
+		if entry.Disabled {
+			warn("skipped: disabled in the client config")
+			continue
+		}
+		server, path, err := convert(name, entry, warn)
+		if err != nil {
+			return nil, fmt.Errorf("server %q: %w", clientName, err)
+		}
+		out.Servers = append(out.Servers, server)
+		out.Routes = append(out.Routes, config.Route{
+			Name:   name,
+			Path:   path,
+			Server: name,
+			Policy: config.RoutePolicy{TimeoutMs: 30000},
+		})
+	}
+	return out, nil
+}
+
+func convert(name string, entry clientServer, warn func(string, ...any)) (config.Server, string, error) {
+	path := "/mcp/" + name
+	if entry.EnvFile != "" {
+		warn("envFile %s is not imported; add its variables as env entries", entry.EnvFile)
+	}
+	if len(entry.Headers) > 0 {
+		warn("headers are not imported; the gateway does not add upstream headers")
+	}
+
+	if entry.Command != "" {
+		server := config.Server{Name: name, Transport: "stdio", Command: entry.Command, Args: entry.Args}
+		envNames := make([]string, 0, len(entry.Env))
+		for k := range entry.Env {
+			envNames = append(envNames, k)
+		}
+		sort.Strings(envNames)
+		for _, k := range envNames {
+			server.Env = append(server.Env, importEnv(k, entry.Env[k], warn))
+		}
+		return server, path, nil
+	}
+
+	if entry.URL == "" {
+		return config.Server{}, "", fmt.Errorf("needs a command or a url")
+	}
+	u, err := url.Parse(entry.URL)
+	if err != nil || u.Host == "" {
+		return config.Server{}, "", fmt.Errorf("invalid url %q", entry.URL)
+	}
+	server := config.Server{Name: name, Transport: urlTransport(entry.Type, u), URL: entry.URL}
+	if server.Transport == "http" {
+		// The gateway forwards <route path> to <server url><route path>, so
+		// the upstream path becomes the route path.
+		if p := strings.TrimSuffix(u.Path, "/"); p != "" {
+			path = p
+			warn("route path is %s, the upstream's own path", p)
+		}
+		u.Path, u.RawPath = "", ""
+		server.URL = u.String()
+	}
+	return server, path, nil
+}
+
+func urlTransport(clientType string, u *url.URL) string {
+	switch {
+	case u.Scheme == "ws" || u.Scheme == "wss":
+		return "websocket"
+	case clientType == "sse":
+		return "sse"
+	case clientType == "" && strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/sse"):
+		return "sse"
+	default:
+		return "http"
+	}
+}
+
+// importEnv keeps references to variables as fromEnv and moves literal
+// secrets out of the config.
+func importEnv(name, value string, warn func(string, ...any)) config.EnvVar {
+	if m := variablePattern.FindStringSubmatch(value); m != nil {
+		if m[1] == "input" {
+			envName := strings.ToUpper(invalidEnvChars.ReplaceAllString(m[2], "_"))
+			warn("env %s prompted for input %q; set %s in the gateway's environment", name, m[2], envName)
+			return config.EnvVar{Name: name, FromEnv: envName}
+		}
+		return config.EnvVar{Name: name, FromEnv: m[2]}
+	}
+	if value != "" && config.LooksSecret(name) {
+		warn("env %s looks like a secret; imported as fromEnv %s, so set it in the gateway's environment", name, name)
+		return config.EnvVar{Name: name, FromEnv: name}
+	}
+	return config.EnvVar{Name: name, Value: value}
+}
+
+// serverName turns a client's server key into a lowercase DNS label.
+func serverName(s string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == "" {
+		name = "server"
+	}
+	return name
+}
+
+// stripJSONComments removes // and /* */ comments, which VS Code allows in
+// mcp.json, leaving string contents alone.
+func stripJSONComments(b []byte) []byte {
+	var out bytes.Buffer
+	inString, escaped := false, false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			i += 2
+			for i+1 < len(b) && !(b[i] == '*' && b[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// newConfigYAML starts a config for Merge when no gateway.yaml exists yet.
+const newConfigYAML = `apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway:
+  name: mcp-gateway
+  listenAddr: ":8080"
+  adminAddr: ":9090"
+  logLevel: info
+auth:
+  requireAuth: true
+servers: []
+routes: []
+`
+
+// Merge appends the imported servers and routes to the gateway config in
+// base, or to a new config when base is empty. Servers whose name or route
+// path is already taken are skipped with a warning. Editing the YAML tree
+// keeps base's comments and layout. Merge returns the new YAML and the
+// number of servers added.
+func Merge(base []byte, imported *Imported) ([]byte, int, []string, error) {
+	if len(bytes.TrimSpace(base)) == 0 {
+		base = []byte(newConfigYAML)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(base, &doc); err != nil {
+		return nil, 0, nil, fmt.Errorf("parse config yaml: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, 0, nil, fmt.Errorf("config yaml must be a mapping")
+	}
+	var existing config.Config
+	if err := doc.Decode(&existing); err != nil {
+		return nil, 0, nil, fmt.Errorf("parse config yaml: %w", err)
+	}
+	takenNames := map[string]bool{}
+	for _, s := range existing.Servers {
+		takenNames[s.Name] = true
+	}
+	takenPaths := map[string]bool{}
+	for _, r := range existing.Routes {
+		takenNames["route:"+r.Name] = true
+		takenPaths[r.Path] = true
+	}
+
+	root := doc.Content[0]
+	servers := sequence(root, "servers")
+	routes := sequence(root, "routes")
+	var warnings []string
+	added := 0
+	for i, s := range imported.Servers {
+		r := imported.Routes[i]
+		switch {
+		case takenNames[s.Name] || takenNames["route:"+r.Name]:
+			warnings = append(warnings, fmt.Sprintf("%s: skipped: a server or route with that name exists", s.Name))
+			continue
+		case takenPaths[r.Path]:
+			warnings = append(warnings, fmt.Sprintf("%s: skipped: route path %s is taken", s.Name, r.Path))
+			continue
+		}
+		takenNames[s.Name], takenNames["route:"+r.Name], takenPaths[r.Path] = true, true, true
+		added++
+		for _, item := range []struct {
+			seq *yaml.Node
+			v   any
+		}{{servers, s}, {routes, r}} {
+			var n yaml.Node
+			if err := n.Encode(item.v); err != nil {
+				return nil, 0, nil, err
+			}
+			flowScalarLists(&n)
+			item.seq.Content = append(item.seq.Content, &n)
+		}
+	}
+	// Lists that gained entries read better in block style.
+	for _, seq := range []*yaml.Node{servers, routes} {
+		if len(seq.Content) > 0 {
+			seq.Style = 0
+		}
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, 0, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, 0, nil, err
+	}
+	return out.Bytes(), added, warnings, nil
+}
+
+// sequence returns the sequence under key in mapping, adding it if missing.
+func sequence(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			v := mapping.Content[i+1]
+			if v.Kind != yaml.SequenceNode {
+				*v = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			}
+			return v
+		}
+	}
+	v := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	return v
+}
+
+// flowScalarLists writes lists of scalars such as args inline, as the
+// example config does.
+func flowScalarLists(n *yaml.Node) {
+	if n.Kind == yaml.SequenceNode {
+		flow := true
+		for _, c := range n.Content {
+			flow = flow && c.Kind == yaml.ScalarNode
+		}
+		if flow {
+			n.Style = yaml.FlowStyle
+		}
+	}
+	for _, c := range n.Content {
+		flowScalarLists(c)
+	}
+}
@@ -0,0 +1,71 @@
+package clients
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+func TestImportMergesIntoExistingConfig(t *testing.T) {
+	vscode := []byte(`{
+  // VS Code allows comments
+  "inputs": [{"type": "promptString", "id": "github-token", "password": true}],
+  "servers": {
+    "GitHub": {"type": "stdio", "command": "npx", "args": ["-y", "@modelcontextprotocol/server-github"],
+               "env": {"GITHUB_TOKEN": "${input:github-token}", "LOG_LEVEL": "info"}},
+    "search": {"type": "http", "url": "https://search.example.com/mcp/search"},
+    "weather": {"url": "https://weather.example.com/sse"}
+  }
+}`)
+	imported, err := Import(vscode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]config.Server{}
+	for _, s := range imported.Servers {
+		byName[s.Name] = s
+	}
+	if s := byName["github"]; s.Transport != "stdio" || s.Env[0] != (config.EnvVar{Name: "GITHUB_TOKEN", FromEnv: "GITHUB_TOKEN"}) {
+		t.Fatalf("unexpected stdio import: %+v", s)
+	}
+	if s := byName["search"]; s.Transport != "http" || s.URL != "https://search.example.com" {
+		t.Fatalf("expected the upstream path moved to the route, got %+v", s)
+	}
+	if s := byName["weather"]; s.Transport != "sse" {
+		t.Fatalf("expected an sse server, got %+v", s)
+	}
+
+	base := []byte(`apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway:
+  name: mcp-gateway
+  listenAddr: ":8080"
+servers:
+  # kept by the merge
+  - name: weather
+    transport: http
+    url: http://weather:8000
+routes:
+  - name: weather
+    path: /mcp/weather
+    server: weather
+`)
+	out, added, warnings, err := Merge(base, imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 || len(warnings) != 1 || !strings.Contains(warnings[0], "weather: skipped") {
+		t.Fatalf("expected github and search added and weather skipped, got %d %v", added, warnings)
+	}
+	if !strings.Contains(string(out), "# kept by the merge") {
+		t.Fatalf("merge dropped comments:\n%s", out)
+	}
+	cfg, err := config.Load(out)
+	if err != nil {
+		t.Fatalf("merged config does not validate: %v\n%s", err, out)
+	}
+	if len(cfg.Servers) != 3 || cfg.Routes[2].Path != "/mcp/search" {
+		t.Fatalf("unexpected merged config: %+v", cfg.Routes)
+	}
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source is one parsed config file.
+type source struct {
+	name string     // path as given; empty for CheckYAML input
+	root *yaml.Node // interpolated document, with source positions
+	// secrets holds the scalars interpolation marked secret.
+	secrets map[*yaml.Node]bool
+}
+
+// origin records where a merged server or route was defined.
+type origin struct {
+	src   *source
+	index int
+}
+
+// loader reads a config file, directory or document together with its
+// includes and merges them into one Config. Servers and routes are
+// concatenated in load order; every other top-level section may be set by
+// only one file.
+type loader struct {
+	merged   Config
+	sources  []*source
+	sections map[string]*source // top-level key -> the file that sets it
+	servers  []origin
+	routes   []origin
+	// files lists every file and directory read, for Watch.
+	files  []string
+	seen   map[string]bool
+	issues Issues
+	// listOnly follows includes without decoding or merging, so files
+	// gateway migrate has yet to rewrite can be found.
+	listOnly bool
+}
+
+func newLoader() *loader {
+	return &loader{sections: map[string]*source{}, seen: map[string]bool{}}
+}
+
+// CheckYAML parses config YAML, interpolates it, loads its includes and
+// returns every issue found, located in the source. dir is the base of
+// relative include and ${file:} paths. The config is nil when a file cannot
+// be read, decoded or interpolated; those problems are reported before any
+// other check runs.
+func CheckYAML(b []byte, dir string) (*Config, Issues) {
+	l := newLoader()
+	l.addBytes("", b, dir)
+	return l.check()
+}
+
+// CheckFile is CheckYAML for the config at path: a file, or a directory
+// whose *.yaml and *.yml files are merged in name order.
+func CheckFile(path string) (*Config, Issues) {
+	cfg, issues, _ := checkFile(path)
+	return cfg, issues
+}
+
+// ResolvedFile is ResolvedYAML for the config at path and everything it
+// includes: one YAML document per file, each headed by its name.
+func ResolvedFile(path string) ([]byte, Issues) {
+	l := newLoader()
+	l.add(path)
+	if len(l.issues) > 0 {
+		return nil, l.issues
+	}
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	for _, src := range l.sources {
+		mask(src.root, src.secrets, "")
+		doc := src.root
+		if len(l.sources) > 1 {
+			doc.HeadComment = "source: " + src.name
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, Issues{{Severity: SeverityError, File: src.name, Message: err.Error()}}
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, Issues{{Severity: SeverityError, Message: err.Error()}}
+	}
+	return out.Bytes(), nil
+}
+
+// SourceFiles lists the files the config at path is read from: the file
+// itself, or a directory's files, and everything they include. Files that
+// do not parse are listed but not followed.
+func SourceFiles(path string) ([]string, Issues) {
+	l := newLoader()
+	l.listOnly = true
+	l.add(path)
+	var files []string
+	for _, f := range l.files {
+		if info, err := os.Stat(f); err == nil && !info.IsDir() {
+			files = append(files, f)
+		}
+	}
+	return files, l.issues
+}
+
+// checkFile also returns the files and directories that were read.
+func checkFile(path string) (*Config, Issues, []string) {
+	l := newLoader()
+	l.add(path)
+	cfg, issues := l.check()
+	return cfg, issues, l.files
+}
+
+// add loads the config file or directory at path.
+func (l *loader) add(path string) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if l.addDir(path) == 0 && len(l.issues) == 0 {
+			l.fail(nil, "", "directory %s has no .yaml or .yml files", path)
+		}
+		return
+	}
+	l.addFile(path)
+}
+
+func (l *loader) fail(src *source, path, format string, args ...any) {
+	issue := Issue{Severity: SeverityError, Path: path, Message: fmt.Sprintf(format, args...)}
+	if src != nil {
+		issue.File = src.name
+		issue.Line, issue.Column = locate(src.root, path)
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *loader) addFile(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		if l.seen[abs] {
+			return
+		}
+		l.seen[abs] = true
+	}
+	l.files = append(l.files, path)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		l.issues = append(l.issues, Issue{Severity: SeverityError, File: path, Message: fmt.Sprintf("read config: %v", err)})
+		return
+	}
+	l.addBytes(path, b, filepath.Dir(path))
+}
+
+// addDir loads the YAML files directly in dir and returns how many there
+// were.
+func (l *loader) addDir(dir string) int {
+	l.files = append(l.files, dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		l.issues = append(l.issues, Issue{Severity: SeverityError, File: dir, Message: fmt.Sprintf("read config directory: %v", err)})
+		return 0
+	}
+	n := 0
+	for _, e := range entries {
+		if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			l.addFile(filepath.Join(dir, e.Name()))
+			n++
+		}
+	}
+	return n
+}
+
+func (l *loader) addBytes(name string, b []byte, dir string) {
+	if l.listOnly {
+		var root yaml.Node
+		var includes struct {
+			Include []string `yaml:"include"`
+		}
+		if err := yaml.Unmarshal(b, &root); err != nil || root.Decode(&includes) != nil {
+			return
+		}
+		src := &source{name: name, root: &root}
+		l.sources = append(l.sources, src)
+		for i, pattern := range includes.Include {
+			l.include(src, i, pattern, dir)
+		}
+		return
+	}
+	located := func(issues Issues) Issues {
+		for i := range issues {
+			issues[i].File = name
+		}
+		return issues
+	}
+	// Unknown fields are only reported by a strict decode, which a
+	// yaml.Node cannot do; keys are never interpolated, so decoding the
+	// source finds the same ones.
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		l.issues = append(l.issues, located(decodeIssues(err))...)
+		return
+	}
+	root, in, err := resolve(b, dir)
+	if err != nil {
+		l.issues = append(l.issues, located(decodeIssues(err))...)
+		return
+	}
+	if len(in.issues) > 0 {
+		l.issues = append(l.issues, located(in.issues)...)
+		return
+	}
+	cfg = Config{}
+	if err := root.Decode(&cfg); err != nil {
+		l.issues = append(l.issues, located(decodeIssues(err))...)
+		return
+	}
+	src := &source{name: name, root: root, secrets: in.secrets}
+	l.sources = append(l.sources, src)
+	l.merge(src, cfg)
+	for i, pattern := range cfg.Include {
+		l.include(src, i, pattern, dir)
+	}
+}
+
+// merge adds one file's config to the merged one.
+func (l *loader) merge(src *source, cfg Config) {
+	for i := range cfg.Servers {
+		l.servers = append(l.servers, origin{src, i})
+	}
+	l.merged.Servers = append(l.merged.Servers, cfg.Servers...)
+	for i := range cfg.Routes {
+		l.routes = append(l.routes, origin{src, i})
+	}
+	l.merged.Routes = append(l.merged.Routes, cfg.Routes...)
+
+	doc := src.root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i < len(doc.Content); i += 2 {
+		key := doc.Content[i].Value
+		switch key {
+		case "servers", "routes", "include":
+			continue
+		}
+		if other, ok := l.sections[key]; ok {
+			l.fail(src, key, "%s is set in both %s and %s", key, displayName(other.name), displayName(src.name))
+			continue
+		}
+		l.sections[key] = src
+		switch key {
+		case "apiVersion":
+			l.merged.APIVersion = cfg.APIVersion
+		case "kind":
+			l.merged.Kind = cfg.Kind
+		case "gateway":
+			l.merged.Gateway = cfg.Gateway
+		case "auth":
+			l.merged.Auth = cfg.Auth
+		case "authProviders":
+			l.merged.AuthProviders = cfg.AuthProviders
+		case "policies":
+			l.merged.Policies = cfg.Policies
+		case "deployment":
+			l.merged.Deployment = cfg.Deployment
+		}
+	}
+}
+
+// include loads the files an include entry names: a file, a directory or a
+// glob, relative to the including file's directory.
+func (l *loader) include(src *source, i int, pattern, dir string) {
+	path := fmt.Sprintf("include[%d]", i)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		l.fail(src, path, "include %q: %v", pattern, err)
+		return
+	}
+	if len(matches) == 0 {
+		l.fail(src, path, "include %s matches no files", pattern)
+		return
+	}
+	sort.Strings(matches)
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			l.addDir(m)
+			continue
+		}
+		l.addFile(m)
+	}
+}
+
+// check validates the merged config and locates its issues.
+func (l *loader) check() (*Config, Issues) {
+	if len(l.issues) > 0 {
+		return nil, l.issues
+	}
+	cfg := l.merged
+	cfg.secrets = l.secretValues()
+	issues := cfg.check(l.position)
+	for i := range issues {
+		issues[i].File, issues[i].Line, issues[i].Column = l.locate(issues[i].Path)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return l.order(issues[i].File) < l.order(issues[j].File)
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return &cfg, issues
+}
+
+// secretValues lists the distinct values of the scalars interpolation
+// marked secret in any source, sorted.
+func (l *loader) secretValues() []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, src := range l.sources {
+		for n := range src.secrets {
+			if n.Value != "" && !seen[n.Value] {
+				seen[n.Value] = true
+				values = append(values, n.Value)
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (l *loader) order(file string) int {
+	for i, src := range l.sources {
+		if src.name == file {
+			return i
+		}
+	}
+	return len(l.sources)
+}
+
+// locate maps a path in the merged config to a file and position.
+func (l *loader) locate(path string) (file string, line, column int) {
+	if len(l.sources) == 0 {
+		return "", 0, 0
+	}
+	head, rest, _ := strings.Cut(path, ".")
+	if list, index, ok := strings.Cut(head, "["); ok {
+		i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+		origins := map[string][]origin{"servers": l.servers, "routes": l.routes}[list]
+		if err == nil && i >= 0 && i < len(origins) {
+			o := origins[i]
+			local := fmt.Sprintf("%s[%d]", list, o.index)
+			if rest != "" {
+				local += "." + rest
+			}
+			line, column = locate(o.src.root, local)
+			return o.src.name, line, column
+		}
+	}
+	src := l.sections[head]
+	if src == nil {
+		src = l.sources[0]
+	}
+	line, column = locate(src.root, path)
+	return src.name, line, column
+}
+
+// position describes where path is defined, for hints.
+func (l *loader) position(path string) string {
+	file, line, _ := l.locate(path)
+	switch {
+	case file != "" && line > 0:
+		return fmt.Sprintf("%s:%d", file, line)
+	case line > 0:
+		return fmt.Sprintf("line %d", line)
+	}
+	return path
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "the config"
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncludeMergesTeamFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("gateway.yaml", `apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+include: [teams/*.yaml]
+`)
+	write("teams/search.yaml", `servers:
+  - {name: search, transport: http, url: "http://search:8000"}
+routes:
+  - {name: search, path: /mcp/search, server: search}
+`)
+	write("teams/weather.yaml", `servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - {name: weather, path: /mcp/weather, server: weather}
+`)
+	cfg, err := LoadFile(filepath.Join(dir, "gateway.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Servers) != 2 || cfg.Routes[1].Name != "weather" || cfg.Gateway.Name != "gw" {
+		t.Fatalf("unexpected merged config: %+v", cfg)
+	}
+
+	// A duplicate route names both files; a section set twice is an error.
+	write("teams/weather.yaml", `servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - {name: weather, path: /mcp/weather, server: weather}
+  - {name: search, path: /mcp/weather2, server: weather}
+`)
+	_, issues := CheckFile(filepath.Join(dir, "gateway.yaml"))
+	errs := issues.Errors()
+	search := filepath.Join(dir, "teams", "search.yaml")
+	if len(errs) != 1 || errs[0].File != filepath.Join(dir, "teams", "weather.yaml") || errs[0].Line != 5 ||
+		errs[0].Hint != "first defined at "+search+":4" {
+		t.Fatalf("unexpected duplicate issues: %+v", issues)
+	}
+	write("teams/extra.yaml", "gateway: {name: other, listenAddr: \":9090\"}\n")
+	if _, issues := CheckFile(filepath.Join(dir, "gateway.yaml")); !strings.Contains(issues.Error(), "gateway is set in both") {
+		t.Fatalf("expected a section conflict, got %v", issues)
+	}
+}
+
+func TestWatchReloadsIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "gateway.yaml")
+	team := filepath.Join(dir, "team.yaml")
+	if err := os.WriteFile(main, []byte("apiVersion: mcp.envoy.io/v1alpha1\nkind: GatewayConfig\ngateway: {name: gw, listenAddr: \":8080\"}\ninclude: [team.yaml]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	route := "servers: [{name: a, transport: http, url: \"http://a\"}]\nroutes: [{name: a, path: /mcp/%s, server: a}]\n"
+	if err := os.WriteFile(team, []byte(strings.ReplaceAll(route, "%s", "a")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan *Config, 1)
+	go Watch(ctx, main, 10*time.Millisecond, func(cfg *Config, err error) {
+		if err == nil {
+			reloaded <- cfg
+		}
+	})
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(team, []byte(strings.ReplaceAll(route, "%s", "changed")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-reloaded:
+		if cfg.Routes[0].Path != "/mcp/changed" {
+			t.Fatalf("unexpected reloaded route: %+v", cfg.Routes[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("included file change was not reloaded")
+	}
+}
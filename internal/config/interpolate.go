@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// referencePattern matches ${...} references and the $${ escape.
+	referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// secretNamePattern flags names whose values are secrets.
+	secretNamePattern = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|API_?KEY|CREDENTIAL|PRIVATE_KEY)`)
+)
+
+// Masked replaces secret values in resolved config output.
+const Masked = "********"
+
+// LooksSecret reports whether an env var or field name suggests its value
+// is a secret.
+func LooksSecret(name string) bool {
+	return secretNamePattern.MatchString(name)
+}
+
+// SecretValues lists the values the config took from ${file:} references
+// or from secret-looking variables, sorted. Renderers use it to keep them out
+// of plain-text objects.
+func (c *Config) SecretValues() []string {
+	return c.secrets
+}
+
+// interpolator expands references in string scalars:
+//
+//	${VAR}          the variable, which must be set
+//	${VAR:-default} the variable, or default when it is unset or empty
+//	${file:PATH}    the file's contents without the trailing newline;
+//	                relative paths start at the config file's directory
+//	$${             a literal ${
+type interpolator struct {
+	dir    string
+	issues Issues
+	// secrets holds the scalars that took a value from a file or from a
+	// secret-looking variable.
+	secrets map[*yaml.Node]bool
+}
+
+// resolve parses b and interpolates it in place, returning the document
+// node with its original positions.
+func resolve(b []byte, dir string) (*yaml.Node, *interpolator, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, nil, err
+	}
+	in := &interpolator{dir: dir, secrets: map[*yaml.Node]bool{}}
+	in.walk(&root, "")
+	return &root, in, nil
+}
+
+func (in *interpolator) walk(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			in.walk(c, path)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			in.walk(n.Content[i+1], key)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			in.walk(c, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.ScalarNode:
+		if n.Tag == "!!str" && strings.Contains(n.Value, "${") {
+			n.Value = in.expand(n, path)
+		}
+	}
+}
+
+func (in *interpolator) expand(n *yaml.Node, path string) string {
+	fail := func(hint, format string, args ...any) {
+		in.issues = append(in.issues, Issue{
+			Severity: SeverityError, Path: path, Line: n.Line, Column: n.Column,
+			Message: fmt.Sprintf(format, args...), Hint: hint,
+		})
+	}
+	return referencePattern.ReplaceAllStringFunc(n.Value, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		expr := ref[2 : len(ref)-1]
+		if file, ok := strings.CutPrefix(expr, "file:"); ok {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(in.dir, file)
+			}
+			b, err := os.ReadFile(file)
+			if err != nil {
+				fail("", "%s: %v", ref, err)
+				return ""
+			}
+			in.secrets[n] = true
+			return strings.TrimRight(string(b), "\r\n")
+		}
+		name, def, hasDefault := strings.Cut(expr, ":-")
+		if !envNamePattern.MatchString(name) {
+			fail("use ${VAR}, ${VAR:-default} or ${file:PATH}; write $${ for a literal ${", "%s is not a valid reference", ref)
+			return ""
+		}
+		value, ok := os.LookupEnv(name)
+		if hasDefault && value == "" {
+			return def
+		}
+		if !ok {
+			fail(fmt.Sprintf("set it, or give a default with ${%s:-value}", name), "variable %s is not set", name)
+			return ""
+		}
+		if LooksSecret(name) {
+			in.secrets[n] = true
+		}
+		return value
+	})
+}
+
+// ResolvedYAML returns config YAML with references interpolated and secret
+// values masked: values read from files or secret-looking variables, API
+// keys, and env values with secret-looking names. dir is the base of
+// relative ${file:} paths.
+func ResolvedYAML(b []byte, dir string) ([]byte, Issues) {
+	root, in, err := resolve(b, dir)
+	if err != nil {
+		return nil, decodeIssues(err)
+	}
+	if len(in.issues) > 0 {
+		return nil, in.issues
+	}
+	mask(root, in.secrets, "")
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, Issues{{Severity: SeverityError, Message: err.Error()}}
+	}
+	return out.Bytes(), nil
+}
+
+// MaskYAML masks the API keys and the env values with secret-looking names
+// in config YAML that has already been resolved, such as the config embedded
+// in a rendered ConfigMap.
+func MaskYAML(b []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+	mask(&root, map[*yaml.Node]bool{}, "")
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// mask replaces secret scalars under n; key is n's mapping key.
+func mask(n *yaml.Node, secrets map[*yaml.Node]bool, key string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			mask(c, secrets, key)
+		}
+	case yaml.MappingNode:
+		var envName string
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == "name" {
+				envName = n.Content[i+1].Value
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i].Value, n.Content[i+1]
+			// An env entry's value is secret when its name looks secret.
+			if k == "value" && v.Kind == yaml.ScalarNode && LooksSecret(envName) {
+				secrets[v] = true
+			}
+			mask(v, secrets, k)
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			mask(c, secrets, key)
+		}
+	case yaml.ScalarNode:
+		if secrets[n] || key == "apiKeys" {
+			n.Value = Masked
+			n.Style = 0
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const interpolatedYAML = `apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":${GW_TEST_PORT:-8080}"}
+servers:
+  - {name: weather, transport: http, url: "${GW_TEST_URL}/mcp"}
+  - name: git
+    transport: stdio
+    command: uvx
+    env:
+      - {name: GITHUB_TOKEN, value: "${GW_TEST_TOKEN}"}
+      - {name: PROMPT, value: "$${literal}"}
+routes:
+  - name: weather
+    path: /mcp/weather
+    server: weather
+    auth: {type: apiKey, headerName: X-API-Key, apiKeys: ["${file:keys/weather}"]}
+  - {name: git, path: /mcp/git, server: git}
+`
+
+func TestInterpolation(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "keys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "keys", "weather"), []byte("k-123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "gateway.yaml")
+	if err := os.WriteFile(path, []byte(interpolatedYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GW_TEST_TOKEN", "ghp_secret")
+	_, issues := CheckYAML([]byte(interpolatedYAML), dir)
+	if len(issues) != 1 || issues[0].Path != "servers[0].url" || issues[0].Line != 5 || !strings.Contains(issues[0].Hint, "${GW_TEST_URL:-value}") {
+		t.Fatalf("expected an unset variable issue, got %+v", issues)
+	}
+
+	t.Setenv("GW_TEST_URL", "http://weather:8000")
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Gateway.ListenAddr != ":8080" || cfg.Servers[0].URL != "http://weather:8000/mcp" {
+		t.Fatalf("unexpected gateway %+v and server %+v", cfg.Gateway, cfg.Servers[0])
+	}
+	if env := cfg.Servers[1].Env; env[0].Value != "ghp_secret" || env[1].Value != "${literal}" {
+		t.Fatalf("unexpected env: %+v", env)
+	}
+	if keys := cfg.Routes[0].Auth.APIKeys; len(keys) != 1 || keys[0] != "k-123" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	resolved, issues := ResolvedYAML([]byte(interpolatedYAML), dir)
+	if len(issues) != 0 {
+		t.Fatal(issues)
+	}
+	out := string(resolved)
+	if strings.Contains(out, "ghp_secret") || strings.Contains(out, "k-123") || !strings.Contains(out, "http://weather:8000/mcp") {
+		t.Fatalf("secrets not masked or values not resolved:\n%s", out)
+	}
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of an Issue.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is one validation finding.
+type Issue struct {
+	Severity string `json:"severity"`
+	// File is the config file the issue is in, when known.
+	File string `json:"file,omitempty"`
+	// Path locates the value in the document, such as routes[1].server.
+	Path string `json:"path,omitempty"`
+	// Line and Column are 1-based source positions, 0 when unknown.
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+func (i Issue) String() string {
+	s := i.Message
+	switch {
+	case i.File != "" && i.Line > 0:
+		s = fmt.Sprintf("%s:%d: %s", i.File, i.Line, s)
+	case i.File != "":
+		s = fmt.Sprintf("%s: %s", i.File, s)
+	case i.Line > 0:
+		s = fmt.Sprintf("line %d: %s", i.Line, s)
+	}
+	if i.Hint != "" {
+		s += " (" + i.Hint + ")"
+	}
+	return s
+}
+
+// Issues lists validation findings. As an error it reports each of them on
+// its own line.
+type Issues []Issue
+
+func (is Issues) Error() string {
+	lines := make([]string, len(is))
+	for i, issue := range is {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Errors returns the issues with error severity.
+func (is Issues) Errors() Issues { return is.filter(SeverityError) }
+
+// Warnings returns the issues with warning severity.
+func (is Issues) Warnings() Issues { return is.filter(SeverityWarning) }
+
+func (is Issues) filter(severity string) Issues {
+	var out Issues
+	for _, i := range is {
+		if i.Severity == severity {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// validator collects issues.
+type validator struct {
+	issues Issues
+	// position describes where a path is defined, for hints; nil leaves
+	// the path as is.
+	position func(path string) string
+}
+
+func (v *validator) describe(path string) string {
+	if v.position == nil {
+		return path
+	}
+	return v.position(path)
+}
+
+func (v *validator) errorf(path, format string, args ...any) *Issue {
+	return v.add(SeverityError, path, format, args...)
+}
+
+func (v *validator) warnf(path, format string, args ...any) *Issue {
+	return v.add(SeverityWarning, path, format, args...)
+}
+
+// add appends an issue and returns it so a hint can be attached before the
+// next one is added.
+func (v *validator) add(severity, path, format string, args ...any) *Issue {
+	v.issues = append(v.issues, Issue{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+	return &v.issues[len(v.issues)-1]
+}
+
+// suggest hints at the option closest to value, or lists the options when
+// none is close.
+func (i *Issue) suggest(value string, options []string) {
+	if best := closest(value, options); best != "" {
+		i.Hint = fmt.Sprintf("did you mean %q?", best)
+		return
+	}
+	var named []string
+	for _, o := range options {
+		if o != "" {
+			named = append(named, o)
+		}
+	}
+	if len(named) > 0 && len(named) <= 8 {
+		i.Hint = "expected one of: " + strings.Join(named, ", ")
+	}
+}
+
+// closest returns the option within a small edit distance of value,
+// ignoring case, or "".
+func closest(value string, options []string) string {
+	if value == "" {
+		return ""
+	}
+	best, bestDist := "", -1
+	for _, o := range options {
+		if o == "" || o == value {
+			continue
+		}
+		d := editDistance(strings.ToLower(value), strings.ToLower(o))
+		if d <= max(1, len(o)/3) && (bestDist < 0 || d < bestDist) {
+			best, bestDist = o, d
+		}
+	}
+	return best
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent bytes that turn a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+var (
+	yamlLinePattern     = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	unknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type (\S+)$`)
+)
+
+// decodeIssues turns a yaml.v3 decoding error into issues with lines.
+func decodeIssues(err error) Issues {
+	if errors.Is(err, io.EOF) {
+		return Issues{{Severity: SeverityError, Message: "config is empty"}}
+	}
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	var issues Issues
+	for _, m := range messages {
+		issue := Issue{Severity: SeverityError, Message: m}
+		if match := yamlLinePattern.FindStringSubmatch(m); match != nil {
+			issue.Line, _ = strconv.Atoi(match[1])
+			issue.Message = match[2]
+		}
+		if match := unknownFieldPattern.FindStringSubmatch(issue.Message); match != nil {
+			issue.Message = fmt.Sprintf("unknown field %q", match[1])
+			issue.suggest(match[1], yamlFields[match[2]])
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// yamlFields lists the YAML keys of each config struct, keyed the way
+// yaml.v3 names types in errors (config.Route).
+var yamlFields = func() map[string][]string {
+	fields := map[string][]string{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		if _, ok := fields[t.String()]; ok {
+			return
+		}
+		fields[t.String()] = nil
+		var add func(owner, t reflect.Type)
+		add = func(owner, t reflect.Type) {
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+				if name == "" && opts == "inline" {
+					add(owner, f.Type)
+					walk(f.Type)
+					continue
+				}
+				if name == "" || name == "-" {
+					continue
+				}
+				fields[owner.String()] = append(fields[owner.String()], name)
+				walk(f.Type)
+			}
+		}
+		add(t, t)
+	}
+	walk(reflect.TypeOf(Config{}))
+	return fields
+}()
+
+// locate returns the position of the node at path, or of its deepest
+// existing ancestor when part of the path is missing.
+func locate(root *yaml.Node, path string) (line, column int) {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if path == "" || n.Kind == 0 {
+		return 0, 0
+	}
+	line, column = n.Line, n.Column
+	for _, seg := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		if seg == "" {
+			continue
+		}
+		n = child(n, seg)
+		if n == nil {
+			break
+		}
+		line, column = n.Line, n.Column
+	}
+	return line, column
+}
+
+// child returns the value under a mapping key or the sequence element of
+// an "[i]" segment.
+func child(n *yaml.Node, seg string) *yaml.Node {
+	if strings.HasPrefix(seg, "[") {
+		i, err := strconv.Atoi(strings.Trim(seg, "[]"))
+		if err != nil || n.Kind != yaml.SequenceNode || i < 0 || i >= len(n.Content) {
+			return nil
+		}
+		return n.Content[i]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == seg {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
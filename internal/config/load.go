@@ -1,28 +1,73 @@
 package config
 
 import (
-	"bytes"
 	"fmt"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"strings"
 )
 
-// LoadFile loads and validates config from a YAML file path.
+// LoadFile loads and validates config from a YAML file, or from a
+// directory of them, together with their includes. Relative include and
+// ${file:} paths start at the directory of the file naming them.
 func LoadFile(path string) (*Config, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+	cfg, issues := CheckFile(path)
+	if errs := issues.Errors(); len(errs) > 0 {
+		return nil, errs
 	}
+	return cfg, nil
+}
 
-	var cfg Config
-	decoder := yaml.NewDecoder(bytes.NewReader(b))
-	decoder.KnownFields(true)
-	if err := decoder.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("parse config yaml: %w", err)
+// Load parses, interpolates and validates config YAML. Validation errors
+// are returned as Issues located in b.
+func Load(b []byte) (*Config, error) {
+	cfg, issues := CheckYAML(b, ".")
+	if errs := issues.Errors(); len(errs) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// ReadAPIKeyFiles appends the keys listed in each route's and auth
+// provider's apiKeysFile to its apiKeys. Blank lines and lines starting with
+// # are ignored.
+func (c *Config) ReadAPIKeyFiles() error {
+	for i := range c.AuthProviders {
+		if err := readAPIKeyFile(&c.AuthProviders[i].RouteAuth); err != nil {
+			return fmt.Errorf("auth provider %q: %w", c.AuthProviders[i].Name, err)
+		}
+	}
+	for i := range c.Routes {
+		auth := c.Routes[i].Auth
+		if auth == nil {
+			continue
+		}
+		copied := *auth
+		if err := readAPIKeyFile(&copied); err != nil {
+			return fmt.Errorf("route %q: %w", c.Routes[i].Name, err)
+		}
+		c.Routes[i].Auth = &copied
+	}
+	return nil
+}
+
+func readAPIKeyFile(auth *RouteAuth) error {
+	if strings.TrimSpace(auth.APIKeysFile) == "" {
+		return nil
+	}
+	b, err := os.ReadFile(auth.APIKeysFile)
+	if err != nil {
+		return fmt.Errorf("read apiKeysFile: %w", err)
+	}
+	keys := append([]string(nil), auth.APIKeys...)
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
 	}
-	if err := cfg.Validate(); err != nil {
-		return nil, err
+	if len(keys) == 0 {
+		return fmt.Errorf("apiKeysFile %s contains no keys", auth.APIKeysFile)
 	}
-	return &cfg, nil
+	auth.APIKeys = keys
+	return nil
 }
@@ -0,0 +1,130 @@
+package config
+
+import "strings"
+
+// DefaultAPIKeyHeader is the header apiKey auth reads when headerName is
+// unset.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// EffectiveAuth returns the auth route r enforces: its auth block over the
+// provider it uses, or over auth.defaultProvider when it names none. Without
+// a type from either, the type is apiKey when auth.requireAuth is set and
+// none otherwise; apiKey auth reads DefaultAPIKeyHeader unless a header is
+// named. The runtime, the renderers and client export all use it,
+// so they agree on what a route accepts.
+func (c *Config) EffectiveAuth(r Route) RouteAuth {
+	auth := c.mergedAuth(r)
+	if strings.TrimSpace(auth.Type) == "" {
+		auth.Type = "none"
+		if c.Auth.RequireAuth {
+			auth.Type = "apiKey"
+		}
+	}
+	if auth.Type == "apiKey" && strings.TrimSpace(auth.HeaderName) == "" {
+		auth.HeaderName = DefaultAPIKeyHeader
+	}
+	return auth
+}
+
+// mergedAuth is r's auth block over its provider, without the type and
+// header defaults.
+func (c *Config) mergedAuth(r Route) RouteAuth {
+	var auth RouteAuth
+	if p, ok := c.authProvider(c.routeProvider(r)); ok {
+		auth = p.RouteAuth
+	}
+	if r.Auth != nil {
+		auth = overrideAuth(auth, *r.Auth)
+	}
+	auth.Use = ""
+	return auth
+}
+
+// routeProvider names the auth provider r starts from.
+func (c *Config) routeProvider(r Route) string {
+	if r.Auth != nil && r.Auth.Use != "" {
+		return r.Auth.Use
+	}
+	return c.Auth.DefaultProvider
+}
+
+// EffectivePolicy returns route r's policy over the policy it uses.
+func (c *Config) EffectivePolicy(r Route) RoutePolicy {
+	var policy RoutePolicy
+	if p, ok := c.policy(r.Policy.Use); ok {
+		policy = p.RoutePolicy
+	}
+	policy = overridePolicy(policy, r.Policy)
+	policy.Use = ""
+	return policy
+}
+
+// ResolveRoute returns r with its effective auth and policy filled in, so
+// code that reads r.Auth and r.Policy sees what the route enforces.
+// Resolving a resolved route changes nothing: r.Auth.Use keeps the provider
+// it was resolved from, so auth.defaultProvider is not layered in again.
+func (c *Config) ResolveRoute(r Route) Route {
+	auth := c.EffectiveAuth(r)
+	auth.Use = c.routeProvider(r)
+	r.Auth = &auth
+	r.Policy = c.EffectivePolicy(r)
+	return r
+}
+
+func (c *Config) authProvider(name string) (AuthProvider, bool) {
+	for _, p := range c.AuthProviders {
+		if name != "" && p.Name == name {
+			return p, true
+		}
+	}
+	return AuthProvider{}, false
+}
+
+func (c *Config) policy(name string) (Policy, bool) {
+	for _, p := range c.Policies {
+		if name != "" && p.Name == name {
+			return p, true
+		}
+	}
+	return Policy{}, false
+}
+
+// overrideAuth returns base with the fields set in over replacing its own.
+func overrideAuth(base, over RouteAuth) RouteAuth {
+	set := func(dst *string, v string) {
+		if strings.TrimSpace(v) != "" {
+			*dst = v
+		}
+	}
+	set(&base.Type, over.Type)
+	set(&base.HeaderName, over.HeaderName)
+	set(&base.APIKeysFile, over.APIKeysFile)
+	set(&base.Issuer, over.Issuer)
+	set(&base.Audience, over.Audience)
+	set(&base.JWKSURI, over.JWKSURI)
+	if len(over.APIKeys) > 0 {
+		base.APIKeys = over.APIKeys
+	}
+	if over.Require != nil {
+		base.Require = over.Require
+	}
+	return base
+}
+
+// overridePolicy returns base with the fields set in over replacing its
+// own.
+func overridePolicy(base, over RoutePolicy) RoutePolicy {
+	if over.TimeoutMs != 0 {
+		base.TimeoutMs = over.TimeoutMs
+	}
+	if over.RetryCount != 0 {
+		base.RetryCount = over.RetryCount
+	}
+	if over.RateLimitRPS != 0 {
+		base.RateLimitRPS = over.RateLimitRPS
+	}
+	if over.AllowedTools != nil {
+		base.AllowedTools = over.AllowedTools
+	}
+	return base
+}
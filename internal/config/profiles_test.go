@@ -0,0 +1,120 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthProvidersAndPolicies(t *testing.T) {
+	cfg, issues := CheckYAML([]byte(`apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+auth: {requireAuth: true}
+authProviders:
+  - {name: corp-sso, type: jwt, issuer: "https://issuer", audience: gw}
+policies:
+  - {name: standard, timeoutMs: 10000, retryCount: 1, rateLimitRps: 20}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - name: weather
+    path: /mcp/weather
+    server: weather
+    auth: {use: corp-sso, audience: weather}
+    policy: {use: standard, rateLimitRps: 5}
+  - {name: open, path: /mcp/open, server: weather}
+`), ".")
+	// requireAuth alone leaves "open" with a presence-only key check.
+	if w := issues.Warnings(); len(issues) != 1 || len(w) != 1 || w[0].Path != "routes[1]" {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+	want := RouteAuth{Type: "jwt", Issuer: "https://issuer", Audience: "weather"}
+	if got := cfg.EffectiveAuth(cfg.Routes[0]); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got := cfg.EffectivePolicy(cfg.Routes[0]); got.TimeoutMs != 10000 || got.RetryCount != 1 || got.RateLimitRPS != 5 {
+		t.Fatalf("unexpected policy: %+v", got)
+	}
+	// A route without auth falls back to the requireAuth default.
+	if got := cfg.EffectiveAuth(cfg.Routes[1]); got.Type != "apiKey" || got.HeaderName != DefaultAPIKeyHeader {
+		t.Fatalf("unexpected default auth: %+v", got)
+	}
+	resolved := cfg.ResolveRoute(cfg.Routes[0])
+	if again := cfg.ResolveRoute(resolved); !reflect.DeepEqual(again, resolved) {
+		t.Fatalf("resolving twice changed the route: %+v", again)
+	}
+
+	// With a default provider, routes without one of their own inherit it.
+	cfg.Auth.DefaultProvider = "corp-sso"
+	if issues := cfg.Check(); len(issues) != 0 {
+		t.Fatalf("unexpected issues with a default provider: %v", issues)
+	}
+	if got := cfg.EffectiveAuth(cfg.Routes[1]); got.Type != "jwt" || got.Audience != "gw" {
+		t.Fatalf("expected the default provider, got %+v", got)
+	}
+	cfg.Auth.DefaultProvider = "corp-so"
+	if errs := cfg.Check().Errors(); len(errs) != 1 || errs[0].Path != "auth.defaultProvider" || errs[0].Hint != `did you mean "corp-sso"?` {
+		t.Fatalf("unexpected errors for an unknown default provider: %v", errs)
+	}
+
+	_, issues = CheckYAML([]byte(`apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+authProviders:
+  - {name: corp-sso, type: jwt, issuer: "https://issuer"}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - {name: a, path: /a, server: weather, auth: {use: corp-so}}
+  - {name: b, path: /b, server: weather, auth: {use: corp-sso}, policy: {use: standard}}
+`), ".")
+	errs := issues.Errors()
+	if len(errs) != 3 || errs[0].Path != "routes[0].auth.use" || errs[0].Hint != `did you mean "corp-sso"?` ||
+		errs[1].Path != "routes[1].policy.use" || errs[2].Message != `route "b" jwt auth requires issuer and audience` {
+		t.Fatalf("unexpected issues: %+v", issues)
+	}
+}
+
+func TestResolveRouteIsIdempotent(t *testing.T) {
+	cfg, issues := CheckYAML([]byte(`apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+auth: {defaultProvider: keys}
+authProviders:
+  - {name: keys, type: apiKey, headerName: X-Key, apiKeys: [secret]}
+  - {name: corp-sso, type: jwt, issuer: "https://issuer", audience: gw}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - {name: sso, path: /mcp/sso, server: weather, auth: {use: corp-sso}}
+  - {name: keyed, path: /mcp/keyed, server: weather}
+  - {name: open, path: /mcp/open, server: weather, auth: {type: none}}
+`), ".")
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+	for _, r := range cfg.Routes {
+		resolved := cfg.ResolveRoute(r)
+		if again := cfg.ResolveRoute(resolved); !reflect.DeepEqual(again, resolved) {
+			t.Fatalf("%s: resolving twice changed the auth from %+v to %+v", r.Name, *resolved.Auth, *again.Auth)
+		}
+	}
+	// The default provider's key list must not leak into a jwt route.
+	if auth := cfg.ResolveRoute(cfg.ResolveRoute(cfg.Routes[0])).Auth; auth.Type != "jwt" || len(auth.APIKeys) != 0 || auth.HeaderName != "" {
+		t.Fatalf("unexpected auth after resolving twice: %+v", *auth)
+	}
+
+	_, issues = CheckYAML([]byte(`apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+authProviders:
+  - {name: keys, type: apiKey, headerName: X-Key, apiKeys: [secret], require: true}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - {name: keyed, path: /mcp/keyed, server: weather, auth: {use: keys}}
+`), ".")
+	if errs := issues.Errors(); len(errs) != 1 || errs[0].Path != "authProviders[0].require" {
+		t.Fatalf("expected require on a provider to be rejected, got %v", issues)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net"
+	"strings"
+)
+
+// MatchHost reports how specifically the route's hosts match host, which
+// may carry a port: 2 for an exact name, 1 for a *. wildcard, 0 for a route
+// without hosts, and -1 when the route does not serve host. Requests go to
+// the most specific match, then to the longest path.
+func (r Route) MatchHost(host string) int {
+	if len(r.Hosts) == 0 {
+		return 0
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	best := -1
+	for _, h := range r.Hosts {
+		h = strings.ToLower(h)
+		if h == host {
+			return 2
+		}
+		if suffix, ok := strings.CutPrefix(h, "*"); ok && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			best = 1
+		}
+	}
+	return best
+}
+
+// UpstreamPath returns the path the upstream receives for a request path
+// under r.Path, after r.Rewrite.
+func (r Route) UpstreamPath(path string) string {
+	rw := r.Rewrite
+	switch {
+	case rw == nil:
+		return path
+	case rw.Path != "":
+		return rw.Path
+	case rw.StripPrefix || rw.ReplacePrefix != "":
+		rest := strings.TrimPrefix(path, strings.TrimSuffix(r.Path, "/"))
+		out := strings.TrimSuffix(rw.ReplacePrefix, "/") + rest
+		if !strings.HasPrefix(out, "/") {
+			out = "/" + out
+		}
+		return out
+	}
+	return path
+}
+
+// ClientHost returns a host name r serves, for clients that have to name
+// one: its first exact host, or else its first wildcard with the * filled
+// in as "mcp". It is empty when r serves every host.
+func (r Route) ClientHost() string {
+	for _, h := range r.Hosts {
+		if !strings.HasPrefix(h, "*") {
+			return strings.ToLower(h)
+		}
+	}
+	if len(r.Hosts) > 0 {
+		return "mcp" + strings.ToLower(strings.TrimPrefix(r.Hosts[0], "*"))
+	}
+	return ""
+}
+
+// hostsOverlap reports whether two routes' hosts can match the same
+// request name.
+func hostsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if hostPatternsOverlap(strings.ToLower(x), strings.ToLower(y)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hostPatternsOverlap reports whether some name matches both x and y, each
+// an exact host or a *. wildcard as MatchHost reads them.
+func hostPatternsOverlap(x, y string) bool {
+	xs, xWild := strings.CutPrefix(x, "*")
+	ys, yWild := strings.CutPrefix(y, "*")
+	switch {
+	case xWild && yWild:
+		return strings.HasSuffix(xs, ys) || strings.HasSuffix(ys, xs)
+	case xWild:
+		return strings.HasSuffix(y, xs) && len(y) > len(xs)
+	case yWild:
+		return strings.HasSuffix(x, ys) && len(x) > len(ys)
+	}
+	return x == y
+}
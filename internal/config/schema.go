@@ -0,0 +1,303 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// fieldSchema annotates one YAML field of a config struct in the JSON
+// Schema. Fields are keyed "Type.field", e.g. "Route.server".
+type fieldSchema struct {
+	description string
+	required    bool
+	enum        []string
+	def         any
+	pattern     string
+	minimum     *int
+}
+
+func atLeast(n int) *int { return &n }
+
+var fieldSchemas = map[string]fieldSchema{
+	"Config.apiVersion":    {description: "Config schema version; gateway migrate upgrades older ones.", required: true, enum: apiVersions},
+	"Config.kind":          {description: "Always GatewayConfig.", required: true, enum: []string{"GatewayConfig"}},
+	"Config.gateway":       {description: "Listener and runtime options.", required: true},
+	"Config.auth":          {description: "Secure-by-default behaviour for routes."},
+	"Config.authProviders": {description: "Named auth settings routes reference with auth.use."},
+	"Config.policies":      {description: "Named route policies routes reference with policy.use."},
+	"Config.servers":       {description: "MCP upstreams.", required: true},
+	"Config.routes":        {description: "Public paths and the servers behind them.", required: true},
+	"Config.deployment":    {description: "Tunes the Kubernetes workload rendered for the runtime."},
+	"Config.include":       {description: "More config files to merge: paths, directories of .yaml files or globs, relative to this file."},
+
+	"Gateway.name":             {description: "Gateway name, used for Kubernetes objects and labels.", required: true},
+	"Gateway.listenAddr":       {description: "Address the runtime serves clients on, e.g. :8080.", required: true},
+	"Gateway.adminAddr":        {description: "Address for /healthz, /readyz, /metrics and /admin/stdio, e.g. :9090. Metrics are not served on listenAddr."},
+	"Gateway.logLevel":         {description: "Log verbosity, e.g. info."},
+	"Gateway.websocket":        {description: "Limits for websocket clients and websocket upstreams."},
+	"Gateway.gatewayClassName": {description: "Envoy Gateway class for rendered Kubernetes resources.", def: "eg"},
+	"Gateway.secretsDir":       {description: "Directory holding Secret keys referenced by stdio server env, one directory per Secret.", def: DefaultSecretsDir},
+
+	"WebSocketSettings.maxMessageBytes": {description: "Largest websocket message accepted.", def: 1 << 20, minimum: atLeast(0)},
+	"WebSocketSettings.pingIntervalMs":  {description: "Interval between websocket pings.", def: 30000, minimum: atLeast(0)},
+
+	"AuthDefaults.requireAuth":     {description: "Routes without an auth type use apiKey auth instead of none."},
+	"AuthDefaults.defaultProvider": {description: "Auth provider routes inherit when they do not use one of their own."},
+
+	"DeploymentSettings.replicas":            {description: "Gateway pods; ignored with autoscaling.", def: 1, minimum: atLeast(0)},
+	"DeploymentSettings.resources":           {description: "Container resources. Unset values default to requests 100m/128Mi and a 512Mi memory limit."},
+	"DeploymentSettings.topologySpread":      {description: "Pod topology spread; defaults to spreading pods across nodes."},
+	"DeploymentSettings.podDisruptionBudget": {description: "Rendered whenever more than one replica can run; defaults to maxUnavailable 1."},
+	"DeploymentSettings.autoscaling":         {description: "Renders a HorizontalPodAutoscaler that owns the replica count."},
+
+	"ResourceRequirements.requests": {description: "Resource requests."},
+	"ResourceRequirements.limits":   {description: "Resource limits."},
+	"ResourceList.cpu":              {description: "CPU quantity, e.g. 250m.", pattern: quantityPattern.String()},
+	"ResourceList.memory":           {description: "Memory quantity, e.g. 256Mi.", pattern: quantityPattern.String()},
+
+	"TopologySpread.topologyKey":       {description: "Node label to spread pods over.", def: "kubernetes.io/hostname"},
+	"TopologySpread.maxSkew":           {description: "Largest allowed difference in pods between topology domains.", def: 1, minimum: atLeast(0)},
+	"TopologySpread.whenUnsatisfiable": {description: "What to do when the spread cannot be met.", enum: []string{"DoNotSchedule", "ScheduleAnyway"}, def: "ScheduleAnyway"},
+
+	"PodDisruptionBudget.minAvailable":   {description: "Pods that must stay available; excludes maxUnavailable.", minimum: atLeast(0)},
+	"PodDisruptionBudget.maxUnavailable": {description: "Pods that may be unavailable; excludes minAvailable.", minimum: atLeast(0)},
+
+	"Autoscaling.minReplicas":                       {description: "Fewest replicas.", def: 1, minimum: atLeast(0)},
+	"Autoscaling.maxReplicas":                       {description: "Most replicas; at least minReplicas.", required: true, minimum: atLeast(1)},
+	"Autoscaling.targetCPUUtilizationPercentage":    {description: "Average CPU utilization to scale at.", def: 80, minimum: atLeast(0)},
+	"Autoscaling.targetMemoryUtilizationPercentage": {description: "Average memory utilization to scale at.", minimum: atLeast(0)},
+
+	"Server.name":        {description: "Unique server name referenced by routes.", required: true},
+	"Server.transport":   {description: "How the gateway talks to the server.", required: true, enum: serverTransports},
+	"Server.url":         {description: "Endpoint of http, sse and websocket servers."},
+	"Server.command":     {description: "Executable of a stdio server."},
+	"Server.args":        {description: "Arguments of a stdio server."},
+	"Server.env":         {description: "Environment added to stdio server processes."},
+	"Server.workingDir":  {description: "Working directory of a stdio server."},
+	"Server.credentials": {description: "Values copied from the request that opens a session into that session's stdio process."},
+	"Server.image":       {description: "Image providing command when a stdio server is rendered for Kubernetes."},
+	"Server.resources":   {description: "Container resources of a stdio server rendered for Kubernetes."},
+	"Server.placement":   {description: "Run a stdio server with an image beside the gateway or as its own Deployment.", enum: []string{"sidecar", "standalone"}, def: "sidecar"},
+	"Server.sandbox":     {description: "Confines the spawned stdio process (Linux only)."},
+
+	"Sandbox.user":         {description: "uid to run as.", minimum: atLeast(0)},
+	"Sandbox.group":        {description: "gid to run as; defaults to user. Supplementary groups are always dropped.", minimum: atLeast(0)},
+	"Sandbox.envAllowlist": {description: "Gateway environment variables the process keeps."},
+	"Sandbox.workingDir":   {description: "Overrides the server's working directory."},
+	"Sandbox.limits":       {description: "Per-process resource limits."},
+	"Sandbox.namespaces":   {description: "New Linux namespaces for the process. mount alone only gives it a private copy of the same mounts."},
+	"Sandbox.seccomp":      {description: "default blocks syscalls that administer the host.", enum: []string{"default"}},
+
+	"Rlimits.cpuSeconds": {description: "CPU time limit.", minimum: atLeast(0)},
+	"Rlimits.memory":     {description: "Address space limit (RLIMIT_AS), e.g. 1Gi. Node.js servers reserve large address ranges and may not start under it.", pattern: quantityPattern.String()},
+	"Rlimits.openFiles":  {description: "Open file limit.", minimum: atLeast(0)},
+	"Rlimits.processes":  {description: "Process limit for the sandbox user.", minimum: atLeast(0)},
+
+	"EnvVar.name":      {description: "Variable name.", required: true},
+	"EnvVar.value":     {description: "Literal value."},
+	"EnvVar.fromEnv":   {description: "Variable of the gateway's own environment to copy."},
+	"EnvVar.fromFile":  {description: "File whose contents, without the trailing newline, become the value."},
+	"EnvVar.secretRef": {description: "Key of a Kubernetes Secret in the gateway's namespace."},
+
+	"SecretRef.name": {description: "Secret name.", required: true},
+	"SecretRef.key":  {description: "Key within the Secret.", required: true},
+
+	"Credential.fromHeader":  {description: "Request header to read."},
+	"Credential.fromClaim":   {description: "Claim of the bearer JWT to read. The gateway does not verify the token, so every route to the server must use jwt auth, which Envoy verifies."},
+	"Credential.toEnv":       {description: "Environment variable to set."},
+	"Credential.toInitParam": {description: "Dotted path under the initialize request's params, e.g. _meta.githubToken."},
+	"Credential.optional":    {description: "Let sessions start without the value instead of rejecting them with 401."},
+
+	"Route.name":             {description: "Unique route name.", required: true},
+	"Route.path":             {description: "Public path prefix, starting with /.", required: true, pattern: "^/"},
+	"Route.server":           {description: "Name of the server behind the route.", required: true},
+	"Route.auth":             {description: "Per-route auth; defaults follow auth.requireAuth."},
+	"Route.policy":           {description: "Baseline traffic control."},
+	"Route.clientTransports": {description: "Client-facing transports the route accepts.", def: []string{"http"}},
+	"Route.record":           {description: "Writes the route's JSON-RPC traffic to a file."},
+	"Route.hosts":            {description: "Host names the route serves; *.example.com matches any subdomain. Unset serves every host."},
+	"Route.rewrite":          {description: "Changes the path sent upstream; by default it is the request path."},
+
+	"Rewrite.stripPrefix":   {description: "Remove the route path: /mcp/weather/x is sent as /x."},
+	"Rewrite.replacePrefix": {description: "Path that replaces the route path.", pattern: "^/"},
+	"Rewrite.path":          {description: "Path every request of the route is sent to.", pattern: "^/"},
+
+	"Record.file":   {description: "JSONL file to append to.", required: true},
+	"Record.redact": {description: "Extra object keys whose values are redacted."},
+
+	"AuthProvider.name": {description: "Name routes use to reference the provider.", required: true},
+	"Policy.name":       {description: "Name routes use to reference the policy.", required: true},
+
+	"RouteAuth.use":         {description: "Auth provider to start from; the other fields override its values."},
+	"RouteAuth.type":        {description: "Auth the route enforces.", enum: authTypes},
+	"RouteAuth.require":     {description: "Deprecated and never enforced; removed in mcp.envoy.io/v1alpha2. Use type: none to turn auth off."},
+	"RouteAuth.headerName":  {description: "Header carrying the API key."},
+	"RouteAuth.apiKeys":     {description: "Accepted API keys."},
+	"RouteAuth.apiKeysFile": {description: "File of additional keys, one per line, read when the runtime starts."},
+	"RouteAuth.issuer":      {description: "Expected JWT issuer."},
+	"RouteAuth.audience":    {description: "Expected JWT audience."},
+	"RouteAuth.jwksUri":     {description: "JWKS endpoint; defaults to <issuer>/.well-known/jwks.json."},
+
+	"RoutePolicy.use":          {description: "Policy to start from; the other fields override its values."},
+	"RoutePolicy.timeoutMs":    {description: "Upstream request timeout.", minimum: atLeast(0)},
+	"RoutePolicy.retryCount":   {description: "Retries of failed upstream requests.", minimum: atLeast(0)},
+	"RoutePolicy.rateLimitRps": {description: "Requests per second allowed on the route.", minimum: atLeast(0)},
+	"RoutePolicy.allowedTools": {description: "Restricts tools/call to these tool names when set."},
+}
+
+// fieldItemSchemas annotates the elements of list fields.
+var fieldItemSchemas = map[string]fieldSchema{
+	"Route.clientTransports": {enum: clientTransports},
+	"Route.hosts":            {pattern: `^(\*\.)?[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`},
+	"Sandbox.namespaces":     {enum: []string{"mount", "pid", "network"}},
+}
+
+// omittedFields lists inlined fields a struct does not accept.
+var omittedFields = map[string][]string{
+	"AuthProvider": {"require"},
+}
+
+// typeRules holds the conditional rules of a struct's schema.
+var typeRules = map[string][]any{
+	"Server": {
+		ifThen("transport", []string{"http", "sse"}, map[string]any{"required": []string{"url"}}),
+		ifThen("transport", []string{"websocket"}, map[string]any{
+			"required":   []string{"url"},
+			"properties": map[string]any{"url": map[string]any{"pattern": "^wss?://"}},
+		}),
+		ifThen("transport", []string{"stdio"}, map[string]any{"required": []string{"command"}}),
+	},
+	"AuthProvider": {
+		map[string]any{"required": []string{"type"}},
+	},
+	"RouteAuth": {
+		map[string]any{"anyOf": []any{map[string]any{"required": []string{"type"}}, map[string]any{"required": []string{"use"}}}},
+		ifThen("type", []string{"apiKey"}, map[string]any{
+			"required": []string{"headerName"},
+			"anyOf":    []any{map[string]any{"required": []string{"apiKeys"}}, map[string]any{"required": []string{"apiKeysFile"}}},
+		}),
+		ifThen("type", []string{"jwt"}, map[string]any{"required": []string{"issuer", "audience"}}),
+	},
+}
+
+func ifThen(field string, values []string, then map[string]any) any {
+	return map[string]any{
+		"if": map[string]any{
+			"required":   []string{field},
+			"properties": map[string]any{field: map[string]any{"enum": values}},
+		},
+		"then": then,
+	}
+}
+
+// Schema returns a JSON Schema (draft-07) for config files. Editors use it
+// to complete and check gateway.yaml as it is typed; Check remains the
+// authority on semantic rules such as server references.
+func Schema() ([]byte, error) {
+	defs := map[string]any{}
+	doc := structSchema(reflect.TypeOf(Config{}), defs)
+	doc["$schema"] = "http://json-schema.org/draft-07/schema#"
+	doc["title"] = "GatewayConfig"
+	doc["description"] = "mcp-gateway-envoy gateway configuration."
+	doc["definitions"] = defs
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// schemaFor returns the schema of t, adding struct definitions to defs.
+func schemaFor(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), defs)}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := defs[name]; !ok {
+			defs[name] = nil // guards against recursion
+			defs[name] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/definitions/" + name}
+	}
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" && opts == "inline" {
+				// Inlined fields keep the descriptions of their own type.
+				addFields(f.Type)
+				continue
+			}
+			if name == "" || name == "-" {
+				continue
+			}
+			key := t.Name() + "." + name
+			fs := fieldSchemas[key]
+			s := schemaFor(f.Type, defs)
+			if items, ok := fieldItemSchemas[key]; ok {
+				s["items"] = annotate(s["items"].(map[string]any), items)
+			}
+			properties[name] = annotate(s, fs)
+			if fs.required {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+	for _, name := range omittedFields[t.Name()] {
+		delete(properties, name)
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	if rules, ok := typeRules[t.Name()]; ok {
+		s["allOf"] = rules
+	}
+	return s
+}
+
+// annotate adds fs to s. A $ref cannot carry siblings in draft-07, so
+// referenced schemas are wrapped in allOf.
+func annotate(s map[string]any, fs fieldSchema) map[string]any {
+	if _, ok := s["$ref"]; ok && fs.description != "" {
+		s = map[string]any{"allOf": []any{s}}
+	}
+	if fs.description != "" {
+		s["description"] = fs.description
+	}
+	if len(fs.enum) > 0 {
+		s["enum"] = fs.enum
+	}
+	if fs.def != nil {
+		s["default"] = fs.def
+	}
+	if fs.pattern != "" {
+		s["pattern"] = fs.pattern
+	}
+	if fs.minimum != nil {
+		s["minimum"] = *fs.minimum
+	}
+	return s
+}
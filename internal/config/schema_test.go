@@ -0,0 +1,275 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const schemaBaseYAML = `apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080", websocket: {pingIntervalMs: 1000}}
+auth: {requireAuth: true}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+  - name: fs
+    transport: stdio
+    command: npx
+    env: [{name: TOKEN, secretRef: {name: fs, key: token}}]
+    sandbox: {namespaces: [mount], limits: {memory: 512Mi}}
+routes:
+  - name: weather
+    path: /mcp/weather
+    server: weather
+    clientTransports: [http, sse]
+    auth: {type: jwt, issuer: "https://issuer", audience: gw}
+  - name: fs
+    path: /mcp/fs
+    server: fs
+    auth: {type: apiKey, headerName: X-API-Key, apiKeys: [k]}
+    record: {file: fs.jsonl}
+deployment:
+  resources: {requests: {cpu: 250m}}
+  autoscaling: {minReplicas: 2, maxReplicas: 4}
+`
+
+// TestSchemaAgreesWithValidate checks that configs the schema rejects are
+// rejected by CheckYAML too.
+func TestSchemaAgreesWithValidate(t *testing.T) {
+	b, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	mutations := map[string]func(doc map[string]any){
+		"valid": func(map[string]any) {},
+		"unknown transport": func(doc map[string]any) {
+			at(doc, "servers", 0)["transport"] = "grpc"
+		},
+		"http without url": func(doc map[string]any) {
+			delete(at(doc, "servers", 0), "url")
+		},
+		"websocket with http url": func(doc map[string]any) {
+			at(doc, "servers", 0)["transport"] = "websocket"
+		},
+		"stdio without command": func(doc map[string]any) {
+			delete(at(doc, "servers", 1), "command")
+		},
+		"unknown auth type": func(doc map[string]any) {
+			at(doc, "routes", 0)["auth"] = map[string]any{"type": "basic"}
+		},
+		"jwt without audience": func(doc map[string]any) {
+			delete(at(doc, "routes", 0)["auth"].(map[string]any), "audience")
+		},
+		"apiKey without keys": func(doc map[string]any) {
+			delete(at(doc, "routes", 1)["auth"].(map[string]any), "apiKeys")
+		},
+		"unknown client transport": func(doc map[string]any) {
+			at(doc, "routes", 0)["clientTransports"] = []any{"grpc"}
+		},
+		"route without server": func(doc map[string]any) {
+			delete(at(doc, "routes", 0), "server")
+		},
+		"relative path": func(doc map[string]any) {
+			at(doc, "routes", 0)["path"] = "mcp"
+		},
+		"record without file": func(doc map[string]any) {
+			at(doc, "routes", 1)["record"] = map[string]any{}
+		},
+		"unknown field": func(doc map[string]any) {
+			at(doc, "routes", 0)["sever"] = "weather"
+		},
+		"negative ping interval": func(doc map[string]any) {
+			doc["gateway"].(map[string]any)["websocket"] = map[string]any{"pingIntervalMs": -1}
+		},
+		"bad quantity": func(doc map[string]any) {
+			doc["deployment"].(map[string]any)["resources"] = map[string]any{"limits": map[string]any{"memory": "lots"}}
+		},
+		"zero maxReplicas": func(doc map[string]any) {
+			doc["deployment"].(map[string]any)["autoscaling"] = map[string]any{"maxReplicas": 0}
+		},
+		"unknown namespace": func(doc map[string]any) {
+			at(doc, "servers", 1)["sandbox"] = map[string]any{"namespaces": []any{"user"}}
+		},
+		"secretRef without key": func(doc map[string]any) {
+			at(doc, "servers", 1)["env"] = []any{map[string]any{"name": "T", "secretRef": map[string]any{"name": "fs"}}}
+		},
+		"wrong kind": func(doc map[string]any) {
+			doc["kind"] = "Gateway"
+		},
+		"missing gateway": func(doc map[string]any) {
+			delete(doc, "gateway")
+		},
+	}
+	for name, mutate := range mutations {
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(schemaBaseYAML), &doc); err != nil {
+			t.Fatal(err)
+		}
+		mutate(doc)
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemaErrs := evaluate(schema, schema, doc, "")
+		_, issues := CheckYAML(out, ".")
+		if schemaValid, valid := len(schemaErrs) == 0, len(issues.Errors()) == 0; schemaValid != valid || valid != (name == "valid") {
+			t.Errorf("%s: schema errors %v, validation errors %v", name, schemaErrs, issues.Errors())
+		}
+	}
+}
+
+func at(doc map[string]any, list string, i int) map[string]any {
+	return doc[list].([]any)[i].(map[string]any)
+}
+
+func TestSchemaFileIsCurrent(t *testing.T) {
+	want, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("../../deploy/schema/gateway.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("deploy/schema/gateway.schema.json is stale; run: go run ./cmd/gateway schema --output deploy/schema/gateway.schema.json")
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(want, &schema); err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]any{"Config": schema}
+	for name, def := range schema["definitions"].(map[string]any) {
+		types[name] = def
+	}
+	for typ, def := range types {
+		for field, prop := range def.(map[string]any)["properties"].(map[string]any) {
+			if _, ok := prop.(map[string]any)["description"]; !ok {
+				t.Errorf("%s.%s has no schema description", typ, field)
+			}
+		}
+	}
+}
+
+// evaluate checks v against the draft-07 keywords the generated schema
+// uses and returns the violations.
+func evaluate(root, s map[string]any, v any, path string) []string {
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		def := root["definitions"].(map[string]any)[strings.TrimPrefix(ref, "#/definitions/")]
+		return evaluate(root, def.(map[string]any), v, path)
+	}
+	for _, sub := range list(s["allOf"]) {
+		errs = append(errs, evaluate(root, sub.(map[string]any), v, path)...)
+	}
+	if anyOf := list(s["anyOf"]); len(anyOf) > 0 {
+		matched := false
+		for _, sub := range anyOf {
+			matched = matched || len(evaluate(root, sub.(map[string]any), v, path)) == 0
+		}
+		if !matched {
+			fail("matches none of anyOf")
+		}
+	}
+	if cond, ok := s["if"].(map[string]any); ok && len(evaluate(root, cond, v, path)) == 0 {
+		errs = append(errs, evaluate(root, s["then"].(map[string]any), v, path)...)
+	}
+	switch s["type"] {
+	case "object":
+		if _, ok := v.(map[string]any); !ok {
+			fail("not an object")
+			return errs
+		}
+	case "array":
+		if _, ok := v.([]any); !ok {
+			fail("not an array")
+			return errs
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			fail("not a string")
+			return errs
+		}
+	case "integer":
+		if _, ok := v.(int); !ok {
+			fail("not an integer")
+			return errs
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("not a boolean")
+			return errs
+		}
+	}
+	if enum := list(s["enum"]); len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, v)
+		}
+		if !found {
+			fail("%v not in enum", v)
+		}
+	}
+	if p, ok := s["pattern"].(string); ok {
+		if str, isString := v.(string); isString && !regexp.MustCompile(p).MatchString(str) {
+			fail("%q does not match %s", str, p)
+		}
+	}
+	if m, ok := s["minimum"].(float64); ok {
+		if n, isInt := v.(int); isInt && float64(n) < m {
+			fail("%d below minimum %v", n, m)
+		}
+	}
+	if obj, ok := v.(map[string]any); ok {
+		for _, r := range list(s["required"]) {
+			if _, ok := obj[r.(string)]; !ok {
+				fail("missing %s", r)
+			}
+		}
+		props, _ := s["properties"].(map[string]any)
+		for k, val := range obj {
+			if ps, ok := props[k].(map[string]any); ok {
+				errs = append(errs, evaluate(root, ps, val, path+"."+k)...)
+			} else if s["additionalProperties"] == false {
+				fail("unknown property %s", k)
+			}
+		}
+	}
+	if arr, ok := v.([]any); ok {
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, e := range arr {
+				errs = append(errs, evaluate(root, items, e, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+func list(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case []string:
+		out := make([]any, len(v))
+		for i, s := range v {
+			out[i] = s
+		}
+		return out
+	}
+	return nil
+}
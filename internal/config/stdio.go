@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSecretsDir is where the runtime reads secretRef env values when
+// gateway.secretsDir is unset.
+const DefaultSecretsDir = "/var/run/mcp-gateway/secrets"
+
+// SecretPath returns the file the runtime reads ref from.
+func (g Gateway) SecretPath(ref SecretRef) string {
+	dir := g.SecretsDir
+	if dir == "" {
+		dir = DefaultSecretsDir
+	}
+	return filepath.Join(dir, ref.Name, ref.Key)
+}
+
+// ResolveEnv returns the server's env entries as NAME=value pairs, reading
+// the fromEnv, fromFile and secretRef sources.
+func (s Server) ResolveEnv(g Gateway) ([]string, error) {
+	env := make([]string, 0, len(s.Env))
+	for _, e := range s.Env {
+		value := e.Value
+		switch {
+		case e.FromEnv != "":
+			v, ok := os.LookupEnv(e.FromEnv)
+			if !ok {
+				return nil, fmt.Errorf("server %q env %s: %s is not set", s.Name, e.Name, e.FromEnv)
+			}
+			value = v
+		case e.FromFile != "":
+			b, err := os.ReadFile(e.FromFile)
+			if err != nil {
+				return nil, fmt.Errorf("server %q env %s: %w", s.Name, e.Name, err)
+			}
+			value = strings.TrimRight(string(b), "\r\n")
+		case e.SecretRef != nil:
+			b, err := os.ReadFile(g.SecretPath(*e.SecretRef))
+			if err != nil {
+				return nil, fmt.Errorf("server %q env %s: read secret %s/%s: %w", s.Name, e.Name, e.SecretRef.Name, e.SecretRef.Key, err)
+			}
+			value = strings.TrimRight(string(b), "\r\n")
+		}
+		env = append(env, e.Name+"="+value)
+	}
+	return env, nil
+}
+
+// ParseCredential parses the compact form used by "gateway adapt
+// --credential": header:NAME or claim:NAME, "=", then env:NAME or
+// param:PATH, optionally followed by ",optional".
+func ParseCredential(s string) (Credential, error) {
+	var c Credential
+	rest, optional := strings.CutSuffix(s, ",optional")
+	c.Optional = optional
+	from, to, ok := strings.Cut(rest, "=")
+	if !ok {
+		return c, fmt.Errorf("credential %q must look like header:NAME=env:NAME", s)
+	}
+	kind, name, _ := strings.Cut(from, ":")
+	switch kind {
+	case "header":
+		c.FromHeader = name
+	case "claim":
+		c.FromClaim = name
+	default:
+		return c, fmt.Errorf("credential %q must start with header: or claim:", s)
+	}
+	kind, name, _ = strings.Cut(to, ":")
+	switch kind {
+	case "env":
+		c.ToEnv = name
+	case "param":
+		c.ToInitParam = name
+	default:
+		return c, fmt.Errorf("credential %q must target env: or param:", s)
+	}
+	return c, c.validate()
+}
+
+// String returns the form ParseCredential reads.
+func (c Credential) String() string {
+	from := "header:" + c.FromHeader
+	if c.FromClaim != "" {
+		from = "claim:" + c.FromClaim
+	}
+	to := "env:" + c.ToEnv
+	if c.ToInitParam != "" {
+		to = "param:" + c.ToInitParam
+	}
+	s := from + "=" + to
+	if c.Optional {
+		s += ",optional"
+	}
+	return s
+}
+
+func (c Credential) validate() error {
+	if (c.FromHeader == "") == (c.FromClaim == "") {
+		return fmt.Errorf("credential needs exactly one of fromHeader and fromClaim")
+	}
+	if (c.ToEnv == "") == (c.ToInitParam == "") {
+		return fmt.Errorf("credential needs exactly one of toEnv and toInitParam")
+	}
+	if strings.Contains(c.ToEnv, "=") {
+		return fmt.Errorf("credential toEnv %q must not contain '='", c.ToEnv)
+	}
+	if c.ToInitParam != "" {
+		for _, part := range strings.Split(c.ToInitParam, ".") {
+			if part == "" {
+				return fmt.Errorf("credential toInitParam %q has an empty segment", c.ToInitParam)
+			}
+		}
+	}
+	return nil
+}
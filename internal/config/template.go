@@ -17,6 +17,7 @@ servers:
     transport: stdio
     command: npx
     args: ["-y", "@modelcontextprotocol/server-filesystem", "/tmp"]
+    image: node:22-slim   # runs as a sidecar when rendered for Kubernetes
 routes:
   - name: weather
     path: /mcp/weather
@@ -6,30 +6,187 @@ type Config struct {
 	Kind       string       `yaml:"kind"`
 	Gateway    Gateway      `yaml:"gateway"`
 	Auth       AuthDefaults `yaml:"auth"`
-	Servers    []Server     `yaml:"servers"`
-	Routes     []Route      `yaml:"routes"`
+	// AuthProviders and Policies are named auth and policy settings that
+	// routes reference with use and may override field by field.
+	AuthProviders []AuthProvider `yaml:"authProviders,omitempty"`
+	Policies      []Policy       `yaml:"policies,omitempty"`
+	Servers       []Server       `yaml:"servers"`
+	Routes        []Route        `yaml:"routes"`
+	// Deployment tunes the Kubernetes workload rendered for the runtime.
+	Deployment DeploymentSettings `yaml:"deployment,omitempty"`
+	// Include lists more config files to merge: paths, directories of
+	// .yaml files or globs, relative to this file. Included files usually
+	// hold only servers and routes.
+	Include []string `yaml:"include,omitempty"`
+
+	// secrets holds the values interpolation took from files or from
+	// secret-looking variables; see SecretValues.
+	secrets []string
 }
 
 // Gateway contains listener and runtime options.
 type Gateway struct {
-	Name       string `yaml:"name"`
-	ListenAddr string `yaml:"listenAddr"`
-	AdminAddr  string `yaml:"adminAddr"`
-	LogLevel   string `yaml:"logLevel"`
+	Name       string            `yaml:"name"`
+	ListenAddr string            `yaml:"listenAddr"`
+	AdminAddr  string            `yaml:"adminAddr"`
+	LogLevel   string            `yaml:"logLevel"`
+	WebSocket  WebSocketSettings `yaml:"websocket,omitempty"`
+	// GatewayClassName selects the Envoy Gateway class for rendered
+	// Kubernetes resources (default eg).
+	GatewayClassName string `yaml:"gatewayClassName,omitempty"`
+	// SecretsDir holds Secret keys referenced by stdio server env, one
+	// directory per Secret (default /var/run/mcp-gateway/secrets).
+	SecretsDir string `yaml:"secretsDir,omitempty"`
+}
+
+// WebSocketSettings applies to websocket clients and websocket upstreams.
+type WebSocketSettings struct {
+	MaxMessageBytes int64 `yaml:"maxMessageBytes,omitempty"` // default 1MiB
+	PingIntervalMs  int   `yaml:"pingIntervalMs,omitempty"`  // default 30000
+}
+
+// DeploymentSettings shapes the rendered Deployment and its companions.
+type DeploymentSettings struct {
+	Replicas  int                  `yaml:"replicas,omitempty"` // default 1; ignored with autoscaling
+	Resources ResourceRequirements `yaml:"resources,omitempty"`
+	// TopologySpread defaults to spreading pods across nodes
+	// (kubernetes.io/hostname, maxSkew 1, ScheduleAnyway).
+	TopologySpread *TopologySpread `yaml:"topologySpread,omitempty"`
+	// PodDisruptionBudget is rendered whenever more than one replica can
+	// run; it defaults to maxUnavailable 1.
+	PodDisruptionBudget *PodDisruptionBudget `yaml:"podDisruptionBudget,omitempty"`
+	Autoscaling         *Autoscaling         `yaml:"autoscaling,omitempty"`
+}
+
+// ResourceRequirements mirrors the Kubernetes container resources block.
+// Unset values default to requests 100m/128Mi and a 512Mi memory limit.
+type ResourceRequirements struct {
+	Requests ResourceList `yaml:"requests,omitempty"`
+	Limits   ResourceList `yaml:"limits,omitempty"`
+}
+
+// ResourceList holds Kubernetes quantities such as 250m or 256Mi.
+type ResourceList struct {
+	CPU    string `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// TopologySpread configures the pod topology spread constraint.
+type TopologySpread struct {
+	TopologyKey       string `yaml:"topologyKey,omitempty"`
+	MaxSkew           int    `yaml:"maxSkew,omitempty"`
+	WhenUnsatisfiable string `yaml:"whenUnsatisfiable,omitempty"` // DoNotSchedule or ScheduleAnyway
+}
+
+// PodDisruptionBudget sets either minAvailable or maxUnavailable.
+type PodDisruptionBudget struct {
+	MinAvailable   int `yaml:"minAvailable,omitempty"`
+	MaxUnavailable int `yaml:"maxUnavailable,omitempty"`
+}
+
+// Autoscaling renders a HorizontalPodAutoscaler that owns the replica count.
+type Autoscaling struct {
+	MinReplicas                       int `yaml:"minReplicas,omitempty"` // default 1
+	MaxReplicas                       int `yaml:"maxReplicas"`
+	TargetCPUUtilizationPercentage    int `yaml:"targetCPUUtilizationPercentage,omitempty"` // default 80
+	TargetMemoryUtilizationPercentage int `yaml:"targetMemoryUtilizationPercentage,omitempty"`
 }
 
 // AuthDefaults sets secure-by-default behavior.
 type AuthDefaults struct {
 	RequireAuth bool `yaml:"requireAuth"`
+	// DefaultProvider names the auth provider routes inherit when they do
+	// not use one of their own.
+	DefaultProvider string `yaml:"defaultProvider,omitempty"`
 }
 
 // Server defines an MCP upstream.
 type Server struct {
 	Name      string   `yaml:"name"`
-	Transport string   `yaml:"transport"` // http or stdio
+	Transport string   `yaml:"transport"` // http, sse, websocket, or stdio
 	URL       string   `yaml:"url,omitempty"`
 	Command   string   `yaml:"command,omitempty"`
 	Args      []string `yaml:"args,omitempty"`
+	// Env is added to the environment of stdio server processes.
+	Env []EnvVar `yaml:"env,omitempty"`
+	// WorkingDir is the stdio server's working directory.
+	WorkingDir string `yaml:"workingDir,omitempty"`
+	// Credentials copy values from the request that opens a session into
+	// that session's stdio process.
+	Credentials []Credential `yaml:"credentials,omitempty"`
+	// Image, Resources and Placement apply to stdio servers rendered for
+	// Kubernetes. The image must provide Command; the gateway runs it behind
+	// a stdio-to-HTTP adapter, either as a sidecar of the gateway pod or as
+	// its own Deployment and Service.
+	Image     string               `yaml:"image,omitempty"`
+	Resources ResourceRequirements `yaml:"resources,omitempty"`
+	Placement string               `yaml:"placement,omitempty"` // sidecar (default) or standalone
+	// Sandbox confines the spawned process (Linux only).
+	Sandbox *Sandbox `yaml:"sandbox,omitempty"`
+}
+
+// Sandbox confines a stdio server process. Any sandbox clears the child's
+// environment down to EnvAllowlist plus the server's env entries. Switching
+// user and creating namespaces need the gateway to run as root.
+type Sandbox struct {
+	User         *int     `yaml:"user,omitempty"`  // uid
+	Group        *int     `yaml:"group,omitempty"` // gid; defaults to User
+	EnvAllowlist []string `yaml:"envAllowlist,omitempty"`
+	WorkingDir   string   `yaml:"workingDir,omitempty"` // overrides the server's
+	Limits       Rlimits  `yaml:"limits,omitempty"`
+	// Namespaces lists new Linux namespaces for the child: mount, pid and
+	// network. pid together with mount also remounts /proc. mount alone
+	// only gives the child a private copy of the same mounts.
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	// Seccomp "default" blocks syscalls that administer the host (mount,
+	// ptrace, module loading, kexec, bpf and similar) and creating or
+	// joining namespaces: unshare, setns and clone with CLONE_NEW* flags.
+	Seccomp string `yaml:"seccomp,omitempty"`
+}
+
+// Rlimits are per-process resource limits; zero leaves a limit unchanged.
+type Rlimits struct {
+	CPUSeconds int `yaml:"cpuSeconds,omitempty"`
+	// Memory caps address space (RLIMIT_AS), e.g. 1Gi, not resident memory.
+	// V8-based servers reserve more than they use and may not start.
+	Memory    string `yaml:"memory,omitempty"`
+	OpenFiles int    `yaml:"openFiles,omitempty"`
+	// Processes caps processes for the sandbox user (RLIMIT_NPROC counts
+	// every process of that uid).
+	Processes int `yaml:"processes,omitempty"`
+}
+
+// EnvVar is one environment variable for a stdio server. At most one source
+// is set: Value, FromEnv (a variable of the gateway's own environment),
+// FromFile (the file's contents without the trailing newline) or SecretRef.
+type EnvVar struct {
+	Name      string     `yaml:"name"`
+	Value     string     `yaml:"value,omitempty"`
+	FromEnv   string     `yaml:"fromEnv,omitempty"`
+	FromFile  string     `yaml:"fromFile,omitempty"`
+	SecretRef *SecretRef `yaml:"secretRef,omitempty"`
+}
+
+// SecretRef names a key of a Kubernetes Secret in the gateway's namespace.
+// The runtime reads it from <gateway.secretsDir>/<name>/<key>, where
+// rendered Deployments mount the Secret.
+type SecretRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// Credential takes a request header or a claim of the bearer JWT and passes
+// it to a stdio server as an env var or as an initialize parameter.
+type Credential struct {
+	FromHeader string `yaml:"fromHeader,omitempty"`
+	FromClaim  string `yaml:"fromClaim,omitempty"`
+	ToEnv      string `yaml:"toEnv,omitempty"`
+	// ToInitParam is a dotted path under the initialize request's params,
+	// such as _meta.githubToken.
+	ToInitParam string `yaml:"toInitParam,omitempty"`
+	// Optional lets a session start without the value; otherwise it is
+	// rejected with 401.
+	Optional bool `yaml:"optional,omitempty"`
 }
 
 // Route maps a public path to an upstream server.
@@ -39,21 +196,88 @@ type Route struct {
 	Server string      `yaml:"server"`
 	Auth   *RouteAuth  `yaml:"auth,omitempty"`
 	Policy RoutePolicy `yaml:"policy"`
+	// ClientTransports lists the client-facing transports the route accepts:
+	// http (streamable HTTP, the default), sse (legacy GET <path>/sse plus
+	// POST <path>/messages), and websocket (upgrade on <path>).
+	ClientTransports []string `yaml:"clientTransports,omitempty"`
+	// Record writes the route's JSON-RPC traffic to a file.
+	Record *Record `yaml:"record,omitempty"`
+	// Hosts limits the route to requests for these host names; *.example.com
+	// matches any subdomain. Without hosts the route serves every host.
+	Hosts []string `yaml:"hosts,omitempty"`
+	// Rewrite changes the path sent upstream; by default it is the request
+	// path.
+	Rewrite *Rewrite `yaml:"rewrite,omitempty"`
+}
+
+// Rewrite sets the upstream path of a route. At most one field is set.
+type Rewrite struct {
+	// StripPrefix removes the route path: /mcp/weather/x becomes /x.
+	StripPrefix bool `yaml:"stripPrefix,omitempty"`
+	// ReplacePrefix puts this path in place of the route path.
+	ReplacePrefix string `yaml:"replacePrefix,omitempty"`
+	// Path sends every request of the route to this path.
+	Path string `yaml:"path,omitempty"`
+}
+
+// Record configures a route recording: one JSON line per message exchanged
+// with the upstream, with secret-looking values redacted.
+type Record struct {
+	File string `yaml:"file"`
+	// Redact lists extra object keys whose values are redacted.
+	Redact []string `yaml:"redact,omitempty"`
+}
+
+// AcceptsClientTransport reports whether the route serves clients using transport.
+func (r Route) AcceptsClientTransport(transport string) bool {
+	if len(r.ClientTransports) == 0 {
+		return transport == "http"
+	}
+	for _, t := range r.ClientTransports {
+		if t == transport {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthProvider is a named auth setting routes share.
+type AuthProvider struct {
+	Name      string `yaml:"name"`
+	RouteAuth `yaml:",inline"`
+}
+
+// Policy is a named route policy routes share.
+type Policy struct {
+	Name        string `yaml:"name"`
+	RoutePolicy `yaml:",inline"`
 }
 
 // RouteAuth allows per-route auth overrides.
 type RouteAuth struct {
-	Type       string   `yaml:"type"` // apiKey, jwt, none
+	// Use names an auth provider; the other fields override its values.
+	Use  string `yaml:"use,omitempty"`
+	Type string `yaml:"type,omitempty"` // apiKey, jwt, none
+	// Require is deprecated: it was never enforced and goes away in
+	// v1alpha2. gateway migrate removes it.
 	Require    *bool    `yaml:"require,omitempty"`
 	HeaderName string   `yaml:"headerName,omitempty"`
 	APIKeys    []string `yaml:"apiKeys,omitempty"`
-	Issuer     string   `yaml:"issuer,omitempty"`
-	Audience   string   `yaml:"audience,omitempty"`
+	// APIKeysFile names a file of additional keys, one per line, read when
+	// the runtime starts (for keys mounted from a Kubernetes Secret).
+	APIKeysFile string `yaml:"apiKeysFile,omitempty"`
+	Issuer      string `yaml:"issuer,omitempty"`
+	Audience    string `yaml:"audience,omitempty"`
+	JWKSURI     string `yaml:"jwksUri,omitempty"` // default <issuer>/.well-known/jwks.json
 }
 
 // RoutePolicy contains baseline traffic control settings.
 type RoutePolicy struct {
-	TimeoutMs    int `yaml:"timeoutMs"`
-	RetryCount   int `yaml:"retryCount"`
-	RateLimitRPS int `yaml:"rateLimitRps"`
+	// Use names a policy; the other fields override its values.
+	Use          string `yaml:"use,omitempty"`
+	TimeoutMs    int    `yaml:"timeoutMs"`
+	RetryCount   int    `yaml:"retryCount"`
+	RateLimitRPS int    `yaml:"rateLimitRps"`
+	// AllowedTools restricts tools/call to the listed tool names when set.
+	AllowedTools []string `yaml:"allowedTools,omitempty"`
 }
@@ -2,89 +2,465 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
-// Validate applies schema and semantic validation rules.
+// quantityPattern matches the Kubernetes resource quantities users write.
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|Ki|M|Mi|G|Gi|T|Ti|P|Pi|E|Ei)?$`)
+
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+var dnsSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+var (
+	serverTransports = []string{"http", "sse", "websocket", "stdio"}
+	clientTransports = []string{"http", "sse", "websocket"}
+	authTypes        = []string{"apiKey", "jwt", "none"}
+)
+
+// Validate applies schema and semantic validation rules. It returns the
+// errors of Check as Issues, or nil when there are none.
 func (c Config) Validate() error {
-	if c.APIVersion != "mcp.envoy.io/v1alpha1" {
-		return fmt.Errorf("apiVersion must be mcp.envoy.io/v1alpha1")
+	if errs := c.Check().Errors(); len(errs) > 0 {
+		return errs
 	}
+	return nil
+}
+
+// Check returns every error and warning in the config. The issues have
+// paths but no positions; CheckYAML and CheckFile add those.
+func (c Config) Check() Issues {
+	return c.check(nil)
+}
+
+func (c Config) check(position func(path string) string) Issues {
+	v := &validator{position: position}
+	v.checkVersion(c.APIVersion)
 	if c.Kind != "GatewayConfig" {
-		return fmt.Errorf("kind must be GatewayConfig")
+		v.errorf("kind", "kind must be GatewayConfig")
 	}
 	if strings.TrimSpace(c.Gateway.Name) == "" {
-		return fmt.Errorf("gateway.name is required")
+		v.errorf("gateway.name", "gateway.name is required")
 	}
 	if strings.TrimSpace(c.Gateway.ListenAddr) == "" {
-		return fmt.Errorf("gateway.listenAddr is required")
+		v.errorf("gateway.listenAddr", "gateway.listenAddr is required")
 	}
+	if c.Gateway.WebSocket.MaxMessageBytes < 0 || c.Gateway.WebSocket.PingIntervalMs < 0 {
+		v.errorf("gateway.websocket", "gateway.websocket values must be >= 0")
+	}
+	c.Deployment.validate(v)
 	if len(c.Servers) == 0 {
-		return fmt.Errorf("servers must include at least one server")
+		v.errorf("servers", "servers must include at least one server")
 	}
 	if len(c.Routes) == 0 {
-		return fmt.Errorf("routes must include at least one route")
+		v.errorf("routes", "routes must include at least one route")
 	}
 
-	seenServers := map[string]struct{}{}
-	for _, s := range c.Servers {
+	var serverNames []string
+	seenServers := map[string]string{} // name -> path of its first definition
+	for i, s := range c.Servers {
+		path := fmt.Sprintf("servers[%d]", i)
 		if strings.TrimSpace(s.Name) == "" {
-			return fmt.Errorf("servers[].name is required")
-		}
-		if _, ok := seenServers[s.Name]; ok {
-			return fmt.Errorf("duplicate server name: %s", s.Name)
+			v.errorf(path+".name", "servers[].name is required")
+		} else if first, ok := seenServers[s.Name]; ok {
+			v.errorf(path+".name", "duplicate server name: %s", s.Name).Hint = "first defined at " + v.describe(first)
+		} else {
+			seenServers[s.Name] = path + ".name"
 		}
-		seenServers[s.Name] = struct{}{}
+		serverNames = append(serverNames, s.Name)
 
 		switch s.Transport {
-		case "http":
+		case "http", "sse":
 			if strings.TrimSpace(s.URL) == "" {
-				return fmt.Errorf("server %q transport http requires url", s.Name)
+				v.errorf(path+".url", "server %q transport %s requires url", s.Name, s.Transport)
+			}
+		case "websocket":
+			if !strings.HasPrefix(s.URL, "ws://") && !strings.HasPrefix(s.URL, "wss://") {
+				v.errorf(path+".url", "server %q transport websocket requires a ws:// or wss:// url", s.Name)
 			}
 		case "stdio":
 			if strings.TrimSpace(s.Command) == "" {
-				return fmt.Errorf("server %q transport stdio requires command", s.Name)
+				v.errorf(path+".command", "server %q transport stdio requires command", s.Name)
 			}
+			s.validateStdio(v, path)
 		default:
-			return fmt.Errorf("server %q has unsupported transport %q", s.Name, s.Transport)
+			v.errorf(path+".transport", "server %q has unsupported transport %q", s.Name, s.Transport).
+				suggest(s.Transport, serverTransports)
+		}
+		if s.Transport != "stdio" && (s.Image != "" || s.Placement != "" || len(s.Env) > 0 || s.WorkingDir != "" || len(s.Credentials) > 0) {
+			v.errorf(path, "server %q: image, placement, env, workingDir and credentials apply only to stdio servers", s.Name)
 		}
 	}
 
-	seenRoutes := map[string]struct{}{}
-	for _, r := range c.Routes {
-		if strings.TrimSpace(r.Name) == "" {
-			return fmt.Errorf("routes[].name is required")
+	providerNames, policyNames := c.checkProfiles(v)
+	usedServers := map[string]bool{}
+	usedProviders := map[string]bool{}
+	usedPolicies := map[string]bool{}
+	if p := c.Auth.DefaultProvider; p != "" {
+		if _, ok := c.authProvider(p); ok {
+			usedProviders[p] = true
+		} else {
+			v.errorf("auth.defaultProvider", "auth.defaultProvider references unknown auth provider %q", p).
+				suggest(p, providerNames)
 		}
-		if _, ok := seenRoutes[r.Name]; ok {
-			return fmt.Errorf("duplicate route name: %s", r.Name)
+	}
+	routePaths := map[string][]int{}  // path -> indexes of the routes serving it
+	unverified := map[string]string{} // server -> first route to it without jwt auth
+	seenRoutes := map[string]string{}
+	for i, r := range c.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		if strings.TrimSpace(r.Name) == "" {
+			v.errorf(path+".name", "routes[].name is required")
+		} else if first, ok := seenRoutes[r.Name]; ok {
+			v.errorf(path+".name", "duplicate route name: %s", r.Name).Hint = "first defined at " + v.describe(first)
+		} else {
+			seenRoutes[r.Name] = path + ".name"
 		}
-		seenRoutes[r.Name] = struct{}{}
 
 		if strings.TrimSpace(r.Path) == "" || !strings.HasPrefix(r.Path, "/") {
-			return fmt.Errorf("route %q path must start with '/'", r.Name)
+			v.errorf(path+".path", "route %q path must start with '/'", r.Name)
+		} else {
+			for _, other := range routePaths[r.Path] {
+				if hostsOverlap(r.Hosts, c.Routes[other].Hosts) {
+					v.warnf(path+".path", "route %q path %s is already served by route %q", r.Name, r.Path, c.Routes[other].Name).
+						Hint = "first defined at " + v.describe(fmt.Sprintf("routes[%d].path", other))
+					break
+				}
+			}
+			routePaths[r.Path] = append(routePaths[r.Path], i)
+		}
+		for j, h := range r.Hosts {
+			if name := strings.TrimPrefix(h, "*."); !dnsSubdomainPattern.MatchString(name) {
+				v.errorf(fmt.Sprintf("%s.hosts[%d]", path, j), "route %q host %q must be a lowercase host name without a port, optionally starting with *.", r.Name, h)
+			}
+		}
+		if rw := r.Rewrite; rw != nil {
+			set := 0
+			for _, on := range []bool{rw.StripPrefix, rw.ReplacePrefix != "", rw.Path != ""} {
+				if on {
+					set++
+				}
+			}
+			if set != 1 {
+				v.errorf(path+".rewrite", "route %q rewrite must set one of stripPrefix, replacePrefix or path", r.Name)
+			}
+			if rw.ReplacePrefix != "" && !strings.HasPrefix(rw.ReplacePrefix, "/") {
+				v.errorf(path+".rewrite.replacePrefix", "route %q rewrite.replacePrefix must start with '/'", r.Name)
+			}
+			if rw.Path != "" && !strings.HasPrefix(rw.Path, "/") {
+				v.errorf(path+".rewrite.path", "route %q rewrite.path must start with '/'", r.Name)
+			}
+		}
+		usedServers[r.Server] = true
+		if _, ok := unverified[r.Server]; !ok && c.EffectiveAuth(r).Type != "jwt" {
+			unverified[r.Server] = r.Name
 		}
 		if _, ok := seenServers[r.Server]; !ok {
-			return fmt.Errorf("route %q references unknown server %q", r.Name, r.Server)
+			v.errorf(path+".server", "route %q references unknown server %q", r.Name, r.Server).
+				suggest(r.Server, serverNames)
 		}
-		if r.Policy.TimeoutMs < 0 || r.Policy.RetryCount < 0 || r.Policy.RateLimitRPS < 0 {
-			return fmt.Errorf("route %q policy values must be >= 0", r.Name)
+		for j, t := range r.ClientTransports {
+			switch t {
+			case "http", "sse", "websocket":
+			default:
+				v.errorf(fmt.Sprintf("%s.clientTransports[%d]", path, j), "route %q has unsupported client transport %q", r.Name, t).
+					suggest(t, clientTransports)
+			}
 		}
-		if r.Auth != nil {
-			switch r.Auth.Type {
-			case "apiKey":
-				if strings.TrimSpace(r.Auth.HeaderName) == "" || len(r.Auth.APIKeys) == 0 {
-					return fmt.Errorf("route %q apiKey auth requires headerName and apiKeys", r.Name)
-				}
-			case "jwt":
-				if strings.TrimSpace(r.Auth.Issuer) == "" || strings.TrimSpace(r.Auth.Audience) == "" {
-					return fmt.Errorf("route %q jwt auth requires issuer and audience", r.Name)
+		if r.Record != nil && strings.TrimSpace(r.Record.File) == "" {
+			v.errorf(path+".record.file", "route %q record requires file", r.Name)
+		}
+		if r.Policy.Use != "" {
+			if _, ok := c.policy(r.Policy.Use); ok {
+				usedPolicies[r.Policy.Use] = true
+			} else {
+				v.errorf(path+".policy.use", "route %q uses unknown policy %q", r.Name, r.Policy.Use).
+					suggest(r.Policy.Use, policyNames)
+			}
+		}
+		if p := c.EffectivePolicy(r); p.TimeoutMs < 0 || p.RetryCount < 0 || p.RateLimitRPS < 0 {
+			v.errorf(path+".policy", "route %q policy values must be >= 0", r.Name)
+		}
+		provider := c.Auth.DefaultProvider
+		if r.Auth != nil && r.Auth.Use != "" {
+			provider = r.Auth.Use
+			if _, ok := c.authProvider(provider); ok {
+				usedProviders[provider] = true
+			} else {
+				v.errorf(path+".auth.use", "route %q uses unknown auth provider %q", r.Name, provider).
+					suggest(provider, providerNames)
+			}
+		}
+		// Unknown providers are reported once, above or at
+		// auth.defaultProvider, rather than as a missing type.
+		_, resolved := c.authProvider(provider)
+		auth := c.mergedAuth(r)
+		switch auth.Type {
+		case "apiKey":
+			if strings.TrimSpace(auth.HeaderName) == "" || (len(auth.APIKeys) == 0 && strings.TrimSpace(auth.APIKeysFile) == "") {
+				v.errorf(path+".auth", "route %q apiKey auth requires headerName and apiKeys or apiKeysFile", r.Name)
+			}
+		case "jwt":
+			if strings.TrimSpace(auth.Issuer) == "" || strings.TrimSpace(auth.Audience) == "" {
+				v.errorf(path+".auth", "route %q jwt auth requires issuer and audience", r.Name)
+			}
+		case "none":
+			if c.Auth.RequireAuth {
+				v.warnf(path+".auth.type", "route %q turns auth off although auth.requireAuth is set", r.Name)
+			}
+		case "":
+			if r.Auth == nil {
+				// auth.requireAuth alone gives apiKey auth with no keys,
+				// which the gateway denies.
+				if c.Auth.RequireAuth && provider == "" {
+					v.warnf(path, "route %q has apiKey auth on %s with no keys and rejects every request", r.Name, DefaultAPIKeyHeader).
+						Hint = "set auth.defaultProvider, or give the route apiKey auth with apiKeys or apiKeysFile"
 				}
-			case "none":
-			default:
-				return fmt.Errorf("route %q auth type must be apiKey, jwt, or none", r.Name)
+				break
 			}
+			fallthrough
+		default:
+			if provider == "" || resolved {
+				v.errorf(path+".auth.type", "route %q auth type must be apiKey, jwt, or none", r.Name).
+					suggest(auth.Type, authTypes)
+			}
+		}
+		if r.Auth != nil && r.Auth.Require != nil {
+			v.deprecated(path+".auth.require", "routes[].auth.require")
 		}
 	}
+	// The gateway reads claims without checking the signature; only Envoy's
+	// jwt auth makes them trustworthy.
+	for i, s := range c.Servers {
+		route, ok := unverified[s.Name]
+		if !ok {
+			continue
+		}
+		for j, cr := range s.Credentials {
+			if cr.FromClaim != "" {
+				v.errorf(fmt.Sprintf("servers[%d].credentials[%d].fromClaim", i, j), "server %q reads token claim %s, but route %q does not use jwt auth", s.Name, cr.FromClaim, route).
+					Hint = "the gateway does not verify tokens; give every route to this server jwt auth, or use fromHeader"
+			}
+		}
+	}
+	for i, s := range c.Servers {
+		if s.Name != "" && !usedServers[s.Name] {
+			v.warnf(fmt.Sprintf("servers[%d].name", i), "server %q is not used by any route", s.Name)
+		}
+	}
+	for i, p := range c.AuthProviders {
+		if p.Name != "" && !usedProviders[p.Name] {
+			v.warnf(fmt.Sprintf("authProviders[%d].name", i), "auth provider %q is not used by any route", p.Name)
+		}
+	}
+	for i, p := range c.Policies {
+		if p.Name != "" && !usedPolicies[p.Name] {
+			v.warnf(fmt.Sprintf("policies[%d].name", i), "policy %q is not used by any route", p.Name)
+		}
+	}
+	return v.issues
+}
 
-	return nil
+// checkProfiles validates the named auth providers and policies and
+// returns their names.
+func (c Config) checkProfiles(v *validator) (providerNames, policyNames []string) {
+	seen := map[string]string{}
+	for i, p := range c.AuthProviders {
+		path := fmt.Sprintf("authProviders[%d]", i)
+		if strings.TrimSpace(p.Name) == "" {
+			v.errorf(path+".name", "authProviders[].name is required")
+		} else if first, ok := seen[p.Name]; ok {
+			v.errorf(path+".name", "duplicate auth provider name: %s", p.Name).Hint = "first defined at " + v.describe(first)
+		} else {
+			seen[p.Name] = path + ".name"
+			providerNames = append(providerNames, p.Name)
+		}
+		if p.Use != "" {
+			v.errorf(path+".use", "auth provider %q cannot use another provider", p.Name)
+		}
+		switch p.Type {
+		case "apiKey", "jwt", "none":
+		default:
+			v.errorf(path+".type", "auth provider %q type must be apiKey, jwt, or none", p.Name).
+				suggest(p.Type, authTypes)
+		}
+		if p.Require != nil {
+			v.errorf(path+".require", "auth provider %q cannot set require", p.Name).
+				Hint = "remove it; use type: none on a route to turn auth off"
+		}
+	}
+	seen = map[string]string{}
+	for i, p := range c.Policies {
+		path := fmt.Sprintf("policies[%d]", i)
+		if strings.TrimSpace(p.Name) == "" {
+			v.errorf(path+".name", "policies[].name is required")
+		} else if first, ok := seen[p.Name]; ok {
+			v.errorf(path+".name", "duplicate policy name: %s", p.Name).Hint = "first defined at " + v.describe(first)
+		} else {
+			seen[p.Name] = path + ".name"
+			policyNames = append(policyNames, p.Name)
+		}
+		if p.Use != "" {
+			v.errorf(path+".use", "policy %q cannot use another policy", p.Name)
+		}
+		if p.TimeoutMs < 0 || p.RetryCount < 0 || p.RateLimitRPS < 0 {
+			v.errorf(path, "policy %q values must be >= 0", p.Name)
+		}
+	}
+	return providerNames, policyNames
+}
+
+func (d DeploymentSettings) validate(v *validator) {
+	if d.Replicas < 0 {
+		v.errorf("deployment.replicas", "deployment.replicas must be >= 0")
+	}
+	d.Resources.validate(v, "deployment.resources", "deployment.resources")
+	if t := d.TopologySpread; t != nil {
+		if t.MaxSkew < 0 {
+			v.errorf("deployment.topologySpread.maxSkew", "deployment.topologySpread.maxSkew must be >= 0")
+		}
+		switch t.WhenUnsatisfiable {
+		case "", "DoNotSchedule", "ScheduleAnyway":
+		default:
+			v.errorf("deployment.topologySpread.whenUnsatisfiable", "deployment.topologySpread.whenUnsatisfiable must be DoNotSchedule or ScheduleAnyway").
+				suggest(t.WhenUnsatisfiable, []string{"DoNotSchedule", "ScheduleAnyway"})
+		}
+	}
+	if p := d.PodDisruptionBudget; p != nil {
+		if p.MinAvailable < 0 || p.MaxUnavailable < 0 {
+			v.errorf("deployment.podDisruptionBudget", "deployment.podDisruptionBudget values must be >= 0")
+		}
+		if p.MinAvailable > 0 && p.MaxUnavailable > 0 {
+			v.errorf("deployment.podDisruptionBudget", "deployment.podDisruptionBudget sets both minAvailable and maxUnavailable")
+		}
+	}
+	if a := d.Autoscaling; a != nil {
+		if a.MinReplicas < 0 || a.TargetCPUUtilizationPercentage < 0 || a.TargetMemoryUtilizationPercentage < 0 {
+			v.errorf("deployment.autoscaling", "deployment.autoscaling values must be >= 0")
+		}
+		if a.MaxReplicas < 1 || a.MaxReplicas < a.MinReplicas {
+			v.errorf("deployment.autoscaling.maxReplicas", "deployment.autoscaling.maxReplicas must be >= 1 and >= minReplicas")
+		}
+	}
+}
+
+// validate checks the quantities under path; prefix names the block in
+// messages.
+func (r ResourceRequirements) validate(v *validator, path, prefix string) {
+	quantities := []struct{ field, value string }{
+		{"requests.cpu", r.Requests.CPU},
+		{"requests.memory", r.Requests.Memory},
+		{"limits.cpu", r.Limits.CPU},
+		{"limits.memory", r.Limits.Memory},
+	}
+	for _, q := range quantities {
+		if q.value != "" && !quantityPattern.MatchString(q.value) {
+			v.errorf(path+"."+q.field, "%s.%s %q is not a valid quantity", prefix, q.field, q.value)
+		}
+	}
+}
+
+func (s Server) validateStdio(v *validator, path string) {
+	seen := map[string]struct{}{}
+	for i, e := range s.Env {
+		envPath := fmt.Sprintf("%s.env[%d]", path, i)
+		if strings.TrimSpace(e.Name) == "" || strings.Contains(e.Name, "=") {
+			v.errorf(envPath+".name", "server %q env entries need a name without '='", s.Name)
+		}
+		if _, ok := seen[e.Name]; ok {
+			v.errorf(envPath+".name", "server %q sets env %s twice", s.Name, e.Name)
+		}
+		seen[e.Name] = struct{}{}
+		sources := 0
+		for _, set := range []bool{e.Value != "", e.FromEnv != "", e.FromFile != "", e.SecretRef != nil} {
+			if set {
+				sources++
+			}
+		}
+		if sources > 1 {
+			v.errorf(envPath, "server %q env %s sets more than one of value, fromEnv, fromFile and secretRef", s.Name, e.Name)
+		}
+		if e.SecretRef != nil && (!dnsSubdomainPattern.MatchString(e.SecretRef.Name) || strings.TrimSpace(e.SecretRef.Key) == "") {
+			v.errorf(envPath+".secretRef", "server %q env %s secretRef needs a Secret name and key", s.Name, e.Name)
+		}
+		if s.Image != "" && (e.FromEnv != "" || e.FromFile != "") {
+			v.errorf(envPath, "server %q env %s: servers with an image run outside the gateway; use value or secretRef", s.Name, e.Name)
+		}
+	}
+	for i, c := range s.Credentials {
+		credPath := fmt.Sprintf("%s.credentials[%d]", path, i)
+		if err := c.validate(); err != nil {
+			v.errorf(credPath, "server %q: %v", s.Name, err)
+		}
+		if _, ok := seen[c.ToEnv]; ok {
+			v.errorf(credPath+".toEnv", "server %q sets env %s twice", s.Name, c.ToEnv)
+		}
+		if c.ToEnv != "" {
+			seen[c.ToEnv] = struct{}{}
+		}
+	}
+	switch s.Placement {
+	case "", "sidecar":
+	case "standalone":
+		if strings.TrimSpace(s.Image) == "" {
+			v.errorf(path+".image", "server %q placement standalone requires image", s.Name)
+		}
+	default:
+		v.errorf(path+".placement", "server %q placement must be sidecar or standalone", s.Name).
+			suggest(s.Placement, []string{"sidecar", "standalone"})
+	}
+	if s.Sandbox != nil {
+		s.Sandbox.validate(v, path+".sandbox", s.Name)
+	}
+	if s.Image != "" && !dnsLabelPattern.MatchString(s.Name) {
+		v.errorf(path+".name", "server %q with an image needs a lowercase DNS label name for its Kubernetes objects", s.Name)
+	}
+	s.Resources.validate(v, path+".resources", fmt.Sprintf("server %q resources", s.Name))
+}
+
+func (sb Sandbox) validate(v *validator, path, server string) {
+	if (sb.User != nil && *sb.User < 0) || (sb.Group != nil && *sb.Group < 0) {
+		v.errorf(path, "server %q sandbox user and group must be >= 0", server)
+	}
+	l := sb.Limits
+	if l.CPUSeconds < 0 || l.OpenFiles < 0 || l.Processes < 0 {
+		v.errorf(path+".limits", "server %q sandbox limits must be >= 0", server)
+	}
+	if l.Memory != "" {
+		if _, err := ParseBytes(l.Memory); err != nil {
+			v.errorf(path+".limits.memory", "server %q sandbox limits.memory: %v", server, err)
+		}
+	}
+	for i, ns := range sb.Namespaces {
+		switch ns {
+		case "mount", "pid", "network":
+		default:
+			v.errorf(fmt.Sprintf("%s.namespaces[%d]", path, i), "server %q sandbox namespace %q must be mount, pid or network", server, ns).
+				suggest(ns, []string{"mount", "pid", "network"})
+		}
+	}
+	switch sb.Seccomp {
+	case "", "default":
+	default:
+		v.errorf(path+".seccomp", "server %q sandbox seccomp must be default or unset", server)
+	}
+}
+
+// ParseBytes converts a Kubernetes-style byte quantity (512Mi, 2G, 1048576)
+// to bytes.
+func ParseBytes(q string) (int64, error) {
+	m := quantityPattern.FindStringSubmatch(q)
+	if m == nil || m[2] == "m" {
+		return 0, fmt.Errorf("%q is not a byte quantity", q)
+	}
+	multipliers := map[string]float64{
+		"": 1, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+		"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+	}
+	var n float64
+	if _, err := fmt.Sscanf(strings.TrimSuffix(q, m[2]), "%g", &n); err != nil {
+		return 0, fmt.Errorf("%q is not a byte quantity", q)
+	}
+	return int64(n * multipliers[m[2]]), nil
 }
@@ -1,6 +1,13 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
 
 func TestValidateTemplate(t *testing.T) {
 	cfg, err := LoadFile("../../deploy/examples/gateway.yaml")
@@ -27,3 +34,336 @@ func TestValidateRouteUnknownServer(t *testing.T) {
 		t.Fatal("expected validation error for unknown server")
 	}
 }
+
+func TestValidateClientTransports(t *testing.T) {
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway: Gateway{
+			Name:       "gw",
+			ListenAddr: ":8080",
+		},
+		Servers: []Server{{Name: "legacy", Transport: "sse", URL: "http://example/sse"}},
+		Routes:  []Route{{Name: "r1", Path: "/mcp", Server: "legacy", ClientTransports: []string{"http", "sse"}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected sse transports to validate, got %v", err)
+	}
+	cfg.Routes[0].ClientTransports = []string{"grpc"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for unsupported client transport")
+	}
+}
+
+func TestValidateRouteHostsAndRewrite(t *testing.T) {
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway:    Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers:    []Server{{Name: "a", Transport: "http", URL: "http://example"}},
+		Routes: []Route{
+			{Name: "weather", Path: "/mcp", Server: "a", Hosts: []string{"weather.mcp.example.com"}, Rewrite: &Rewrite{StripPrefix: true}},
+			{Name: "fs", Path: "/mcp", Server: "a", Hosts: []string{"*.fs.example.com"}},
+		},
+	}
+	if issues := cfg.Check(); len(issues) != 0 {
+		t.Fatalf("expected routes on different hosts to share a path, got %v", issues)
+	}
+	if got := cfg.Routes[0].UpstreamPath("/mcp/tools"); got != "/tools" {
+		t.Fatalf("unexpected stripped path %q", got)
+	}
+	for _, hosts := range [][]string{{"a.fs.example.com"}, {"*.a.fs.example.com"}} {
+		overlapping := cfg
+		overlapping.Routes = []Route{cfg.Routes[0], cfg.Routes[1], {Name: "a", Path: "/mcp", Server: "a", Hosts: hosts}}
+		if w := overlapping.Check().Warnings(); len(w) != 1 || w[0].Path != "routes[2].path" {
+			t.Fatalf("expected %v to overlap *.fs.example.com, got %v", hosts, w)
+		}
+	}
+
+	cfg.Routes[1].Hosts = []string{"Fs.example.com:443"}
+	cfg.Routes[0].Rewrite = &Rewrite{StripPrefix: true, Path: "mcp"}
+	var paths []string
+	for _, issue := range cfg.Check() {
+		paths = append(paths, issue.Path)
+	}
+	want := []string{"routes[0].rewrite", "routes[0].rewrite.path", "routes[1].hosts[0]"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected issues at %v, want %v", paths, want)
+	}
+}
+
+func TestReadAPIKeyFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte("# rotated monthly\nk1\n\nk2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway:    Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers:    []Server{{Name: "a", Transport: "http", URL: "http://example"}},
+		Routes: []Route{{Name: "r1", Path: "/mcp", Server: "a", Auth: &RouteAuth{
+			Type: "apiKey", HeaderName: "X-API-Key", APIKeysFile: path,
+		}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected apiKeysFile to satisfy apiKey auth, got %v", err)
+	}
+	if err := cfg.ReadAPIKeyFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Routes[0].Auth.APIKeys; len(got) != 2 || got[0] != "k1" || got[1] != "k2" {
+		t.Fatalf("unexpected keys: %v", got)
+	}
+}
+
+func TestValidateDeploymentSettings(t *testing.T) {
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway:    Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers:    []Server{{Name: "a", Transport: "http", URL: "http://example"}},
+		Routes:     []Route{{Name: "r1", Path: "/mcp", Server: "a"}},
+		Deployment: DeploymentSettings{
+			Resources:   ResourceRequirements{Requests: ResourceList{CPU: "250m", Memory: "256Mi"}},
+			Autoscaling: &Autoscaling{MinReplicas: 2, MaxReplicas: 4},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected deployment settings to validate, got %v", err)
+	}
+	cfg.Deployment.Resources.Limits.Memory = "lots"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for invalid quantity")
+	}
+	cfg.Deployment.Resources.Limits.Memory = ""
+	cfg.Deployment.Autoscaling.MaxReplicas = 1
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for maxReplicas below minReplicas")
+	}
+}
+
+func TestValidateSandbox(t *testing.T) {
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway:    Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers: []Server{{Name: "fs", Transport: "stdio", Command: "npx", Sandbox: &Sandbox{
+			Limits:     Rlimits{Memory: "512Mi", OpenFiles: 256},
+			Namespaces: []string{"mount", "pid"},
+			Seccomp:    "default",
+		}}},
+		Routes: []Route{{Name: "r1", Path: "/mcp", Server: "fs"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected sandbox to validate, got %v", err)
+	}
+	if n, _ := ParseBytes("512Mi"); n != 512<<20 {
+		t.Fatalf("expected 512Mi in bytes, got %d", n)
+	}
+	cfg.Servers[0].Sandbox.Namespaces = []string{"user"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for unsupported namespace")
+	}
+}
+
+func TestValidateStdioEnvSources(t *testing.T) {
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway:    Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers: []Server{{Name: "git", Transport: "stdio", Command: "uvx", Env: []EnvVar{
+			{Name: "GITHUB_TOKEN", SecretRef: &SecretRef{Name: "github", Key: "token"}},
+			{Name: "HOME", FromEnv: "HOME"},
+		}, Credentials: []Credential{{FromHeader: "X-GitHub-Token", ToEnv: "GH_USER_TOKEN", Optional: true}}}},
+		Routes: []Route{{Name: "r1", Path: "/mcp", Server: "git"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected env sources to validate, got %v", err)
+	}
+	if c, err := ParseCredential(cfg.Servers[0].Credentials[0].String()); err != nil || c != cfg.Servers[0].Credentials[0] {
+		t.Fatalf("credential did not round-trip: %+v %v", c, err)
+	}
+
+	cfg.Servers[0].Image = "ghcr.io/astral-sh/uv:python3.12-bookworm-slim"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for fromEnv on a server with an image")
+	}
+	cfg.Servers[0].Image = ""
+	cfg.Servers[0].Env[1].Value = "/tmp"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for two env sources")
+	}
+}
+
+func TestValidateClaimCredentialsNeedJWTRoutes(t *testing.T) {
+	jwt := &RouteAuth{Type: "jwt", Issuer: "https://issuer.example.com", Audience: "mcp"}
+	cfg := Config{
+		APIVersion: "mcp.envoy.io/v1alpha1",
+		Kind:       "GatewayConfig",
+		Gateway:    Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers: []Server{{Name: "git", Transport: "stdio", Command: "uvx",
+			Credentials: []Credential{{FromClaim: "sub", ToEnv: "GIT_AUTHOR_EMAIL"}}}},
+		Routes: []Route{
+			{Name: "signed", Path: "/mcp/git", Server: "git", Auth: jwt},
+			{Name: "open", Path: "/mcp/git-open", Server: "git"},
+		},
+	}
+	issues := cfg.Check()
+	var found bool
+	for _, issue := range issues {
+		found = found || (issue.Path == "servers[0].credentials[0].fromClaim" && strings.Contains(issue.Message, `route "open"`))
+	}
+	if !found {
+		t.Fatalf("expected fromClaim rejected on a server with a non-jwt route, got %v", issues)
+	}
+
+	cfg.Routes[1].Auth = jwt
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected fromClaim behind jwt routes to validate, got %v", err)
+	}
+}
+
+func TestCheckYAMLReportsEveryIssue(t *testing.T) {
+	_, issues := CheckYAML([]byte(`apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+servers:
+  - {name: weather, transport: http, url: "http://localhost:8000"}
+  - {name: spare, transport: stdoi, command: x}
+routes:
+  - name: w
+    path: /mcp/w
+    server: wether
+    auth: {type: apikey}
+  - {name: w2, path: /mcp/w2, server: weather}
+`), ".")
+	want := []struct {
+		severity, path, hint string
+		line                 int
+	}{
+		{SeverityError, "servers[1].transport", `did you mean "stdio"?`, 6},
+		{SeverityWarning, "servers[1].name", "", 6},
+		{SeverityError, "routes[0].server", `did you mean "weather"?`, 10},
+		{SeverityError, "routes[0].auth.type", `did you mean "apiKey"?`, 11},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i, w := range want {
+		got := issues[i]
+		if got.Severity != w.severity || got.Path != w.path || got.Hint != w.hint || got.Line != w.line {
+			t.Fatalf("issue %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if errs := issues.Errors(); len(errs) != 3 || !strings.Contains(errs.Error(), "line 10: route \"w\" references unknown server") {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	_, issues = CheckYAML([]byte("apiVersion: mcp.envoy.io/v1alpha1\nroutes:\n  - name: a\n    sever: b\n"), ".")
+	if len(issues) != 1 || issues[0].Line != 4 || issues[0].Message != `unknown field "sever"` || issues[0].Hint != `did you mean "server"?` {
+		t.Fatalf("unexpected decode issues: %+v", issues)
+	}
+}
+
+func TestMigrateRemovesDeprecatedFields(t *testing.T) {
+	old := `apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: gw, listenAddr: ":8080"}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  # the public weather route
+  - name: weather
+    path: /mcp/weather
+    server: weather
+    auth:
+      type: jwt
+      require: true # never enforced
+      issuer: https://issuer
+      audience: gw
+`
+	_, issues := CheckYAML([]byte(old), ".")
+	warnings := issues.Warnings()
+	if len(warnings) != 1 || warnings[0].Path != "routes[0].auth.require" || warnings[0].Line != 13 ||
+		!strings.Contains(warnings[0].Message, "removed in mcp.envoy.io/v1alpha2") {
+		t.Fatalf("unexpected deprecation warnings: %+v", issues)
+	}
+
+	out, changes, err := Migrate([]byte(old), LatestAPIVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Line != 13 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if strings.Contains(string(out), "require") || !strings.Contains(string(out), "# the public weather route") {
+		t.Fatalf("expected require removed and comments kept:\n%s", out)
+	}
+	if _, issues := CheckYAML(out, "."); len(issues) != 0 {
+		t.Fatalf("migrated config has issues: %v", issues)
+	}
+	if again, changes, _ := Migrate(out, LatestAPIVersion); len(changes) != 0 || string(again) != string(out) {
+		t.Fatalf("expected a migrated config to be left alone, got %+v", changes)
+	}
+	if _, _, err := Migrate([]byte("apiVersion: mcp.envoy.io/v9\n"), LatestAPIVersion); err == nil {
+		t.Fatal("expected an error for an unknown apiVersion")
+	}
+}
+
+func TestMigrateUpgradesOlderVersion(t *testing.T) {
+	const older = "mcp.envoy.io/v1alpha0"
+	oldVersions, oldMigrations := apiVersions, migrations
+	t.Cleanup(func() { apiVersions, migrations = oldVersions, oldMigrations })
+	apiVersions = []string{older, LatestAPIVersion}
+	// The stub step renames gateway.listen to listenAddr.
+	migrations = map[string]func(doc *yaml.Node) []Change{
+		older: func(doc *yaml.Node) []Change {
+			gateway := mappingValue(doc, "gateway")
+			if gateway == nil {
+				return nil
+			}
+			for i := 0; i+1 < len(gateway.Content); i += 2 {
+				if key := gateway.Content[i]; key.Value == "listen" {
+					key.Value = "listenAddr"
+					return []Change{{Path: "gateway.listen", Line: key.Line, Message: "renamed gateway.listen to listenAddr"}}
+				}
+			}
+			return nil
+		},
+	}
+
+	const rest = `servers:
+  - {name: weather, transport: http, url: "http://weather:8000"}
+routes:
+  - {name: weather, path: /mcp/weather, server: weather}
+`
+	_, issues := CheckYAML([]byte("apiVersion: "+older+"\nkind: GatewayConfig\ngateway: {name: gw, listenAddr: \":8080\"}\n"+rest), ".")
+	warnings := issues.Warnings()
+	if len(issues.Errors()) != 0 || len(warnings) != 1 || warnings[0].Path != "apiVersion" ||
+		!strings.Contains(warnings[0].Message, "older than "+LatestAPIVersion) {
+		t.Fatalf("expected an older-version warning, got %+v", issues)
+	}
+
+	old := "apiVersion: " + older + `
+kind: GatewayConfig
+gateway:
+  name: gw
+  listen: ":8080"
+` + rest
+	out, changes, err := Migrate([]byte(old), LatestAPIVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Line != 5 || !strings.Contains(changes[1].Message, "-> "+LatestAPIVersion) {
+		t.Fatalf("expected the step and the version bump, got %+v", changes)
+	}
+	if _, issues := CheckYAML(out, "."); len(issues) != 0 {
+		t.Fatalf("migrated config has issues: %v\n%s", issues, out)
+	}
+
+	// Included files without an apiVersion take the version they are given.
+	if _, changes, _ := Migrate([]byte("gateway:\n  listen: \":8080\"\n"), older); len(changes) != 1 {
+		t.Fatalf("expected the step run on a versionless file, got %+v", changes)
+	}
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LatestAPIVersion is the config version this build writes and migrates to.
+const LatestAPIVersion = "mcp.envoy.io/v1alpha1"
+
+// apiVersions lists the config versions this build reads, oldest first.
+// Older versions load with a warning; gateway migrate rewrites them.
+var apiVersions = []string{LatestAPIVersion}
+
+// migrations upgrade a document from the version they are keyed by to the
+// next one in apiVersions.
+var migrations = map[string]func(doc *yaml.Node) []Change{}
+
+// deprecation is a field that still loads but is going away. Migrate
+// removes it.
+type deprecation struct {
+	path      string // YAML path with [] for every list element
+	removedIn string
+	hint      string
+}
+
+var deprecations = []deprecation{
+	{path: "routes[].auth.require", removedIn: "mcp.envoy.io/v1alpha2", hint: "it was never enforced; use type: none to turn auth off for a route"},
+}
+
+// deprecated reports a deprecated field at path; pattern is its entry in
+// deprecations.
+func (v *validator) deprecated(path, pattern string) {
+	for _, d := range deprecations {
+		if d.path == pattern {
+			v.warnf(path, "%s is deprecated and will be removed in %s", lastSegment(path), d.removedIn).Hint = d.hint + " (gateway migrate removes it)"
+			return
+		}
+	}
+}
+
+// checkVersion reports an unknown apiVersion, or an older one that still
+// loads.
+func (v *validator) checkVersion(version string) {
+	for i, known := range apiVersions {
+		if version != known {
+			continue
+		}
+		if i < len(apiVersions)-1 {
+			v.warnf("apiVersion", "apiVersion %s is older than %s", version, LatestAPIVersion).Hint = "run gateway migrate to upgrade the config"
+		}
+		return
+	}
+	v.errorf("apiVersion", "apiVersion must be %s", LatestAPIVersion).suggest(version, apiVersions)
+}
+
+func lastSegment(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// Change is one rewrite made by Migrate.
+type Change struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// Migrate rewrites config YAML to LatestAPIVersion and drops deprecated
+// fields. Comments and the layout of untouched nodes are kept; b is returned
+// as is when nothing changes. Documents without an apiVersion, such as
+// included team files, are taken to be at version.
+func Migrate(b []byte, version string) ([]byte, []Change, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, nil, fmt.Errorf("parse config: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return b, nil, nil
+	}
+	doc := root.Content[0]
+	versionNode := mappingValue(doc, "apiVersion")
+	if versionNode != nil {
+		version = versionNode.Value
+	}
+	start := -1
+	for i, known := range apiVersions {
+		if known == version {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil, nil, fmt.Errorf("unknown apiVersion %q", version)
+	}
+
+	var changes []Change
+	for _, from := range apiVersions[start : len(apiVersions)-1] {
+		if step := migrations[from]; step != nil {
+			changes = append(changes, step(doc)...)
+		}
+	}
+	for _, d := range deprecations {
+		for _, m := range matchPath(doc, d.path) {
+			changes = append(changes, Change{Path: m.path, Line: m.key.Line, Message: fmt.Sprintf("removed deprecated %s", m.path)})
+			removeKey(m.parent, m.key)
+		}
+	}
+	if versionNode != nil && versionNode.Value != LatestAPIVersion {
+		changes = append(changes, Change{Path: "apiVersion", Line: versionNode.Line, Message: fmt.Sprintf("apiVersion %s -> %s", versionNode.Value, LatestAPIVersion)})
+		versionNode.Value = LatestAPIVersion
+	}
+	if len(changes) == 0 {
+		return b, nil, nil
+	}
+	var out strings.Builder
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, nil, fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, fmt.Errorf("encode config: %w", err)
+	}
+	return []byte(out.String()), changes, nil
+}
+
+// MigratedFile is one config file as rewritten by MigrateFiles.
+type MigratedFile struct {
+	Path    string
+	Data    []byte
+	Changes []Change
+}
+
+// MigrateFiles migrates the config at path and every file it includes.
+// Files without an apiVersion are taken to be at the version of the first
+// file that has one. Nothing is written.
+func MigrateFiles(path string) ([]MigratedFile, error) {
+	files, issues := SourceFiles(path)
+	if errs := issues.Errors(); len(errs) > 0 {
+		return nil, errs
+	}
+	var contents [][]byte
+	version := LatestAPIVersion
+	found := false
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+		contents = append(contents, b)
+		var header struct {
+			APIVersion string `yaml:"apiVersion"`
+		}
+		if !found && yaml.Unmarshal(b, &header) == nil && header.APIVersion != "" {
+			version, found = header.APIVersion, true
+		}
+	}
+	var out []MigratedFile
+	for i, f := range files {
+		b, changes, err := Migrate(contents[i], version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		out = append(out, MigratedFile{Path: f, Data: b, Changes: changes})
+	}
+	return out, nil
+}
+
+// pathMatch is a mapping key found by matchPath.
+type pathMatch struct {
+	path   string
+	parent *yaml.Node
+	key    *yaml.Node
+}
+
+// matchPath finds the keys a deprecation path names under n.
+func matchPath(n *yaml.Node, pattern string) []pathMatch {
+	var matches []pathMatch
+	var walk func(n *yaml.Node, segments []string, path string)
+	walk = func(n *yaml.Node, segments []string, path string) {
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		name, list := strings.CutSuffix(segments[0], "[]")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value != name {
+				continue
+			}
+			keyPath := name
+			if path != "" {
+				keyPath = path + "." + name
+			}
+			switch {
+			case len(segments) == 1:
+				matches = append(matches, pathMatch{keyPath, n, key})
+			case list && value.Kind == yaml.SequenceNode:
+				for j, item := range value.Content {
+					walk(item, segments[1:], fmt.Sprintf("%s[%d]", keyPath, j))
+				}
+			case !list:
+				walk(value, segments[1:], keyPath)
+			}
+		}
+	}
+	walk(n, strings.Split(pattern, "."), "")
+	return matches
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func removeKey(n, key *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i] == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return
+		}
+	}
+}
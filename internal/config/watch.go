@@ -0,0 +1,51 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Watch polls the config at path, and every file and directory it
+// includes, and calls reload with the new config whenever one of them
+// changes. Load errors are passed to reload too; the caller should keep
+// running the previous config. Watch returns when ctx is done.
+func Watch(ctx context.Context, path string, interval time.Duration, reload func(*Config, error)) {
+	_, _, files := checkFile(path)
+	last := stamp(files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if stamp(files) == last {
+			continue
+		}
+		cfg, issues, read := checkFile(path)
+		files, last = read, stamp(read)
+		if errs := issues.Errors(); len(errs) > 0 {
+			reload(nil, errs)
+			continue
+		}
+		reload(cfg, nil)
+	}
+}
+
+// stamp summarizes the size and modification time of files, so a change
+// to any of them, or to a directory's entries, changes the stamp.
+func stamp(files []string) string {
+	var b strings.Builder
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			fmt.Fprintf(&b, "%s %d %d\n", f, info.Size(), info.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(&b, "%s missing\n", f)
+		}
+	}
+	return b.String()
+}
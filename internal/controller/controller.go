@@ -2,7 +2,6 @@ package controller
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/djsam/mcp-gateway-envoy/internal/config"
 )
@@ -22,6 +21,7 @@ func BuildResources(cfg *config.Config) ([]Resource, error) {
 
 	resources := make([]Resource, 0, len(cfg.Routes)*3)
 	for _, route := range cfg.Routes {
+		route = cfg.ResolveRoute(route)
 		resources = append(resources,
 			Resource{
 				Kind: "BackendRef",
@@ -44,7 +44,7 @@ func BuildResources(cfg *config.Config) ([]Resource, error) {
 			},
 		)
 
-		if authKind := routeAuthKind(cfg, route); authKind != "none" {
+		if authKind := route.Auth.Type; authKind != "none" {
 			resources = append(resources, Resource{
 				Kind: "MCPAuthPolicy",
 				Name: route.Name + "-auth",
@@ -67,20 +67,3 @@ func lookupServerTransport(cfg *config.Config, serverName string) string {
 	}
 	return "unknown"
 }
-
-func routeAuthKind(cfg *config.Config, route config.Route) string {
-	if route.Auth == nil {
-		if cfg.Auth.RequireAuth {
-			return "apiKey"
-		}
-		return "none"
-	}
-	kind := strings.TrimSpace(route.Auth.Type)
-	if kind == "" {
-		if cfg.Auth.RequireAuth {
-			return "apiKey"
-		}
-		return "none"
-	}
-	return kind
-}
@@ -0,0 +1,347 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube"
+	"gopkg.in/yaml.v3"
+)
+
+// Change kinds used by ResourceDiff and FieldChange.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange is one differing field, addressed by a dotted path.
+type FieldChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Old    any    `json:"old,omitempty"`
+	New    any    `json:"new,omitempty"`
+}
+
+// ResourceDiff describes how one object differs between the current state and
+// the desired (rendered) state.
+type ResourceDiff struct {
+	Object string        `json:"object"`
+	Change string        `json:"change"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// ParseManifests decodes a multi-document YAML manifest such as the output of
+// RenderManifests.
+func ParseManifests(b []byte) ([]kube.Object, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	var out []kube.Object
+	for {
+		var doc map[string]any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse manifest yaml: %w", err)
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		out = append(out, normalize(doc))
+	}
+}
+
+// DiffObjects compares current objects against desired ones, matching them by
+// kind, namespace and name.
+func DiffObjects(current, desired []kube.Object) []ResourceDiff {
+	return diffObjectSets(current, desired, false)
+}
+
+// DiffLive compares the objects rendered from cfg with the cluster. Fields the
+// API server adds (status, defaults, other managers) are ignored; owned
+// objects that are no longer rendered are reported as removed.
+func DiffLive(ctx context.Context, client KubeClient, cfg *config.Config, namespace, image string) ([]ResourceDiff, error) {
+	docs, err := RenderObjects(cfg, namespace, image)
+	if err != nil {
+		return nil, err
+	}
+	desired := make([]kube.Object, 0, len(docs))
+	var current []kube.Object
+	seen := map[string]bool{}
+	for _, doc := range docs {
+		obj := normalize(doc)
+		desired = append(desired, obj)
+		apiVersion, kind, ns, name := objectCoordinates(obj)
+		seen[objectID(kind, ns, name)] = true
+		live, err := client.Get(ctx, apiVersion, kind, ns, name)
+		if kube.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", objectID(kind, ns, name), err)
+		}
+		current = append(current, secretStringData(live))
+	}
+
+	selector := labelSelector(OwnerLabels(cfg))
+	for _, info := range kube.Resources {
+		if !info.Namespaced {
+			continue
+		}
+		items, err := client.List(ctx, info.APIVersion, info.Kind, namespace, selector)
+		if kube.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", info.Kind, err)
+		}
+		for _, item := range items {
+			_, _, ns, name := objectCoordinates(item)
+			if !seen[objectID(info.Kind, ns, name)] {
+				current = append(current, item)
+			}
+		}
+	}
+	return diffObjectSets(current, desired, true), nil
+}
+
+// diffObjectSets reports desired objects in render order, then removed ones
+// sorted by id. With liveSubset, fields only present in current are ignored.
+func diffObjectSets(current, desired []kube.Object, liveSubset bool) []ResourceDiff {
+	current, desired = maskConfigMaps(current), maskConfigMaps(desired)
+	currentByID := map[string]kube.Object{}
+	for _, obj := range current {
+		_, kind, ns, name := objectCoordinates(obj)
+		currentByID[objectID(kind, ns, name)] = obj
+	}
+
+	var out []ResourceDiff
+	desiredIDs := map[string]bool{}
+	for _, obj := range desired {
+		_, kind, ns, name := objectCoordinates(obj)
+		id := objectID(kind, ns, name)
+		desiredIDs[id] = true
+		old, ok := currentByID[id]
+		if !ok {
+			out = append(out, ResourceDiff{Object: id, Change: ChangeAdded})
+			continue
+		}
+		fields := diffValues(old, obj, "", liveSubset)
+		if len(fields) == 0 {
+			continue
+		}
+		if kind == "Secret" {
+			redact(fields)
+		}
+		out = append(out, ResourceDiff{Object: id, Change: ChangeChanged, Fields: fields})
+	}
+
+	var removed []string
+	for id := range currentByID {
+		if !desiredIDs[id] {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		out = append(out, ResourceDiff{Object: id, Change: ChangeRemoved})
+	}
+	return out
+}
+
+// diffValues walks old and new and returns the differing leaves. Lists are
+// compared element by element; a length change reports the whole list.
+func diffValues(old, new any, path string, ignoreExtra bool) []FieldChange {
+	switch n := new.(type) {
+	case map[string]any:
+		o, ok := old.(map[string]any)
+		if !ok {
+			return []FieldChange{leafChange(old, new, path)}
+		}
+		keys := make([]string, 0, len(n)+len(o))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		for k := range o {
+			if _, dup := n[k]; !dup && !ignoreExtra {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var out []FieldChange
+		for _, k := range keys {
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			p := joinFieldPath(path, k)
+			switch {
+			case !inOld:
+				out = append(out, FieldChange{Path: p, Change: ChangeAdded, New: nv})
+			case !inNew:
+				out = append(out, FieldChange{Path: p, Change: ChangeRemoved, Old: ov})
+			default:
+				out = append(out, diffValues(ov, nv, p, ignoreExtra)...)
+			}
+		}
+		return out
+	case []any:
+		o, ok := old.([]any)
+		if !ok || len(o) != len(n) {
+			return []FieldChange{leafChange(old, new, path)}
+		}
+		var out []FieldChange
+		for i := range n {
+			out = append(out, diffValues(o[i], n[i], fmt.Sprintf("%s[%d]", path, i), ignoreExtra)...)
+		}
+		return out
+	default:
+		if old != new {
+			return []FieldChange{leafChange(old, new, path)}
+		}
+		return nil
+	}
+}
+
+func leafChange(old, new any, path string) FieldChange {
+	return FieldChange{Path: pathOrRoot(path), Change: ChangeChanged, Old: old, New: new}
+}
+
+func redact(fields []FieldChange) {
+	for i := range fields {
+		if strings.HasPrefix(fields[i].Path, "data") || strings.HasPrefix(fields[i].Path, "stringData") {
+			if fields[i].Old != nil {
+				fields[i].Old = "(redacted)"
+			}
+			if fields[i].New != nil {
+				fields[i].New = "(redacted)"
+			}
+		}
+	}
+}
+
+// maskConfigMaps copies objs with the API keys and secret env values of
+// embedded gateway configs masked, as Secrets are redacted.
+func maskConfigMaps(objs []kube.Object) []kube.Object {
+	out := make([]kube.Object, len(objs))
+	for i, obj := range objs {
+		out[i] = obj
+		data, _ := obj["data"].(map[string]any)
+		embedded, ok := data["gateway.yaml"].(string)
+		if obj["kind"] != "ConfigMap" || !ok {
+			continue
+		}
+		masked, err := config.MaskYAML([]byte(embedded))
+		if err != nil {
+			// Not a config this build can read; hide it rather than leak it.
+			masked = []byte("(redacted)")
+		}
+		copied := kube.Object{}
+		for k, v := range obj {
+			copied[k] = v
+		}
+		copiedData := map[string]any{}
+		for k, v := range data {
+			copiedData[k] = v
+		}
+		copiedData["gateway.yaml"] = string(masked)
+		copied["data"] = copiedData
+		out[i] = copied
+	}
+	return out
+}
+
+// WriteDiff prints diffs in a compact human-readable form.
+func WriteDiff(w io.Writer, diffs []ResourceDiff) {
+	if len(diffs) == 0 {
+		fmt.Fprintln(w, "no differences")
+		return
+	}
+	counts := map[string]int{}
+	for _, d := range diffs {
+		counts[d.Change]++
+		fmt.Fprintf(w, "%s %s\n", changeMarker(d.Change), d.Object)
+		for _, f := range d.Fields {
+			switch f.Change {
+			case ChangeAdded:
+				fmt.Fprintf(w, "    + %s: %s\n", f.Path, compactJSON(f.New))
+			case ChangeRemoved:
+				fmt.Fprintf(w, "    - %s: %s\n", f.Path, compactJSON(f.Old))
+			default:
+				oldText, oldOK := f.Old.(string)
+				newText, newOK := f.New.(string)
+				if oldOK && newOK && (strings.Contains(oldText, "\n") || strings.Contains(newText, "\n")) {
+					fmt.Fprintf(w, "    ~ %s:\n", f.Path)
+					writeLineDiff(w, oldText, newText)
+					continue
+				}
+				fmt.Fprintf(w, "    ~ %s: %s -> %s\n", f.Path, compactJSON(f.Old), compactJSON(f.New))
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d resources differ: %d added, %d removed, %d changed\n",
+		len(diffs), counts[ChangeAdded], counts[ChangeRemoved], counts[ChangeChanged])
+}
+
+// writeLineDiff prints the changed lines of a multi-line value (an embedded
+// config file, say) instead of two long escaped strings.
+func writeLineDiff(w io.Writer, oldText, newText string) {
+	a := strings.Split(strings.TrimSuffix(oldText, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(newText, "\n"), "\n")
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(w, "        - %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(w, "        + %s\n", b[j])
+			j++
+		}
+	}
+}
+
+func changeMarker(change string) string {
+	switch change {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func compactJSON(v any) string {
+	if v == nil {
+		return "null"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube/kubetest"
+)
+
+func TestDiffRenderedManifests(t *testing.T) {
+	before := reconcileTestConfig()
+	manifest, err := RenderManifests(before, "mcp", "example/gateway:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ParseManifests(manifest)
+	if err != nil {
+		t.Fatalf("parse manifest: %v", err)
+	}
+
+	if diffs := DiffObjects(current, current); len(diffs) != 0 {
+		t.Fatalf("expected no differences against itself, got %+v", diffs)
+	}
+
+	after := reconcileTestConfig()
+	after.Routes[0].Policy.TimeoutMs = 8000
+	after.Routes = append(after.Routes, config.Route{Name: "search", Path: "/mcp/search", Server: "weather"})
+	desiredDocs, err := RenderObjects(after, "mcp", "example/gateway:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ = marshalDocs(desiredDocs)
+	desired, err := ParseManifests(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byObject := map[string]ResourceDiff{}
+	for _, d := range DiffObjects(current, desired) {
+		byObject[d.Object] = d
+	}
+	if d := byObject["HTTPRoute/mcp/search"]; d.Change != ChangeAdded {
+		t.Fatalf("expected search route added, got %+v", d)
+	}
+	if d := byObject["BackendTrafficPolicy/mcp/weather"]; d.Change != ChangeChanged || len(d.Fields) != 1 ||
+		d.Fields[0].Path != "spec.timeout.http.requestTimeout" || d.Fields[0].New != "8000ms" {
+		t.Fatalf("expected requestTimeout change, got %+v", d)
+	}
+	if d := byObject["Deployment/mcp/mcp-gateway"]; d.Change != ChangeChanged {
+		t.Fatalf("expected image change on deployment, got %+v", d)
+	}
+
+	// Swapping the sides turns the new route into a removal.
+	var out bytes.Buffer
+	WriteDiff(&out, DiffObjects(desired, current))
+	if !strings.Contains(out.String(), "- HTTPRoute/mcp/search") || !strings.Contains(out.String(), `~ spec.timeout.http.requestTimeout: "8000ms" -> "5000ms"`) {
+		t.Fatalf("unexpected human diff:\n%s", out.String())
+	}
+}
+
+func TestDiffMasksEmbeddedSecrets(t *testing.T) {
+	before := reconcileTestConfig()
+	before.Routes[0].Auth = &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"oldkey789"}}
+	after := reconcileTestConfig()
+	after.Servers = append(after.Servers, config.Server{Name: "git", Transport: "stdio", Command: "git-mcp",
+		Env: []config.EnvVar{{Name: "GITHUB_TOKEN", Value: "envsecret456"}}})
+	after.Routes[0].Auth = &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"supersecret123"}}
+	after.Routes = append(after.Routes, config.Route{Name: "git", Path: "/mcp/git", Server: "git"})
+
+	parse := func(cfg *config.Config) []kube.Object {
+		manifest, err := RenderManifests(cfg, "mcp", "example/gateway:1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		objs, err := ParseManifests(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return objs
+	}
+	diffs := DiffObjects(parse(before), parse(after))
+	var text bytes.Buffer
+	WriteDiff(&text, diffs)
+	encoded, err := json.Marshal(diffs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "ConfigMap/mcp/mcp-gateway-config") {
+		t.Fatalf("expected the config change reported, got:\n%s", text.String())
+	}
+	for _, secret := range []string{"oldkey789", "supersecret123", "envsecret456"} {
+		if strings.Contains(text.String(), secret) || strings.Contains(string(encoded), secret) {
+			t.Fatalf("diff output leaks %s:\n%s\n%s", secret, text.String(), encoded)
+		}
+	}
+}
+
+func TestDiffLiveIgnoresServerFields(t *testing.T) {
+	api := kubetest.NewServer()
+	defer api.Close()
+	client, err := kube.NewClient(api.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := reconcileTestConfig()
+	r := &Reconciler{Client: client, Namespace: "mcp"}
+	if _, err := r.Reconcile(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Server-populated fields are not differences; a removed route is.
+	svc := api.Object("v1", "Service", "mcp", "mcp-gateway")
+	svc["spec"].(map[string]any)["clusterIP"] = "10.0.0.12"
+	api.Put(svc)
+	cfg.Routes = nil
+
+	diffs, err := DiffLive(context.Background(), client, cfg, "mcp", "")
+	if err != nil {
+		t.Fatalf("diff live: %v", err)
+	}
+	removed := map[string]bool{}
+	for _, d := range diffs {
+		if d.Object == "Service/mcp/mcp-gateway" {
+			t.Fatalf("server-populated field reported as a difference: %+v", d)
+		}
+		if d.Change == ChangeRemoved {
+			removed[d.Object] = true
+		}
+	}
+	if !removed["HTTPRoute/mcp/weather"] || !removed["Backend/mcp/weather"] {
+		t.Fatalf("expected weather route and backend reported removed, got %+v", diffs)
+	}
+}
@@ -0,0 +1,340 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+const (
+	gatewayAPIVersion      = "gateway.networking.k8s.io/v1"
+	envoyGatewayAPIVersion = "gateway.envoyproxy.io/v1alpha1"
+	defaultGatewayClass    = "eg"
+)
+
+// envoyGatewayDocs renders the Gateway API and Envoy Gateway resources that
+// let Envoy route, authenticate and shape MCP traffic itself. Routes Envoy
+// cannot serve alone (non-HTTP upstreams, transport translation, per-message
+// policy) are sent to the gateway runtime Service instead of a Backend.
+func envoyGatewayDocs(cfg *config.Config, namespace string) []map[string]any {
+	docs := []map[string]any{
+		gatewayDoc(cfg, namespace),
+		clientTrafficPolicyDoc(cfg, namespace),
+	}
+
+	backends := map[string]bool{}
+	for _, r := range cfg.Routes {
+		r = cfg.ResolveRoute(r)
+		server, _ := lookupServer(cfg, r.Server)
+		direct := routeDirectToBackend(r, server)
+		if direct && !backends[server.Name] {
+			backends[server.Name] = true
+			docs = append(docs, backendDoc(server, namespace))
+		}
+		docs = append(docs, httpRouteDoc(cfg, r, server, namespace, direct))
+		if policy := backendTrafficPolicyDoc(r, namespace); policy != nil {
+			docs = append(docs, policy)
+		}
+		if kind := r.Auth.Type; kind != "none" {
+			if kind == "apiKey" && len(r.Auth.APIKeys) > 0 {
+				docs = append(docs, apiKeySecretDoc(r, namespace))
+			}
+			if policy := securityPolicyDoc(r, namespace, kind); policy != nil {
+				docs = append(docs, policy)
+			}
+		}
+	}
+	return docs
+}
+
+// routeDirectToBackend reports whether Envoy can forward the resolved route
+// straight to the upstream without the gateway runtime in the path.
+func routeDirectToBackend(route config.Route, server config.Server) bool {
+	if server.Transport != "http" || isLoopbackURL(server.URL) {
+		return false
+	}
+	for _, t := range route.ClientTransports {
+		if t != "http" {
+			return false
+		}
+	}
+	if len(route.Policy.AllowedTools) > 0 || route.Record != nil {
+		return false
+	}
+	// An API key check without a key list has nothing for Envoy's
+	// apiKeyAuth to match; the runtime denies it.
+	if route.Auth.Type == "apiKey" && len(route.Auth.APIKeys) == 0 {
+		return false
+	}
+	return true
+}
+
+func gatewayDoc(cfg *config.Config, namespace string) map[string]any {
+	className := cfg.Gateway.GatewayClassName
+	if strings.TrimSpace(className) == "" {
+		className = defaultGatewayClass
+	}
+	return map[string]any{
+		"apiVersion": gatewayAPIVersion,
+		"kind":       "Gateway",
+		"metadata": map[string]any{
+			"name":      cfg.Gateway.Name,
+			"namespace": namespace,
+		},
+		"spec": map[string]any{
+			"gatewayClassName": className,
+			"listeners": []map[string]any{
+				{
+					"name":     "http",
+					"protocol": "HTTP",
+					"port":     parsePort(cfg.Gateway.ListenAddr),
+				},
+			},
+		},
+	}
+}
+
+func clientTrafficPolicyDoc(cfg *config.Config, namespace string) map[string]any {
+	spec := map[string]any{
+		"targetRefs": []map[string]any{
+			{"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": cfg.Gateway.Name},
+		},
+		// MCP sessions keep SSE and websocket streams open between messages.
+		"timeout": map[string]any{
+			"http": map[string]any{"idleTimeout": "3600s"},
+		},
+	}
+	if n := cfg.Gateway.WebSocket.MaxMessageBytes; n > 0 {
+		spec["connection"] = map[string]any{"bufferLimit": strconv.FormatInt(n, 10)}
+	}
+	return map[string]any{
+		"apiVersion": envoyGatewayAPIVersion,
+		"kind":       "ClientTrafficPolicy",
+		"metadata": map[string]any{
+			"name":      cfg.Gateway.Name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+}
+
+func backendDoc(server config.Server, namespace string) map[string]any {
+	spec := map[string]any{}
+	u, err := url.Parse(server.URL)
+	if err == nil {
+		host := u.Hostname()
+		port := upstreamPort(u)
+		if net.ParseIP(host) != nil {
+			spec["endpoints"] = []map[string]any{{"ip": map[string]any{"address": host, "port": port}}}
+		} else {
+			spec["endpoints"] = []map[string]any{{"fqdn": map[string]any{"hostname": host, "port": port}}}
+		}
+		if u.Scheme == "https" {
+			spec["tls"] = map[string]any{"wellKnownCACertificates": "System"}
+		}
+	}
+	return map[string]any{
+		"apiVersion": envoyGatewayAPIVersion,
+		"kind":       "Backend",
+		"metadata": map[string]any{
+			"name":      server.Name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+}
+
+func httpRouteDoc(cfg *config.Config, route config.Route, server config.Server, namespace string, direct bool) map[string]any {
+	rule := map[string]any{
+		"matches": []map[string]any{
+			{"path": map[string]any{"type": "PathPrefix", "value": route.Path}},
+		},
+	}
+	if direct {
+		rule["backendRefs"] = []map[string]any{
+			{"group": "gateway.envoyproxy.io", "kind": "Backend", "name": server.Name},
+		}
+		// The runtime appends the route's upstream path to the server URL
+		// path; keep Envoy's upstream path identical.
+		prefix := ""
+		if u, err := url.Parse(server.URL); err == nil {
+			prefix = strings.TrimSuffix(u.Path, "/")
+		}
+		if path := rewritePath(route, prefix); path != nil {
+			rule["filters"] = []map[string]any{
+				{"type": "URLRewrite", "urlRewrite": map[string]any{"path": path}},
+			}
+		}
+	} else {
+		rule["backendRefs"] = []map[string]any{
+			{"name": cfg.Gateway.Name, "port": parsePort(cfg.Gateway.ListenAddr)},
+		}
+	}
+	spec := map[string]any{
+		"parentRefs": []map[string]any{{"name": cfg.Gateway.Name}},
+		"rules":      []map[string]any{rule},
+	}
+	if len(route.Hosts) > 0 {
+		spec["hostnames"] = route.Hosts
+	}
+	return map[string]any{
+		"apiVersion": gatewayAPIVersion,
+		"kind":       "HTTPRoute",
+		"metadata": map[string]any{
+			"name":      route.Name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+}
+
+// rewritePath returns the URLRewrite path of a route sent straight to its
+// server, whose URL path is prefix, or nil when Envoy's path is already the
+// one the runtime would send. Routes through the runtime are rewritten
+// there.
+func rewritePath(route config.Route, prefix string) map[string]any {
+	if route.Rewrite != nil && route.Rewrite.Path != "" {
+		return map[string]any{"type": "ReplaceFullPath", "replaceFullPath": prefix + route.Rewrite.Path}
+	}
+	replacement := prefix + route.UpstreamPath(route.Path)
+	if replacement == route.Path {
+		return nil
+	}
+	return map[string]any{"type": "ReplacePrefixMatch", "replacePrefixMatch": replacement}
+}
+
+func backendTrafficPolicyDoc(route config.Route, namespace string) map[string]any {
+	spec := map[string]any{
+		"targetRefs": []map[string]any{routeTargetRef(route)},
+	}
+	if route.Policy.TimeoutMs > 0 {
+		spec["timeout"] = map[string]any{
+			"http": map[string]any{"requestTimeout": fmt.Sprintf("%dms", route.Policy.TimeoutMs)},
+		}
+	}
+	if route.Policy.RetryCount > 0 {
+		spec["retry"] = map[string]any{
+			"numRetries": route.Policy.RetryCount,
+			"retryOn": map[string]any{
+				"triggers":        []string{"connect-failure", "retriable-status-codes"},
+				"httpStatusCodes": []int{502, 503, 504},
+			},
+		}
+	}
+	if route.Policy.RateLimitRPS > 0 {
+		spec["rateLimit"] = map[string]any{
+			"type": "Local",
+			"local": map[string]any{
+				"rules": []map[string]any{
+					{"limit": map[string]any{"requests": route.Policy.RateLimitRPS, "unit": "Second"}},
+				},
+			},
+		}
+	}
+	if len(spec) == 1 {
+		return nil
+	}
+	return map[string]any{
+		"apiVersion": envoyGatewayAPIVersion,
+		"kind":       "BackendTrafficPolicy",
+		"metadata": map[string]any{
+			"name":      route.Name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+}
+
+func apiKeySecretDoc(route config.Route, namespace string) map[string]any {
+	data := map[string]any{}
+	for i, key := range route.Auth.APIKeys {
+		data[fmt.Sprintf("client-%d", i+1)] = key
+	}
+	return map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]any{
+			"name":      route.Name + "-api-keys",
+			"namespace": namespace,
+		},
+		"type":       "Opaque",
+		"stringData": data,
+	}
+}
+
+func securityPolicyDoc(route config.Route, namespace, authType string) map[string]any {
+	spec := map[string]any{
+		"targetRefs": []map[string]any{routeTargetRef(route)},
+	}
+	switch authType {
+	case "jwt":
+		provider := map[string]any{"name": route.Name}
+		if route.Auth != nil {
+			provider["issuer"] = route.Auth.Issuer
+			if route.Auth.Audience != "" {
+				provider["audiences"] = []string{route.Auth.Audience}
+			}
+			provider["remoteJWKS"] = map[string]any{"uri": jwksURI(route.Auth)}
+		}
+		spec["jwt"] = map[string]any{"providers": []map[string]any{provider}}
+	case "apiKey":
+		header := config.DefaultAPIKeyHeader
+		if route.Auth != nil && strings.TrimSpace(route.Auth.HeaderName) != "" {
+			header = route.Auth.HeaderName
+		}
+		spec["apiKeyAuth"] = map[string]any{
+			"credentialRefs": []map[string]any{
+				{"group": "", "kind": "Secret", "name": route.Name + "-api-keys"},
+			},
+			"extractFrom": []map[string]any{{"headers": []string{header}}},
+		}
+		if route.Auth == nil || len(route.Auth.APIKeys) == 0 {
+			// Presence-only checks are enforced by the runtime the route
+			// points at; there is no key Secret to reference.
+			return nil
+		}
+	}
+	return map[string]any{
+		"apiVersion": envoyGatewayAPIVersion,
+		"kind":       "SecurityPolicy",
+		"metadata": map[string]any{
+			"name":      route.Name + "-auth",
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+}
+
+func routeTargetRef(route config.Route) map[string]any {
+	return map[string]any{"group": "gateway.networking.k8s.io", "kind": "HTTPRoute", "name": route.Name}
+}
+
+func jwksURI(auth *config.RouteAuth) string {
+	if strings.TrimSpace(auth.JWKSURI) != "" {
+		return auth.JWKSURI
+	}
+	return strings.TrimSuffix(auth.Issuer, "/") + "/.well-known/jwks.json"
+}
+
+func upstreamPort(u *url.URL) int {
+	if p, err := strconv.Atoi(u.Port()); err == nil && p > 0 {
+		return p
+	}
+	if u.Scheme == "https" {
+		return 443
+	}
+	return 80
+}
+
+func lookupServer(cfg *config.Config, name string) (config.Server, bool) {
+	for _, s := range cfg.Servers {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return config.Server{}, false
+}
@@ -0,0 +1,696 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// helmSecretsDir is where the chart mounts the API key Secret; each route's
+// keys are a file named after the route.
+const helmSecretsDir = "/etc/mcp-gateway/secrets"
+
+// RenderHelmChart renders a Helm chart that deploys the gateway runtime for
+// cfg. Files are keyed by their path inside the chart directory. API keys move
+// from the embedded config into a Secret the chart creates from values (or an
+// existing Secret named in values), referenced through apiKeysFile.
+func RenderHelmChart(cfg *config.Config, image string) (map[string][]byte, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+	if err := checkPodSandbox(cfg); err != nil {
+		return nil, err
+	}
+	if err := checkPodRecordings(cfg); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(image) == "" {
+		image = "ghcr.io/dsampath/mcp-gateway-envoy:latest"
+	}
+	repository, tag := splitImage(image)
+
+	chartCfg, apiKeys := chartConfig(cfg)
+	gatewayYAML := []byte(configToYAML(&chartCfg))
+
+	chart, err := yaml.Marshal(map[string]any{
+		"apiVersion":  "v2",
+		"name":        cfg.Gateway.Name,
+		"description": "MCP gateway " + cfg.Gateway.Name,
+		"type":        "application",
+		"version":     "0.1.0",
+		"appVersion":  tag,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal Chart.yaml: %w", err)
+	}
+
+	files := map[string][]byte{
+		"Chart.yaml":                   chart,
+		"values.yaml":                  []byte(helmValues(cfg, repository, tag, apiKeys)),
+		"files/gateway.yaml":           gatewayYAML,
+		"templates/_helpers.tpl":       []byte(helmHelpers),
+		"templates/configmap.yaml":     []byte(helmConfigMap),
+		"templates/secret.yaml":        []byte(helmSecret),
+		"templates/config-secret.yaml": []byte(helmConfigSecret),
+		"templates/deployment.yaml":    []byte(helmDeployment(cfg)),
+		"templates/service.yaml":       []byte(helmService),
+		"templates/pdb.yaml":           []byte(helmPodDisruptionBudget),
+		"templates/hpa.yaml":           []byte(helmHorizontalPodAutoscaler),
+		"templates/ingress.yaml":       []byte(helmIngress(cfg)),
+		"templates/stdio.yaml":         []byte(helmStdioServers(cfg)),
+		"templates/gateway.yaml":       []byte(helmGateway),
+		"templates/httproute.yaml":     []byte(helmHTTPRoute(cfg)),
+		"templates/NOTES.txt":          []byte(helmNotes),
+		".helmignore":                  []byte(".git/\n*.swp\n*.bak\n"),
+	}
+	return files, nil
+}
+
+// WriteHelmChart renders the chart for cfg into dir.
+func WriteHelmChart(cfg *config.Config, image, dir string) error {
+	files, err := RenderHelmChart(cfg, image)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		target := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("create chart directory: %w", err)
+		}
+		if err := os.WriteFile(target, files[p], 0o644); err != nil {
+			return fmt.Errorf("write chart file: %w", err)
+		}
+	}
+	return nil
+}
+
+// chartConfig copies cfg with each route's effective auth and policy
+// written out, since the chart's config has no auth providers or policies,
+// and inline API keys replaced by apiKeysFile paths under the mounted
+// Secret. Routes that name their own apiKeysFile keep it and their inline
+// keys; the chart mounts that file from a Secret (see helmAPIKeysFiles). Stdio servers with an image are rewired to their adapters, as in
+// the flat render. It returns the keys per route for values.yaml.
+func chartConfig(cfg *config.Config) (config.Config, map[string][]string) {
+	out := *kubernetesConfig(cfg, "")
+	out.AuthProviders, out.Policies = nil, nil
+	out.Auth.DefaultProvider = ""
+	out.Routes = make([]config.Route, len(cfg.Routes))
+	keys := map[string][]string{}
+	for i, r := range cfg.Routes {
+		r = cfg.ResolveRoute(r)
+		r.Auth.Use = ""
+		if len(r.Auth.APIKeys) > 0 && r.Auth.APIKeysFile == "" {
+			keys[r.Name] = r.Auth.APIKeys
+			r.Auth.APIKeys = nil
+			r.Auth.APIKeysFile = helmSecretsDir + "/" + r.Name
+		}
+		out.Routes[i] = r
+	}
+	return out, keys
+}
+
+// helmAPIKeysFiles lists the values entries mounting each apiKeysFile the
+// config names. The Secrets are not created by the chart.
+func helmAPIKeysFiles(cfg *config.Config) []map[string]any {
+	var files []map[string]any
+	for _, f := range apiKeysFiles(cfg) {
+		files = append(files, map[string]any{"path": f.path, "secretName": f.secretName, "key": f.key})
+	}
+	return files
+}
+
+// helmConfigSecrets returns the config's secret values keyed by the
+// variables configToYAML references.
+func helmConfigSecrets(cfg *config.Config) map[string]string {
+	values := map[string]string{}
+	for i, v := range cfg.SecretValues() {
+		values[configSecretVariable(i)] = v
+	}
+	return values
+}
+
+func splitImage(image string) (string, string) {
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
+
+func helmValues(cfg *config.Config, repository, tag string, apiKeys map[string][]string) string {
+	className := cfg.Gateway.GatewayClassName
+	if strings.TrimSpace(className) == "" {
+		className = defaultGatewayClass
+	}
+	resources := valuesYAML(resourceRequirements(cfg.Deployment.Resources))
+	keys := valuesYAML(map[string]any{"apiKeys": apiKeys})
+	if len(apiKeys) == 0 {
+		keys = "apiKeys: {}\n"
+	}
+	keys += "# Existing Secrets mounted at the apiKeysFile paths the config names.\n"
+	if files := helmAPIKeysFiles(cfg); len(files) > 0 {
+		keys += valuesYAML(map[string]any{"apiKeysFiles": files})
+	} else {
+		keys += "apiKeysFiles: []\n"
+	}
+	keys += "# Values the config read from files and secret variables, passed to the\n# gateway as the variables files/gateway.yaml references.\n"
+	if secrets := helmConfigSecrets(cfg); len(secrets) > 0 {
+		keys += valuesYAML(map[string]any{"config": secrets})
+	} else {
+		keys += "config: {}\n"
+	}
+
+	autoscaling := config.Autoscaling{MaxReplicas: max(3, replicaCount(cfg.Deployment))}
+	if a := cfg.Deployment.Autoscaling; a != nil {
+		autoscaling = *a
+	}
+	autoscaling = autoscalingDefaults(autoscaling)
+	spread := topologySpread(cfg.Deployment)
+	pdb := podDisruptionBudgetDoc(cfg, "")
+	minAvailable, maxUnavailable := 0, 1
+	if pdb != nil {
+		spec := pdb["spec"].(map[string]any)
+		minAvailable, _ = spec["minAvailable"].(int)
+		maxUnavailable, _ = spec["maxUnavailable"].(int)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Defaults for the %s gateway chart.
+
+fullnameOverride: ""
+
+image:
+  repository: %s
+  tag: %q
+  pullPolicy: IfNotPresent
+
+replicaCount: %d
+
+# A HorizontalPodAutoscaler; when enabled it owns the replica count.
+autoscaling:
+  enabled: %t
+  minReplicas: %d
+  maxReplicas: %d
+  targetCPUUtilizationPercentage: %d
+  # 0 leaves memory out of the scaling metrics.
+  targetMemoryUtilizationPercentage: %d
+
+# A PodDisruptionBudget; minAvailable wins when set. Keep it disabled while
+# a single replica runs, where it would only block node drains.
+podDisruptionBudget:
+  enabled: %t
+  minAvailable: %d
+  maxUnavailable: %d
+
+# Spreads the pods across topology domains, nodes by default.
+topologySpread:
+  topologyKey: %s
+  maxSkew: %d
+  # DoNotSchedule or ScheduleAnyway.
+  whenUnsatisfiable: %s
+
+# Container resource requests and limits.
+resources:
+%s
+
+service:
+  # ClusterIP, NodePort or LoadBalancer.
+  type: ClusterIP
+  port: %d
+
+# How traffic reaches the gateway from outside the cluster.
+exposure:
+  # none, ingress (networking.k8s.io Ingress) or gateway (Gateway API
+  # Gateway plus HTTPRoute).
+  type: none
+  ingress:
+    className: ""
+    host: ""
+    annotations: {}
+    tlsSecretName: ""
+  gateway:
+    # Set create to false to attach the HTTPRoute to an existing Gateway.
+    create: true
+    name: ""
+    className: %s
+    port: 80
+
+# API keys per route, mounted as files the gateway reads at startup. Prefer
+# existingSecret (keys named after routes, one key per line) over committing
+# keys to values.
+secrets:
+  existingSecret: ""
+`, cfg.Gateway.Name, repository, tag, replicaCount(cfg.Deployment),
+		cfg.Deployment.Autoscaling != nil, autoscaling.MinReplicas, autoscaling.MaxReplicas,
+		autoscaling.TargetCPUUtilizationPercentage, autoscaling.TargetMemoryUtilizationPercentage,
+		pdb != nil, minAvailable, maxUnavailable,
+		spread.TopologyKey, spread.MaxSkew, spread.WhenUnsatisfiable,
+		indentLines(resources, "  "),
+		parsePort(cfg.Gateway.ListenAddr), className)
+	b.WriteString(indentLines(keys, "  ") + "\n")
+	return b.String()
+}
+
+// valuesYAML marshals v with the two-space indent used in values.yaml.
+func valuesYAML(v any) string {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	_ = enc.Encode(v)
+	_ = enc.Close()
+	return b.String()
+}
+
+// Placeholders for install-time values in objects built in Go; helmObjects
+// turns them into chart expressions.
+const (
+	helmNamespace = "__RELEASE_NAMESPACE__"
+	helmImage     = "__GATEWAY_IMAGE__"
+)
+
+// helmObjects renders v as template text, escaping any template actions that
+// come from the config.
+func helmObjects(v any) string {
+	s := strings.ReplaceAll(valuesYAML(v), "{{", `{{ "{{" }}`)
+	return strings.NewReplacer(
+		helmNamespace, "{{ .Release.Namespace }}",
+		helmImage, `"{{ .Values.image.repository }}:{{ .Values.image.tag }}"`,
+	).Replace(s)
+}
+
+func indentLines(s, pad string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i := range lines {
+		lines[i] = pad + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+const helmHelpers = `{{- define "gateway.fullname" -}}
+{{- default .Release.Name .Values.fullnameOverride | trunc 63 | trimSuffix "-" -}}
+{{- end -}}
+
+{{- define "gateway.selectorLabels" -}}
+app.kubernetes.io/name: {{ .Chart.Name }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end -}}
+
+{{- define "gateway.labels" -}}
+{{ include "gateway.selectorLabels" . }}
+app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+helm.sh/chart: {{ printf "%s-%s" .Chart.Name .Chart.Version }}
+{{- end -}}
+
+{{- define "gateway.secretName" -}}
+{{- default (printf "%s-api-keys" (include "gateway.fullname" .)) .Values.secrets.existingSecret -}}
+{{- end -}}
+`
+
+const helmConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ include "gateway.fullname" . }}-config
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+data:
+  gateway.yaml: |
+    {{- .Files.Get "files/gateway.yaml" | nindent 4 }}
+`
+
+const helmSecret = `{{- if not .Values.secrets.existingSecret }}
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ include "gateway.secretName" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+type: Opaque
+stringData:
+  {{- range $route, $keys := .Values.secrets.apiKeys }}
+  {{ $route }}: {{ join "\n" $keys | quote }}
+  {{- end }}
+{{- end }}
+`
+
+const helmConfigSecret = `{{- with .Values.secrets.config }}
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ include "gateway.fullname" $ }}-config-secrets
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" $ | nindent 4 }}
+type: Opaque
+stringData:
+  {{- toYaml . | nindent 2 }}
+{{- end }}
+`
+
+// helmDeployment mirrors deploymentDoc's probes, security settings and
+// topology spread. The container port is the listenAddr baked into
+// files/gateway.yaml, not service.port, which only sets the Service's own
+// port.
+func helmDeployment(cfg *config.Config) string {
+	probePort, adminPortSpec := "http", ""
+	if port, ok := adminPort(cfg); ok {
+		probePort = "admin"
+		adminPortSpec = fmt.Sprintf("            - name: admin\n              containerPort: %d\n", port)
+	}
+	var secretMounts, secretVolumes string
+	volumes, mounts := envSecretVolumes(cfg)
+	for i := range volumes {
+		secretMounts += fmt.Sprintf("            - name: %s\n              mountPath: %s\n              readOnly: true\n", mounts[i]["name"], mounts[i]["mountPath"])
+		secretVolumes += fmt.Sprintf("        - name: %s\n          secret:\n            secretName: %s\n", volumes[i]["name"], volumes[i]["secret"].(map[string]any)["secretName"])
+	}
+	var secretsChecksum, secretsEnv string
+	if len(cfg.SecretValues()) > 0 {
+		secretsChecksum = "        checksum/config-secrets: {{ toYaml .Values.secrets.config | sha256sum }}\n"
+		secretsEnv = "          envFrom:\n            - secretRef:\n                name: {{ include \"gateway.fullname\" . }}-config-secrets\n"
+	}
+	var initContainers, sidecars string
+	for _, w := range stdioWorkloads(cfg) {
+		if !w.standalone {
+			sidecars += indentLines(helmObjects([]map[string]any{w.adapterContainer()}), "        ") + "\n"
+		}
+	}
+	if sidecars != "" {
+		initContainers = "      initContainers:\n" + indentLines(helmObjects([]map[string]any{installAdapterContainer(helmImage)}), "        ") + "\n"
+		secretVolumes += "        - name: adapter-bin\n          emptyDir: {}\n"
+	}
+	return `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "gateway.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+spec:
+  {{- if not .Values.autoscaling.enabled }}
+  replicas: {{ .Values.replicaCount }}
+  {{- end }}
+  selector:
+    matchLabels:
+      {{- include "gateway.selectorLabels" . | nindent 6 }}
+  template:
+    metadata:
+      labels:
+        {{- include "gateway.selectorLabels" . | nindent 8 }}
+        app: ` + cfg.Gateway.Name + `
+      annotations:
+        checksum/config: {{ .Files.Get "files/gateway.yaml" | sha256sum }}
+` + secretsChecksum + `    spec:
+      securityContext:
+        runAsNonRoot: true
+        runAsUser: 65532
+        runAsGroup: 65532
+        fsGroup: 65532
+        seccompProfile:
+          type: RuntimeDefault
+      topologySpreadConstraints:
+        - maxSkew: {{ .Values.topologySpread.maxSkew }}
+          topologyKey: {{ .Values.topologySpread.topologyKey }}
+          whenUnsatisfiable: {{ .Values.topologySpread.whenUnsatisfiable }}
+          labelSelector:
+            matchLabels:
+              {{- include "gateway.selectorLabels" . | nindent 14 }}
+` + initContainers + `      containers:
+        - name: gateway
+          image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+          imagePullPolicy: {{ .Values.image.pullPolicy }}
+          args: ["serve", "--file", "/etc/mcp-gateway/gateway.yaml"]
+` + secretsEnv + `          ports:
+            - name: http
+              containerPort: ` + fmt.Sprint(parsePort(cfg.Gateway.ListenAddr)) + `
+` + adminPortSpec + `          livenessProbe:
+            httpGet:
+              path: /healthz
+              port: ` + probePort + `
+            periodSeconds: 10
+            failureThreshold: 3
+          readinessProbe:
+            httpGet:
+              path: /readyz
+              port: ` + probePort + `
+            periodSeconds: 5
+            failureThreshold: 2
+          resources:
+            {{- toYaml .Values.resources | nindent 12 }}
+          securityContext:
+            allowPrivilegeEscalation: false
+            readOnlyRootFilesystem: true
+            capabilities:
+              drop: ["ALL"]
+          volumeMounts:
+            - name: config
+              mountPath: /etc/mcp-gateway
+            - name: api-keys
+              mountPath: ` + helmSecretsDir + `
+              readOnly: true
+            - name: tmp
+              mountPath: /tmp
+            {{- range $i, $f := .Values.secrets.apiKeysFiles }}
+            - name: api-keys-file-{{ $i }}
+              mountPath: {{ $f.path }}
+              subPath: {{ $f.key }}
+              readOnly: true
+            {{- end }}
+` + secretMounts + sidecars + `      volumes:
+        - name: config
+          configMap:
+            name: {{ include "gateway.fullname" . }}-config
+        - name: api-keys
+          secret:
+            secretName: {{ include "gateway.secretName" . }}
+            optional: true
+        - name: tmp
+          emptyDir: {}
+        {{- range $i, $f := .Values.secrets.apiKeysFiles }}
+        - name: api-keys-file-{{ $i }}
+          secret:
+            secretName: {{ $f.secretName }}
+        {{- end }}
+` + secretVolumes
+}
+
+const helmService = `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ include "gateway.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+spec:
+  type: {{ .Values.service.type }}
+  selector:
+    {{- include "gateway.selectorLabels" . | nindent 4 }}
+  ports:
+    - name: http
+      port: {{ .Values.service.port }}
+      targetPort: http
+`
+
+const helmPodDisruptionBudget = `{{- if .Values.podDisruptionBudget.enabled }}
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: {{ include "gateway.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+spec:
+  selector:
+    matchLabels:
+      {{- include "gateway.selectorLabels" . | nindent 6 }}
+  {{- if .Values.podDisruptionBudget.minAvailable }}
+  minAvailable: {{ .Values.podDisruptionBudget.minAvailable }}
+  {{- else }}
+  maxUnavailable: {{ .Values.podDisruptionBudget.maxUnavailable }}
+  {{- end }}
+{{- end }}
+`
+
+const helmHorizontalPodAutoscaler = `{{- if .Values.autoscaling.enabled }}
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ include "gateway.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: {{ include "gateway.fullname" . }}
+  minReplicas: {{ .Values.autoscaling.minReplicas }}
+  maxReplicas: {{ .Values.autoscaling.maxReplicas }}
+  metrics:
+    - type: Resource
+      resource:
+        name: cpu
+        target:
+          type: Utilization
+          averageUtilization: {{ .Values.autoscaling.targetCPUUtilizationPercentage }}
+    {{- with .Values.autoscaling.targetMemoryUtilizationPercentage }}
+    - type: Resource
+      resource:
+        name: memory
+        target:
+          type: Utilization
+          averageUtilization: {{ . }}
+    {{- end }}
+{{- end }}
+`
+
+// helmStdioServers renders the Deployment, Service and NetworkPolicy of each
+// standalone stdio server.
+func helmStdioServers(cfg *config.Config) string {
+	var docs []string
+	for _, doc := range standaloneStdioDocs(cfg, helmNamespace, helmImage) {
+		docs = append(docs, helmObjects(doc))
+	}
+	return strings.Join(docs, "---\n")
+}
+
+// helmIngress and helmHTTPRoute list the config's route paths so only MCP
+// traffic is exposed. Routes with hosts get an Ingress rule per host; the
+// others share the rule for exposure.ingress.host.
+func helmIngress(cfg *config.Config) string {
+	pathEntry := func(path string) string {
+		return fmt.Sprintf(`          - path: %s
+            pathType: Prefix
+            backend:
+              service:
+                name: {{ include "gateway.fullname" $ }}
+                port:
+                  name: http
+`, path)
+	}
+	var hostless strings.Builder
+	var hosts []string
+	hostPaths := map[string]*strings.Builder{}
+	for _, r := range cfg.Routes {
+		if len(r.Hosts) == 0 {
+			hostless.WriteString(pathEntry(r.Path))
+			continue
+		}
+		for _, h := range r.Hosts {
+			if hostPaths[h] == nil {
+				hosts = append(hosts, h)
+				hostPaths[h] = &strings.Builder{}
+			}
+			hostPaths[h].WriteString(pathEntry(r.Path))
+		}
+	}
+
+	var rules, tlsHosts strings.Builder
+	if hostless.Len() > 0 {
+		rules.WriteString(`    - {{- with .Values.exposure.ingress.host }}
+      host: {{ . | quote }}
+      {{- end }}
+      http:
+        paths:
+` + hostless.String())
+	}
+	for _, h := range hosts {
+		fmt.Fprintf(&rules, "    - host: %q\n      http:\n        paths:\n%s", h, hostPaths[h].String())
+		fmt.Fprintf(&tlsHosts, "        - %q\n", h)
+	}
+	tls := `      {{- with $.Values.exposure.ingress.host }}
+      hosts: [{{ . | quote }}]
+      {{- end }}
+`
+	if len(hosts) > 0 {
+		tls = `      hosts:
+        {{- with $.Values.exposure.ingress.host }}
+        - {{ . | quote }}
+        {{- end }}
+` + tlsHosts.String()
+	}
+	return `{{- if eq .Values.exposure.type "ingress" }}
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: {{ include "gateway.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+  {{- with .Values.exposure.ingress.annotations }}
+  annotations:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+spec:
+  {{- with .Values.exposure.ingress.className }}
+  ingressClassName: {{ . }}
+  {{- end }}
+  {{- with .Values.exposure.ingress.tlsSecretName }}
+  tls:
+    - secretName: {{ . }}
+` + tls + `  {{- end }}
+  rules:
+` + rules.String() + `{{- end }}
+`
+}
+
+const helmGateway = `{{- if and (eq .Values.exposure.type "gateway") .Values.exposure.gateway.create }}
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: {{ default (include "gateway.fullname" .) .Values.exposure.gateway.name }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+spec:
+  gatewayClassName: {{ .Values.exposure.gateway.className }}
+  listeners:
+    - name: http
+      protocol: HTTP
+      port: {{ .Values.exposure.gateway.port }}
+{{- end }}
+`
+
+func helmHTTPRoute(cfg *config.Config) string {
+	var matches strings.Builder
+	for _, r := range cfg.Routes {
+		fmt.Fprintf(&matches, `        - path:
+            type: PathPrefix
+            value: %s
+`, r.Path)
+	}
+	return `{{- if eq .Values.exposure.type "gateway" }}
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: {{ include "gateway.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+  labels:
+    {{- include "gateway.labels" . | nindent 4 }}
+spec:
+  parentRefs:
+    - name: {{ default (include "gateway.fullname" .) .Values.exposure.gateway.name }}
+  rules:
+    - matches:
+` + matches.String() + `      backendRefs:
+        - name: {{ include "gateway.fullname" . }}
+          port: {{ .Values.service.port }}
+{{- end }}
+`
+}
+
+const helmNotes = `The MCP gateway {{ include "gateway.fullname" . }} is running in {{ .Release.Namespace }}.
+
+In-cluster endpoint: http://{{ include "gateway.fullname" . }}.{{ .Release.Namespace }}.svc:{{ .Values.service.port }}
+`
@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// helmFiles implements .Files.Get for renderChart.
+type helmFiles map[string][]byte
+
+func (f helmFiles) Get(name string) string { return string(f[name]) }
+
+// renderChart executes the chart templates the way helm template would,
+// with the subset of Sprig functions the chart uses, and returns the
+// rendered documents per template.
+func renderChart(t *testing.T, files map[string][]byte, values map[string]any) map[string]string {
+	t.Helper()
+	root := template.New("chart").Option("missingkey=zero")
+	root.Funcs(template.FuncMap{
+		"include": func(name string, data any) (string, error) {
+			var b bytes.Buffer
+			err := root.ExecuteTemplate(&b, name, data)
+			return b.String(), err
+		},
+		"default": func(def, v any) any {
+			if v == nil || v == "" || v == false {
+				return def
+			}
+			return v
+		},
+		"trunc": func(n int, s string) string {
+			if len(s) > n {
+				return s[:n]
+			}
+			return s
+		},
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"quote":      func(v any) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"nindent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"toYaml": func(v any) string {
+			b, _ := yaml.Marshal(v)
+			return strings.TrimSuffix(string(b), "\n")
+		},
+		"sha256sum": func(s string) string {
+			sum := sha256.Sum256([]byte(s))
+			return hex.EncodeToString(sum[:])
+		},
+		"join": func(sep string, v any) string {
+			var parts []string
+			for _, p := range v.([]any) {
+				parts = append(parts, fmt.Sprint(p))
+			}
+			return strings.Join(parts, sep)
+		},
+	})
+
+	var chart map[string]any
+	if err := yaml.Unmarshal(files["Chart.yaml"], &chart); err != nil {
+		t.Fatalf("Chart.yaml: %v", err)
+	}
+	data := map[string]any{
+		"Values":  values,
+		"Release": map[string]any{"Name": "demo", "Namespace": "mcp", "Service": "Helm"},
+		"Chart":   map[string]any{"Name": chart["name"], "Version": chart["version"], "AppVersion": chart["appVersion"]},
+		"Files":   helmFiles(files),
+	}
+
+	var names []string
+	for name, content := range files {
+		if !strings.HasPrefix(name, "templates/") {
+			continue
+		}
+		if _, err := root.New(name).Parse(string(content)); err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+		if strings.HasSuffix(name, ".yaml") {
+			names = append(names, name)
+		}
+	}
+	out := map[string]string{}
+	for _, name := range names {
+		var b bytes.Buffer
+		if err := root.ExecuteTemplate(&b, name, data); err != nil {
+			t.Fatalf("execute %s: %v", name, err)
+		}
+		out[name] = b.String()
+	}
+	return out
+}
+
+func TestHelmChartLints(t *testing.T) {
+	cfg := reconcileTestConfig()
+	cfg.APIVersion, cfg.Kind = "mcp.envoy.io/v1alpha1", "GatewayConfig"
+	cfg.Deployment.Replicas = 2
+	cfg.Routes = append(cfg.Routes, config.Route{
+		Name: "private", Path: "/mcp/private", Server: "weather",
+		Auth: &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"k1", "k2"}},
+	}, config.Route{
+		Name: "team", Path: "/mcp/team", Server: "weather", Hosts: []string{"team.mcp.example.com"},
+		Auth: &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeysFile: "/etc/keys/team.txt"},
+	})
+	files, err := RenderHelmChart(cfg, "registry.example.com:5000/mcp/gateway:1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chart map[string]any
+	if err := yaml.Unmarshal(files["Chart.yaml"], &chart); err != nil {
+		t.Fatalf("Chart.yaml: %v", err)
+	}
+	if chart["apiVersion"] != "v2" || chart["name"] != "mcp-gateway" || chart["appVersion"] != "1.2.3" ||
+		!regexp.MustCompile(`^\d+\.\d+\.\d+$`).MatchString(fmt.Sprint(chart["version"])) {
+		t.Fatalf("Chart.yaml is not lint-clean: %v", chart)
+	}
+
+	// The embedded config must still load, with keys moved to the Secret.
+	embedded, err := config.Load(files["files/gateway.yaml"])
+	if err != nil {
+		t.Fatalf("embedded config: %v", err)
+	}
+	if a := embedded.Routes[1].Auth; len(a.APIKeys) != 0 || a.APIKeysFile != helmSecretsDir+"/private" {
+		t.Fatalf("expected api keys replaced by apiKeysFile, got %+v", a)
+	}
+	if a := embedded.Routes[2].Auth; a.APIKeysFile != "/etc/keys/team.txt" {
+		t.Fatalf("expected the route's own apiKeysFile kept, got %+v", a)
+	}
+
+	for _, exposure := range []string{"none", "ingress", "gateway"} {
+		var values map[string]any
+		if err := yaml.Unmarshal(files["values.yaml"], &values); err != nil {
+			t.Fatalf("values.yaml: %v", err)
+		}
+		values["exposure"].(map[string]any)["type"] = exposure
+		values["exposure"].(map[string]any)["ingress"].(map[string]any)["host"] = "mcp.example.com"
+		values["exposure"].(map[string]any)["ingress"].(map[string]any)["tlsSecretName"] = "mcp-tls"
+		values["service"].(map[string]any)["port"] = 80
+		autoscaled := exposure == "gateway"
+		values["autoscaling"].(map[string]any)["enabled"] = autoscaled
+
+		kinds := map[string]map[string]any{}
+		for name, out := range renderChart(t, files, values) {
+			decoder := yaml.NewDecoder(strings.NewReader(out))
+			for {
+				var doc map[string]any
+				if err := decoder.Decode(&doc); err != nil {
+					if err.Error() != "EOF" {
+						t.Fatalf("%s (%s) renders invalid YAML: %v\n%s", name, exposure, err, out)
+					}
+					break
+				}
+				if doc == nil {
+					continue
+				}
+				meta, _ := doc["metadata"].(map[string]any)
+				if doc["apiVersion"] == nil || doc["kind"] == nil || meta["name"] == nil || meta["namespace"] != "mcp" {
+					t.Fatalf("%s (%s) renders an incomplete object: %v", name, exposure, doc)
+				}
+				kinds[doc["kind"].(string)] = doc
+			}
+		}
+
+		for _, kind := range []string{"ConfigMap", "Secret", "Deployment", "Service"} {
+			if kinds[kind] == nil {
+				t.Fatalf("exposure %s: chart is missing a %s", exposure, kind)
+			}
+		}
+		if (kinds["Ingress"] != nil) != (exposure == "ingress") || (kinds["HTTPRoute"] != nil) != (exposure == "gateway") {
+			t.Fatalf("exposure %s rendered unexpected kinds: %v", exposure, kinds)
+		}
+		// A route with hosts is reachable on its own host, not only on
+		// exposure.ingress.host.
+		if ingress := kinds["Ingress"]; ingress != nil {
+			ingressSpec := ingress["spec"].(map[string]any)
+			rules := ingressSpec["rules"].([]any)
+			team := rules[1].(map[string]any)
+			teamPaths := team["http"].(map[string]any)["paths"].([]any)
+			tlsHosts := ingressSpec["tls"].([]any)[0].(map[string]any)["hosts"].([]any)
+			if len(rules) != 2 || rules[0].(map[string]any)["host"] != "mcp.example.com" || team["host"] != "team.mcp.example.com" ||
+				len(teamPaths) != 1 || teamPaths[0].(map[string]any)["path"] != "/mcp/team" || len(tlsHosts) != 2 {
+				t.Fatalf("unexpected ingress rules %v and tls hosts %v", rules, tlsHosts)
+			}
+		}
+		spec := kinds["Deployment"]["spec"].(map[string]any)
+		selector := spec["selector"].(map[string]any)["matchLabels"].(map[string]any)
+		podLabels := spec["template"].(map[string]any)["metadata"].(map[string]any)["labels"].(map[string]any)
+		for k, v := range selector {
+			if podLabels[k] != v {
+				t.Fatalf("deployment selector %s=%v does not match pod labels %v", k, v, podLabels)
+			}
+		}
+		if _, ok := spec["replicas"]; ok == autoscaled || (kinds["HorizontalPodAutoscaler"] != nil) != autoscaled {
+			t.Fatalf("exposure %s: expected the HPA to own replicas only when autoscaling is enabled: %v", exposure, spec)
+		}
+		if pdb := kinds["PodDisruptionBudget"]; pdb == nil || pdb["spec"].(map[string]any)["maxUnavailable"] != 1 {
+			t.Fatalf("expected a PodDisruptionBudget for two replicas, got %v", pdb)
+		}
+		spread := spec["template"].(map[string]any)["spec"].(map[string]any)["topologySpreadConstraints"].([]any)[0].(map[string]any)
+		if spread["topologyKey"] != "kubernetes.io/hostname" || spread["labelSelector"] == nil {
+			t.Fatalf("expected pods spread across nodes, got %v", spread)
+		}
+		// service.port only moves the Service; the pod keeps listenAddr.
+		container := spec["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]any)[0].(map[string]any)
+		servicePort := kinds["Service"]["spec"].(map[string]any)["ports"].([]any)[0].(map[string]any)
+		if port := container["ports"].([]any)[0].(map[string]any); port["containerPort"] != 8080 || servicePort["port"] != 80 || servicePort["targetPort"] != "http" {
+			t.Fatalf("expected Service port 80 targeting container port 8080, got %v and %v", servicePort, port)
+		}
+		pod := spec["template"].(map[string]any)["spec"].(map[string]any)
+		var mounted, volume bool
+		for _, m := range container["volumeMounts"].([]any) {
+			m := m.(map[string]any)
+			mounted = mounted || (m["name"] == "api-keys-file-0" && m["mountPath"] == "/etc/keys/team.txt" && m["subPath"] == "team.txt")
+		}
+		for _, v := range pod["volumes"].([]any) {
+			v := v.(map[string]any)
+			if v["name"] == "api-keys-file-0" {
+				volume = v["secret"].(map[string]any)["secretName"] == "mcp-gateway-api-keys-0"
+			}
+		}
+		if !mounted || !volume {
+			t.Fatalf("expected the team apiKeysFile mounted from its Secret, got %v", pod)
+		}
+		if got := kinds["Secret"]["stringData"].(map[string]any)["private"]; got != "k1\nk2" {
+			t.Fatalf("expected route keys in the Secret, got %q", got)
+		}
+	}
+}
+
+func TestHelmChartRendersStdioAdapters(t *testing.T) {
+	cfg := reconcileTestConfig()
+	cfg.APIVersion, cfg.Kind = "mcp.envoy.io/v1alpha1", "GatewayConfig"
+	cfg.Servers = append(cfg.Servers,
+		config.Server{Name: "fs", Transport: "stdio", Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-filesystem", "{{ .Values.root }}"}, Image: "node:22-slim"},
+		config.Server{Name: "git", Transport: "stdio", Command: "uvx", Args: []string{"mcp-server-git"}, Image: "ghcr.io/astral-sh/uv:python3.12-bookworm-slim", Placement: "standalone"},
+	)
+	cfg.Routes = append(cfg.Routes,
+		config.Route{Name: "fs", Path: "/mcp/fs", Server: "fs"},
+		config.Route{Name: "git", Path: "/mcp/git", Server: "git"},
+	)
+	files, err := RenderHelmChart(cfg, "example/gateway:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embedded, err := config.Load(files["files/gateway.yaml"])
+	if err != nil {
+		t.Fatalf("embedded config: %v", err)
+	}
+	urls := map[string]string{}
+	for _, s := range embedded.Servers {
+		urls[s.Name] = s.Transport + " " + s.URL
+	}
+	if urls["fs"] != "http http://127.0.0.1:9100" || urls["git"] != "http http://mcp-gateway-git:8080" {
+		t.Fatalf("expected image-based servers rewired to their adapters, got %v", urls)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(files["values.yaml"], &values); err != nil {
+		t.Fatalf("values.yaml: %v", err)
+	}
+	objects := map[string]map[string]any{}
+	for name, out := range renderChart(t, files, values) {
+		decoder := yaml.NewDecoder(strings.NewReader(out))
+		for {
+			var doc map[string]any
+			if err := decoder.Decode(&doc); err != nil {
+				if err.Error() != "EOF" {
+					t.Fatalf("%s renders invalid YAML: %v\n%s", name, err, out)
+				}
+				break
+			}
+			if doc != nil {
+				objects[doc["kind"].(string)+"/"+doc["metadata"].(map[string]any)["name"].(string)] = doc
+			}
+		}
+	}
+
+	pod := objects["Deployment/demo"]["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+	containers := pod["containers"].([]any)
+	if len(containers) != 2 {
+		t.Fatalf("expected the gateway and the fs sidecar, got %v", containers)
+	}
+	sidecar := containers[1].(map[string]any)
+	command := fmt.Sprint(sidecar["command"])
+	if sidecar["image"] != "node:22-slim" || !strings.Contains(command, "--listen 127.0.0.1:9100") || !strings.Contains(command, "{{ .Values.root }}") {
+		t.Fatalf("unexpected sidecar %v", sidecar)
+	}
+	if init := pod["initContainers"].([]any)[0].(map[string]any); init["image"] != "example/gateway:1" {
+		t.Fatalf("expected the adapter installed from the gateway image, got %v", init)
+	}
+
+	standalone := objects["Deployment/mcp-gateway-git"]
+	if standalone == nil || objects["Service/mcp-gateway-git"] == nil || objects["NetworkPolicy/mcp-gateway-git"] == nil {
+		t.Fatalf("expected a standalone Deployment, Service and NetworkPolicy for git, got %v", objects)
+	}
+	if ns := standalone["metadata"].(map[string]any)["namespace"]; ns != "mcp" {
+		t.Fatalf("expected the release namespace, got %v", ns)
+	}
+	podLabels := objects["Deployment/demo"]["spec"].(map[string]any)["template"].(map[string]any)["metadata"].(map[string]any)["labels"].(map[string]any)
+	from := objects["NetworkPolicy/mcp-gateway-git"]["spec"].(map[string]any)["ingress"].([]any)[0].(map[string]any)["from"].([]any)
+	for k, v := range from[0].(map[string]any)["podSelector"].(map[string]any)["matchLabels"].(map[string]any) {
+		if podLabels[k] != v {
+			t.Fatalf("NetworkPolicy does not admit the gateway pods: %s=%v, pod labels %v", k, v, podLabels)
+		}
+	}
+}
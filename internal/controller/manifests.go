@@ -1,18 +1,45 @@
 package controller
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/djsam/mcp-gateway-envoy/internal/config"
 	"gopkg.in/yaml.v3"
 )
 
-// RenderManifests renders Kubernetes YAML for gateway runtime + MCP route policy resources.
+// Ownership labels stamped on every rendered object so the reconciler can
+// find (and prune) what it manages.
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "mcp-gateway-envoy"
+	GatewayLabel   = "mcp.envoy.io/gateway"
+)
+
+// RenderManifests renders Kubernetes YAML for the gateway runtime plus the
+// Gateway API and Envoy Gateway resources that route and police MCP traffic.
 func RenderManifests(cfg *config.Config, namespace string, image string) ([]byte, error) {
+	docs, err := RenderObjects(cfg, namespace, image)
+	if err != nil {
+		return nil, err
+	}
+	return marshalDocs(docs)
+}
+
+// RenderObjects returns the objects RenderManifests serializes, in apply order.
+func RenderObjects(cfg *config.Config, namespace string, image string) ([]map[string]any, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config is nil")
 	}
+	if err := checkPodSandbox(cfg); err != nil {
+		return nil, err
+	}
+	if err := checkPodRecordings(cfg); err != nil {
+		return nil, err
+	}
 	if strings.TrimSpace(namespace) == "" {
 		namespace = "default"
 	}
@@ -20,21 +47,53 @@ func RenderManifests(cfg *config.Config, namespace string, image string) ([]byte
 		image = "ghcr.io/dsampath/mcp-gateway-envoy:latest"
 	}
 
-	docs := make([]map[string]any, 0, 4+len(cfg.Routes)*2)
-	docs = append(docs,
-		namespaceDoc(namespace),
-		configMapDoc(cfg, namespace),
-		deploymentDoc(cfg, namespace, image),
-		serviceDoc(cfg, namespace),
-	)
+	// The runtime and Envoy see stdio servers with an image as HTTP servers
+	// reached through their adapters.
+	runtimeCfg := kubernetesConfig(cfg, namespace)
+	deployment := deploymentDoc(runtimeCfg, namespace, image)
+	addStdioSidecars(deployment, cfg, image)
+	addEnvSecretVolumes(deployment, runtimeCfg)
+	addAPIKeysFileVolumes(deployment, runtimeCfg)
+	addConfigSecretsEnv(deployment, runtimeCfg)
 
-	for _, r := range cfg.Routes {
-		docs = append(docs, routeDoc(r, namespace))
-		if kind := routeAuthKind(cfg, r); kind != "none" {
-			docs = append(docs, authPolicyDoc(r, namespace, kind))
+	docs := make([]map[string]any, 0, 7+len(cfg.Routes)*4)
+	docs = append(docs, namespaceDoc(namespace), configMapDoc(runtimeCfg, namespace))
+	if secret := configSecretsDoc(runtimeCfg, namespace); secret != nil {
+		docs = append(docs, secret)
+	}
+	docs = append(docs, deployment, serviceDoc(cfg, namespace))
+	docs = append(docs, standaloneStdioDocs(cfg, namespace, image)...)
+	if pdb := podDisruptionBudgetDoc(cfg, namespace); pdb != nil {
+		docs = append(docs, pdb)
+	}
+	if hpa := horizontalPodAutoscalerDoc(cfg, namespace); hpa != nil {
+		docs = append(docs, hpa)
+	}
+	docs = append(docs, envoyGatewayDocs(runtimeCfg, namespace)...)
+
+	for _, d := range docs {
+		meta := d["metadata"].(map[string]any)
+		labels, _ := meta["labels"].(map[string]any)
+		if labels == nil {
+			labels = map[string]any{}
+			meta["labels"] = labels
+		}
+		for k, v := range OwnerLabels(cfg) {
+			labels[k] = v
 		}
 	}
+	return docs, nil
+}
 
+// OwnerLabels returns the labels identifying objects owned by cfg's gateway.
+func OwnerLabels(cfg *config.Config) map[string]string {
+	return map[string]string{
+		ManagedByLabel: ManagedByValue,
+		GatewayLabel:   cfg.Gateway.Name,
+	}
+}
+
+func marshalDocs(docs []map[string]any) ([]byte, error) {
 	var b strings.Builder
 	for i, d := range docs {
 		if i > 0 {
@@ -74,58 +133,258 @@ func configMapDoc(cfg *config.Config, namespace string) map[string]any {
 }
 
 func deploymentDoc(cfg *config.Config, namespace, image string) map[string]any {
-	return map[string]any{
-		"apiVersion": "apps/v1",
-		"kind":       "Deployment",
-		"metadata": map[string]any{
-			"name":      cfg.Gateway.Name,
-			"namespace": namespace,
-			"labels": map[string]any{
-				"app": cfg.Gateway.Name,
+	d := cfg.Deployment
+	labels := map[string]any{"app": cfg.Gateway.Name}
+
+	ports := []map[string]any{{"name": "http", "containerPort": parsePort(cfg.Gateway.ListenAddr)}}
+	probePort := "http"
+	if adminPort, ok := adminPort(cfg); ok {
+		ports = append(ports, map[string]any{"name": "admin", "containerPort": adminPort})
+		probePort = "admin"
+	}
+
+	spread := topologySpread(d)
+	podSpec := map[string]any{
+		// Matches the distroless nonroot user the image runs as.
+		"securityContext": map[string]any{
+			"runAsNonRoot":   true,
+			"runAsUser":      65532,
+			"runAsGroup":     65532,
+			"fsGroup":        65532,
+			"seccompProfile": map[string]any{"type": "RuntimeDefault"},
+		},
+		"topologySpreadConstraints": []map[string]any{
+			{
+				"maxSkew":           spread.MaxSkew,
+				"topologyKey":       spread.TopologyKey,
+				"whenUnsatisfiable": spread.WhenUnsatisfiable,
+				"labelSelector":     map[string]any{"matchLabels": labels},
 			},
 		},
-		"spec": map[string]any{
-			"replicas": 1,
-			"selector": map[string]any{
-				"matchLabels": map[string]any{
-					"app": cfg.Gateway.Name,
+		"containers": []map[string]any{
+			{
+				"name":      "gateway",
+				"image":     image,
+				"args":      []string{"serve", "--file", "/etc/mcp-gateway/gateway.yaml"},
+				"ports":     ports,
+				"resources": resourceRequirements(d.Resources),
+				"livenessProbe": map[string]any{
+					"httpGet":          map[string]any{"path": "/healthz", "port": probePort},
+					"periodSeconds":    10,
+					"failureThreshold": 3,
 				},
-			},
-			"template": map[string]any{
-				"metadata": map[string]any{
-					"labels": map[string]any{
-						"app": cfg.Gateway.Name,
-					},
+				"readinessProbe": map[string]any{
+					"httpGet":          map[string]any{"path": "/readyz", "port": probePort},
+					"periodSeconds":    5,
+					"failureThreshold": 2,
+				},
+				"securityContext": map[string]any{
+					"allowPrivilegeEscalation": false,
+					"readOnlyRootFilesystem":   true,
+					"capabilities":             map[string]any{"drop": []string{"ALL"}},
 				},
-				"spec": map[string]any{
-					"containers": []map[string]any{
-						{
-							"name":  "gateway",
-							"image": image,
-							"args":  []string{"serve", "--file", "/etc/mcp-gateway/gateway.yaml"},
-							"ports": []map[string]any{{"name": "http", "containerPort": parsePort(cfg.Gateway.ListenAddr)}},
-							"volumeMounts": []map[string]any{
-								{
-									"name":      "config",
-									"mountPath": "/etc/mcp-gateway",
-								},
-							},
-						},
+				"volumeMounts": []map[string]any{
+					{
+						"name":      "config",
+						"mountPath": "/etc/mcp-gateway",
 					},
-					"volumes": []map[string]any{
-						{
-							"name": "config",
-							"configMap": map[string]any{
-								"name": cfg.Gateway.Name + "-config",
-							},
-						},
+					{
+						// Writable scratch space for stdio servers under a
+						// read-only root filesystem.
+						"name":      "tmp",
+						"mountPath": "/tmp",
 					},
 				},
 			},
 		},
+		"volumes": []map[string]any{
+			{
+				"name": "config",
+				"configMap": map[string]any{
+					"name": cfg.Gateway.Name + "-config",
+				},
+			},
+			{
+				"name":     "tmp",
+				"emptyDir": map[string]any{},
+			},
+		},
+	}
+
+	spec := map[string]any{
+		"selector": map[string]any{
+			"matchLabels": labels,
+		},
+		"template": map[string]any{
+			"metadata": map[string]any{
+				"labels": labels,
+				// Rolls the pods whenever the rendered config changes.
+				"annotations": map[string]any{
+					"checksum/config": configChecksum(cfg),
+				},
+			},
+			"spec": podSpec,
+		},
+	}
+	// With an autoscaler the HPA owns the replica count.
+	if d.Autoscaling == nil {
+		spec["replicas"] = replicaCount(d)
+	}
+
+	return map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      cfg.Gateway.Name,
+			"namespace": namespace,
+			"labels":    map[string]any{"app": cfg.Gateway.Name},
+		},
+		"spec": spec,
+	}
+}
+
+// topologySpread returns d's spread constraint with defaults filled in.
+func topologySpread(d config.DeploymentSettings) config.TopologySpread {
+	spread := config.TopologySpread{}
+	if d.TopologySpread != nil {
+		spread = *d.TopologySpread
+	}
+	if spread.TopologyKey == "" {
+		spread.TopologyKey = "kubernetes.io/hostname"
+	}
+	if spread.MaxSkew == 0 {
+		spread.MaxSkew = 1
+	}
+	if spread.WhenUnsatisfiable == "" {
+		spread.WhenUnsatisfiable = "ScheduleAnyway"
+	}
+	return spread
+}
+
+// podDisruptionBudgetDoc returns nil when at most one replica runs, where a
+// budget would only block node drains.
+func podDisruptionBudgetDoc(cfg *config.Config, namespace string) map[string]any {
+	d := cfg.Deployment
+	replicas := replicaCount(d)
+	if d.Autoscaling != nil {
+		replicas = max(d.Autoscaling.MinReplicas, d.Autoscaling.MaxReplicas)
+	}
+	if replicas <= 1 {
+		return nil
+	}
+	spec := map[string]any{
+		"selector": map[string]any{"matchLabels": map[string]any{"app": cfg.Gateway.Name}},
+	}
+	switch p := d.PodDisruptionBudget; {
+	case p != nil && p.MinAvailable > 0:
+		spec["minAvailable"] = p.MinAvailable
+	case p != nil && p.MaxUnavailable > 0:
+		spec["maxUnavailable"] = p.MaxUnavailable
+	default:
+		spec["maxUnavailable"] = 1
+	}
+	return map[string]any{
+		"apiVersion": "policy/v1",
+		"kind":       "PodDisruptionBudget",
+		"metadata": map[string]any{
+			"name":      cfg.Gateway.Name,
+			"namespace": namespace,
+		},
+		"spec": spec,
 	}
 }
 
+func horizontalPodAutoscalerDoc(cfg *config.Config, namespace string) map[string]any {
+	a := cfg.Deployment.Autoscaling
+	if a == nil {
+		return nil
+	}
+	as := autoscalingDefaults(*a)
+	metrics := []map[string]any{utilizationMetric("cpu", as.TargetCPUUtilizationPercentage)}
+	if as.TargetMemoryUtilizationPercentage > 0 {
+		metrics = append(metrics, utilizationMetric("memory", as.TargetMemoryUtilizationPercentage))
+	}
+	return map[string]any{
+		"apiVersion": "autoscaling/v2",
+		"kind":       "HorizontalPodAutoscaler",
+		"metadata": map[string]any{
+			"name":      cfg.Gateway.Name,
+			"namespace": namespace,
+		},
+		"spec": map[string]any{
+			"scaleTargetRef": map[string]any{"apiVersion": "apps/v1", "kind": "Deployment", "name": cfg.Gateway.Name},
+			"minReplicas":    as.MinReplicas,
+			"maxReplicas":    as.MaxReplicas,
+			"metrics":        metrics,
+		},
+	}
+}
+
+// autoscalingDefaults fills a's unset minimum and CPU target.
+func autoscalingDefaults(a config.Autoscaling) config.Autoscaling {
+	if a.MinReplicas == 0 {
+		a.MinReplicas = 1
+	}
+	if a.TargetCPUUtilizationPercentage == 0 {
+		a.TargetCPUUtilizationPercentage = 80
+	}
+	return a
+}
+
+func utilizationMetric(resource string, percent int) map[string]any {
+	return map[string]any{
+		"type": "Resource",
+		"resource": map[string]any{
+			"name":   resource,
+			"target": map[string]any{"type": "Utilization", "averageUtilization": percent},
+		},
+	}
+}
+
+func replicaCount(d config.DeploymentSettings) int {
+	if d.Replicas > 0 {
+		return d.Replicas
+	}
+	return 1
+}
+
+// resourceRequirements fills unset values with defaults sized for the
+// runtime. No CPU limit is set by default to avoid throttling streams.
+func resourceRequirements(r config.ResourceRequirements) map[string]any {
+	requests := map[string]any{
+		"cpu":    orDefault(r.Requests.CPU, "100m"),
+		"memory": orDefault(r.Requests.Memory, "128Mi"),
+	}
+	limits := map[string]any{"memory": orDefault(r.Limits.Memory, "512Mi")}
+	if r.Limits.CPU != "" {
+		limits["cpu"] = r.Limits.CPU
+	}
+	return map[string]any{"requests": requests, "limits": limits}
+}
+
+func orDefault(v, def string) string {
+	if strings.TrimSpace(v) == "" {
+		return def
+	}
+	return v
+}
+
+func adminPort(cfg *config.Config) (int, bool) {
+	if strings.TrimSpace(cfg.Gateway.AdminAddr) == "" {
+		return 0, false
+	}
+	port := parsePort(cfg.Gateway.AdminAddr)
+	return port, port != parsePort(cfg.Gateway.ListenAddr)
+}
+
+// configChecksum hashes the config with its secret values filled in, so
+// rotating one also rolls the pods.
+func configChecksum(cfg *config.Config) string {
+	b, _ := yaml.Marshal(cfg)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
 func serviceDoc(cfg *config.Config, namespace string) map[string]any {
 	port := parsePort(cfg.Gateway.ListenAddr)
 	return map[string]any{
@@ -148,44 +407,141 @@ func serviceDoc(cfg *config.Config, namespace string) map[string]any {
 	}
 }
 
-func routeDoc(route config.Route, namespace string) map[string]any {
-	return map[string]any{
-		"apiVersion": "mcp.envoy.io/v1alpha1",
-		"kind":       "MCPRoute",
-		"metadata": map[string]any{
-			"name":      route.Name,
-			"namespace": namespace,
-		},
-		"spec": map[string]any{
-			"path":       route.Path,
-			"backendRef": route.Server,
-			"policy": map[string]any{
-				"timeoutMs":    route.Policy.TimeoutMs,
-				"retryCount":   route.Policy.RetryCount,
-				"rateLimitRps": route.Policy.RateLimitRPS,
-			},
-		},
+// apiKeysFile is an apiKeysFile the config names, mounted from a key of an
+// existing Secret.
+type apiKeysFile struct {
+	path, secretName, key string
+}
+
+// apiKeysFiles lists the distinct apiKeysFile paths of cfg's routes in route
+// order. The Secrets are not rendered: the config only points at the keys.
+func apiKeysFiles(cfg *config.Config) []apiKeysFile {
+	var files []apiKeysFile
+	seen := map[string]bool{}
+	for _, r := range cfg.Routes {
+		file := cfg.EffectiveAuth(r).APIKeysFile
+		if strings.TrimSpace(file) == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		files = append(files, apiKeysFile{
+			path:       file,
+			secretName: fmt.Sprintf("%s-api-keys-%d", cfg.Gateway.Name, len(files)),
+			key:        path.Base(file),
+		})
+	}
+	return files
+}
+
+// addAPIKeysFileVolumes mounts each apiKeysFile into the gateway container,
+// which reads them at startup.
+func addAPIKeysFileVolumes(deployment map[string]any, cfg *config.Config) {
+	pod := deployment["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+	gateway := pod["containers"].([]map[string]any)[0]
+	for i, f := range apiKeysFiles(cfg) {
+		volume := fmt.Sprintf("api-keys-file-%d", i)
+		pod["volumes"] = append(pod["volumes"].([]map[string]any), map[string]any{"name": volume, "secret": map[string]any{"secretName": f.secretName}})
+		gateway["volumeMounts"] = append(gateway["volumeMounts"].([]map[string]any),
+			map[string]any{"name": volume, "mountPath": f.path, "subPath": f.key, "readOnly": true})
 	}
 }
 
-func authPolicyDoc(route config.Route, namespace, authType string) map[string]any {
+// checkPodRecordings rejects recordings the rendered gateway pod cannot
+// write: its root filesystem is read-only and only /tmp is writable.
+func checkPodRecordings(cfg *config.Config) error {
+	for _, r := range cfg.Routes {
+		if r.Record == nil {
+			continue
+		}
+		if file := path.Clean(r.Record.File); !strings.HasPrefix(file, "/tmp/") {
+			return fmt.Errorf("route %q: record.file %s is not writable in the gateway pod, whose root filesystem is read-only; record under /tmp", r.Name, r.Record.File)
+		}
+	}
+	return nil
+}
+
+// configToYAML renders cfg for the gateway's ConfigMap. Values the config
+// took from files or secret-looking variables become references to the
+// variables the gateway container reads from the config Secret, and every
+// other ${ is escaped, since the gateway interpolates the file again.
+func configToYAML(cfg *config.Config) string {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return ""
+	}
+	refs := map[string]string{}
+	for i, v := range cfg.SecretValues() {
+		refs[v] = "${" + configSecretVariable(i) + "}"
+	}
+	referenceSecrets(&doc, refs)
+	b, _ := yaml.Marshal(&doc)
+	return string(b)
+}
+
+// referenceSecrets rewrites the string values under n; mapping keys are left
+// alone.
+func referenceSecrets(n *yaml.Node, refs map[string]string) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			referenceSecrets(n.Content[i], refs)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			referenceSecrets(c, refs)
+		}
+	case yaml.ScalarNode:
+		if n.Tag != "!!str" {
+			return
+		}
+		if ref, ok := refs[n.Value]; ok {
+			n.Value, n.Style = ref, 0
+			return
+		}
+		n.Value = strings.ReplaceAll(n.Value, "${", "$${")
+	}
+}
+
+// configSecretVariable names the variable holding the i'th of the config's
+// secret values.
+func configSecretVariable(i int) string {
+	return fmt.Sprintf("GATEWAY_CONFIG_SECRET_%d", i)
+}
+
+// configSecretsDoc holds the values configToYAML leaves out of the
+// ConfigMap, keyed by variable. It is nil when the config has none.
+func configSecretsDoc(cfg *config.Config, namespace string) map[string]any {
+	values := cfg.SecretValues()
+	if len(values) == 0 {
+		return nil
+	}
+	data := map[string]any{}
+	for i, v := range values {
+		data[configSecretVariable(i)] = v
+	}
 	return map[string]any{
-		"apiVersion": "mcp.envoy.io/v1alpha1",
-		"kind":       "MCPAuthPolicy",
+		"apiVersion": "v1",
+		"kind":       "Secret",
 		"metadata": map[string]any{
-			"name":      route.Name + "-auth",
+			"name":      cfg.Gateway.Name + "-config-secrets",
 			"namespace": namespace,
 		},
-		"spec": map[string]any{
-			"targetRoute": route.Name,
-			"type":        authType,
-		},
+		"type":       "Opaque",
+		"stringData": data,
 	}
 }
 
-func configToYAML(cfg *config.Config) string {
-	b, _ := yaml.Marshal(cfg)
-	return string(b)
+// addConfigSecretsEnv passes the config Secret to the gateway container as
+// environment variables.
+func addConfigSecretsEnv(deployment map[string]any, cfg *config.Config) {
+	if len(cfg.SecretValues()) == 0 {
+		return
+	}
+	pod := deployment["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+	gateway := pod["containers"].([]map[string]any)[0]
+	gateway["envFrom"] = []map[string]any{
+		{"secretRef": map[string]any{"name": cfg.Gateway.Name + "-config-secrets"}},
+	}
 }
 
 func parsePort(listenAddr string) int {
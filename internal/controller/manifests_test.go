@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"gopkg.in/yaml.v3"
 )
 
 func TestRenderManifests(t *testing.T) {
@@ -29,9 +33,377 @@ func TestRenderManifests(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	text := string(manifest)
-	for _, needle := range []string{"kind: Namespace", "kind: Deployment", "kind: MCPRoute", "kind: MCPAuthPolicy"} {
+	for _, needle := range []string{"kind: Namespace", "kind: Deployment", "kind: Gateway\n", "kind: HTTPRoute", "kind: ClientTrafficPolicy", "kind: BackendTrafficPolicy"} {
 		if !strings.Contains(text, needle) {
 			t.Fatalf("manifest missing %q", needle)
 		}
 	}
+	if strings.Contains(text, "MCPRoute") || strings.Contains(text, "MCPAuthPolicy") {
+		t.Fatal("manifest still contains placeholder MCP kinds")
+	}
+}
+
+func TestRenderEnvoyGatewayResources(t *testing.T) {
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "mcp-gateway", ListenAddr: ":8080"},
+		Auth:    config.AuthDefaults{RequireAuth: true},
+		AuthProviders: []config.AuthProvider{{Name: "corp-sso", RouteAuth: config.RouteAuth{
+			Type: "jwt", Issuer: "https://issuer.example.com", Audience: "mcp",
+		}}},
+		Policies: []config.Policy{{Name: "standard", RoutePolicy: config.RoutePolicy{TimeoutMs: 10000, RetryCount: 1, RateLimitRPS: 20}}},
+		Servers: []config.Server{
+			{Name: "weather-http", Transport: "http", URL: "https://weather.example.com/api"},
+			{Name: "fs", Transport: "stdio", Command: "npx"},
+		},
+		Routes: []config.Route{
+			{
+				Name:    "weather",
+				Path:    "/mcp/weather",
+				Server:  "weather-http",
+				Auth:    &config.RouteAuth{Use: "corp-sso"},
+				Policy:  config.RoutePolicy{Use: "standard"},
+				Hosts:   []string{"weather.mcp.example.com"},
+				Rewrite: &config.Rewrite{ReplacePrefix: "/mcp"},
+			},
+			{
+				Name:   "fs",
+				Path:   "/mcp/fs",
+				Server: "fs",
+				Auth:   &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"k1"}},
+			},
+		},
+	}
+
+	docs := envoyGatewayDocs(cfg, "mcp")
+	byName := map[string]map[string]any{}
+	for _, d := range docs {
+		meta := d["metadata"].(map[string]any)
+		byName[d["kind"].(string)+"/"+meta["name"].(string)] = d
+	}
+
+	backend, ok := byName["Backend/weather-http"]
+	if !ok {
+		t.Fatal("expected Backend for http server")
+	}
+	endpoint := backend["spec"].(map[string]any)["endpoints"].([]map[string]any)[0]["fqdn"].(map[string]any)
+	if endpoint["hostname"] != "weather.example.com" || endpoint["port"] != 443 {
+		t.Fatalf("unexpected backend endpoint: %v", endpoint)
+	}
+	if _, ok := byName["Backend/fs"]; ok {
+		t.Fatal("stdio server must not be rendered as a Backend")
+	}
+
+	weatherRule := byName["HTTPRoute/weather"]["spec"].(map[string]any)["rules"].([]map[string]any)[0]
+	if weatherRule["backendRefs"].([]map[string]any)[0]["kind"] != "Backend" {
+		t.Fatalf("weather route should target the Backend: %v", weatherRule)
+	}
+	rewrite := weatherRule["filters"].([]map[string]any)[0]["urlRewrite"].(map[string]any)["path"].(map[string]any)
+	if rewrite["replacePrefixMatch"] != "/api/mcp" {
+		t.Fatalf("unexpected rewrite: %v", rewrite)
+	}
+	if hosts := byName["HTTPRoute/weather"]["spec"].(map[string]any)["hostnames"]; !reflect.DeepEqual(hosts, []string{"weather.mcp.example.com"}) {
+		t.Fatalf("unexpected hostnames: %v", hosts)
+	}
+	fsRef := byName["HTTPRoute/fs"]["spec"].(map[string]any)["rules"].([]map[string]any)[0]["backendRefs"].([]map[string]any)[0]
+	if fsRef["name"] != "mcp-gateway" || fsRef["port"] != 8080 {
+		t.Fatalf("stdio route should target the runtime Service: %v", fsRef)
+	}
+
+	jwt := byName["SecurityPolicy/weather-auth"]["spec"].(map[string]any)["jwt"].(map[string]any)["providers"].([]map[string]any)[0]
+	if jwt["remoteJWKS"].(map[string]any)["uri"] != "https://issuer.example.com/.well-known/jwks.json" {
+		t.Fatalf("unexpected jwt provider: %v", jwt)
+	}
+	if _, ok := byName["Secret/fs-api-keys"]; !ok {
+		t.Fatal("expected API key Secret")
+	}
+	if _, ok := byName["SecurityPolicy/fs-auth"]; !ok {
+		t.Fatal("expected apiKey SecurityPolicy")
+	}
+	btp := byName["BackendTrafficPolicy/weather"]["spec"].(map[string]any)
+	if btp["timeout"] == nil || btp["retry"] == nil || btp["rateLimit"] == nil {
+		t.Fatalf("expected timeout, retry and rateLimit: %v", btp)
+	}
+	if _, ok := byName["BackendTrafficPolicy/fs"]; ok {
+		t.Fatal("route without policy values should not get a BackendTrafficPolicy")
+	}
+}
+
+func TestRenderDeploymentHardening(t *testing.T) {
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":8080", AdminAddr: ":9090"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: "http://example"}},
+		Routes:  []config.Route{{Name: "r1", Path: "/mcp", Server: "s1"}},
+		Deployment: config.DeploymentSettings{
+			Replicas:    3,
+			Resources:   config.ResourceRequirements{Limits: config.ResourceList{CPU: "2"}},
+			Autoscaling: &config.Autoscaling{MinReplicas: 2, MaxReplicas: 6},
+		},
+	}
+	docs, err := RenderObjects(cfg, "mcp", "example/image:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKind := map[string]map[string]any{}
+	for _, d := range docs {
+		byKind[d["kind"].(string)] = d
+	}
+
+	spec := byKind["Deployment"]["spec"].(map[string]any)
+	if _, ok := spec["replicas"]; ok {
+		t.Fatal("replicas must be left to the autoscaler")
+	}
+	pod := spec["template"].(map[string]any)
+	checksum := pod["metadata"].(map[string]any)["annotations"].(map[string]any)["checksum/config"]
+	container := pod["spec"].(map[string]any)["containers"].([]map[string]any)[0]
+	readiness := container["readinessProbe"].(map[string]any)["httpGet"].(map[string]any)
+	if readiness["path"] != "/readyz" || readiness["port"] != "admin" {
+		t.Fatalf("expected readiness on admin /readyz, got %v", readiness)
+	}
+	if container["securityContext"].(map[string]any)["readOnlyRootFilesystem"] != true {
+		t.Fatal("expected read-only root filesystem")
+	}
+	resources := container["resources"].(map[string]any)
+	if resources["limits"].(map[string]any)["cpu"] != "2" || resources["requests"].(map[string]any)["memory"] != "128Mi" {
+		t.Fatalf("unexpected resources: %v", resources)
+	}
+	if hpa := byKind["HorizontalPodAutoscaler"]; hpa == nil || hpa["spec"].(map[string]any)["maxReplicas"] != 6 {
+		t.Fatalf("expected an HPA scaling to 6, got %v", hpa)
+	}
+	if pdb := byKind["PodDisruptionBudget"]; pdb == nil || pdb["spec"].(map[string]any)["maxUnavailable"] != 1 {
+		t.Fatalf("expected a default PDB, got %v", pdb)
+	}
+
+	cfg.Routes[0].Policy.TimeoutMs = 500
+	docs, _ = RenderObjects(cfg, "mcp", "example/image:1")
+	for _, d := range docs {
+		if d["kind"] == "Deployment" {
+			tmpl := d["spec"].(map[string]any)["template"].(map[string]any)
+			if tmpl["metadata"].(map[string]any)["annotations"].(map[string]any)["checksum/config"] == checksum {
+				t.Fatal("config checksum did not change with the config")
+			}
+		}
+	}
+}
+
+func TestRenderStdioWorkloads(t *testing.T) {
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":8080"},
+		Servers: []config.Server{
+			{Name: "fs", Transport: "stdio", Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-filesystem", "/tmp"}, Image: "node:22-slim"},
+			{Name: "git", Transport: "stdio", Command: "uvx", Args: []string{"mcp-server-git"}, Image: "ghcr.io/astral-sh/uv:python3.12-bookworm-slim", Placement: "standalone",
+				Env: []config.EnvVar{
+					{Name: "GIT_AUTHOR_NAME", Value: "gateway"},
+					{Name: "GITHUB_TOKEN", SecretRef: &config.SecretRef{Name: "github", Key: "token"}},
+				},
+				Credentials: []config.Credential{{FromClaim: "sub", ToEnv: "GIT_AUTHOR_EMAIL"}}},
+			{Name: "local", Transport: "stdio", Command: "mcp-local",
+				Env: []config.EnvVar{{Name: "API_TOKEN", SecretRef: &config.SecretRef{Name: "local-api", Key: "token"}}}},
+		},
+		Routes: []config.Route{
+			{Name: "fs", Path: "/mcp/fs", Server: "fs"},
+			{Name: "git", Path: "/mcp/git", Server: "git"},
+			{Name: "local", Path: "/mcp/local", Server: "local"},
+		},
+	}
+	docs, err := RenderObjects(cfg, "mcp", "example/image:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := map[string]map[string]any{}
+	for _, d := range docs {
+		meta := d["metadata"].(map[string]any)
+		objects[d["kind"].(string)+"/"+meta["name"].(string)] = d
+	}
+
+	var runtimeCfg config.Config
+	if err := yaml.Unmarshal([]byte(objects["ConfigMap/gw-config"]["data"].(map[string]any)["gateway.yaml"].(string)), &runtimeCfg); err != nil {
+		t.Fatal(err)
+	}
+	if s := runtimeCfg.Servers[0]; s.Transport != "http" || s.URL != "http://127.0.0.1:9100" {
+		t.Fatalf("expected sidecar server rewired to localhost adapter, got %+v", s)
+	}
+	if s := runtimeCfg.Servers[1]; s.Transport != "http" || s.URL != "http://gw-git.mcp.svc.cluster.local:8080" {
+		t.Fatalf("expected standalone server rewired to its Service, got %+v", s)
+	}
+
+	pod := objects["Deployment/gw"]["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+	containers := pod["containers"].([]map[string]any)
+	if len(containers) != 2 || containers[1]["image"] != "node:22-slim" || pod["initContainers"] == nil {
+		t.Fatalf("expected gateway plus fs sidecar with adapter init container, got %v", containers)
+	}
+	fsCommand := strings.Join(containers[1]["command"].([]string), " ")
+	if !strings.Contains(fsCommand, "--listen 127.0.0.1:9100") || containers[1]["readinessProbe"].(map[string]any)["exec"] == nil {
+		t.Fatalf("expected the sidecar bound to localhost with an exec probe, got %v", containers[1])
+	}
+	mounts := containers[0]["volumeMounts"].([]map[string]any)
+	if m := mounts[len(mounts)-1]; m["mountPath"] != config.DefaultSecretsDir+"/local-api" {
+		t.Fatalf("expected the local server's Secret mounted for the runtime, got %v", mounts)
+	}
+	if objects["Deployment/gw-git"] == nil || objects["Service/gw-git"] == nil {
+		t.Fatal("expected standalone Deployment and Service for git")
+	}
+	git := objects["Deployment/gw-git"]["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]map[string]any)[0]
+	if env := git["env"].([]map[string]any)[1]; env["valueFrom"] == nil {
+		t.Fatalf("expected secretRef rendered as secretKeyRef, got %v", env)
+	}
+	if !strings.Contains(strings.Join(git["command"].([]string), " "), "--credential claim:sub=env:GIT_AUTHOR_EMAIL --") {
+		t.Fatalf("expected credentials passed to the adapter, got %v", git["command"])
+	}
+	policy, ok := objects["NetworkPolicy/gw-git"]
+	if !ok {
+		t.Fatal("expected a NetworkPolicy for the standalone adapter")
+	}
+	from := policy["spec"].(map[string]any)["ingress"].([]map[string]any)[0]["from"].([]map[string]any)
+	if len(from) != 2 || from[0]["podSelector"].(map[string]any)["matchLabels"].(map[string]any)["app"] != "gw" {
+		t.Fatalf("expected ingress only from the gateway and its Envoy proxies, got %v", from)
+	}
+
+	// Envoy cannot reach the pod-local sidecar but can reach the Service.
+	fsRef := objects["HTTPRoute/fs"]["spec"].(map[string]any)["rules"].([]map[string]any)[0]["backendRefs"].([]map[string]any)[0]
+	gitRef := objects["HTTPRoute/git"]["spec"].(map[string]any)["rules"].([]map[string]any)[0]["backendRefs"].([]map[string]any)[0]
+	if fsRef["name"] != "gw" || gitRef["kind"] != "Backend" || objects["Backend/git"] == nil {
+		t.Fatalf("unexpected route wiring: fs=%v git=%v", fsRef, gitRef)
+	}
+}
+
+func TestRenderMountsAPIKeysFiles(t *testing.T) {
+	cfg := reconcileTestConfig()
+	cfg.Routes[0].Auth = &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeysFile: "/etc/keys/w"}
+	docs, err := RenderObjects(cfg, "mcp", "example/image:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pod map[string]any
+	for _, d := range docs {
+		if d["kind"] == "Deployment" {
+			pod = d["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+		}
+	}
+	var volume map[string]any
+	for _, v := range pod["volumes"].([]map[string]any) {
+		if v["name"] == "api-keys-file-0" {
+			volume = v
+		}
+	}
+	if volume == nil || volume["secret"].(map[string]any)["secretName"] != "mcp-gateway-api-keys-0" {
+		t.Fatalf("expected a Secret volume for the apiKeysFile, got %v", pod["volumes"])
+	}
+	var mounted bool
+	for _, m := range pod["containers"].([]map[string]any)[0]["volumeMounts"].([]map[string]any) {
+		mounted = mounted || (m["name"] == "api-keys-file-0" && m["mountPath"] == "/etc/keys/w" && m["subPath"] == "w")
+	}
+	if !mounted {
+		t.Fatalf("expected the apiKeysFile mounted into the gateway, got %v", pod["containers"])
+	}
+}
+
+func TestRenderRejectsPrivilegedSandbox(t *testing.T) {
+	uid := 65534
+	cfg := reconcileTestConfig()
+	cfg.Servers = append(cfg.Servers, config.Server{Name: "local", Transport: "stdio", Command: "mcp-local",
+		Sandbox: &config.Sandbox{User: &uid}})
+	if _, err := RenderObjects(cfg, "mcp", "example/image:1"); err == nil || !strings.Contains(err.Error(), `server "local"`) {
+		t.Fatalf("expected the sandbox user rejected for the gateway pod, got %v", err)
+	}
+	if _, err := RenderHelmChart(cfg, "example/image:1"); err == nil {
+		t.Fatal("expected the chart to reject the sandbox user too")
+	}
+
+	cfg.Servers[1].Sandbox = &config.Sandbox{Limits: config.Rlimits{OpenFiles: 256}, Seccomp: "default"}
+	if _, err := RenderObjects(cfg, "mcp", "example/image:1"); err != nil {
+		t.Fatalf("expected unprivileged sandbox settings to render, got %v", err)
+	}
+}
+
+func TestRenderRecordedRoutes(t *testing.T) {
+	cfg := reconcileTestConfig()
+	cfg.Routes[0].Record = &config.Record{File: "recordings/weather.jsonl"}
+	if _, err := RenderObjects(cfg, "mcp", "example/image:1"); err == nil || !strings.Contains(err.Error(), "/tmp") {
+		t.Fatalf("expected a recording outside /tmp rejected, got %v", err)
+	}
+	if _, err := RenderHelmChart(cfg, "example/image:1"); err == nil {
+		t.Fatal("expected the chart to reject it too")
+	}
+
+	// Envoy forwarding straight to the upstream would bypass the recorder.
+	cfg.Routes[0].Record.File = "/tmp/recordings/weather.jsonl"
+	objects := map[string]map[string]any{}
+	rendered, err := RenderObjects(cfg, "mcp", "example/image:1")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for _, o := range rendered {
+		objects[o["kind"].(string)+"/"+o["metadata"].(map[string]any)["name"].(string)] = o
+	}
+	ref := objects["HTTPRoute/weather"]["spec"].(map[string]any)["rules"].([]map[string]any)[0]["backendRefs"].([]map[string]any)[0]
+	if ref["name"] != "mcp-gateway" || objects["Backend/weather"] != nil {
+		t.Fatalf("expected the recorded route sent through the gateway, got %v", ref)
+	}
+}
+
+func TestRenderKeepsConfigSecretsOutOfConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "key.txt"), []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WEATHER_TOKEN", "s3cret")
+	cfg, issues := config.CheckYAML([]byte(`
+apiVersion: mcp.envoy.io/v1alpha1
+kind: GatewayConfig
+gateway: {name: mcp-gateway, listenAddr: ":8080"}
+servers:
+  - {name: weather, transport: http, url: "http://weather:8000/mcp?token=${WEATHER_TOKEN}"}
+routes:
+  - name: weather
+    path: /mcp/weather
+    server: weather
+    auth: {type: apiKey, headerName: X-API-Key, apiKeys: ["${file:key.txt}"]}
+`), dir)
+	if errs := issues.Errors(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	docs, err := RenderObjects(cfg, "mcp", "example/image:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := map[string]map[string]any{}
+	for _, d := range docs {
+		objects[d["kind"].(string)+"/"+d["metadata"].(map[string]any)["name"].(string)] = d
+	}
+	data := objects["ConfigMap/mcp-gateway-config"]["data"].(map[string]any)["gateway.yaml"].(string)
+	if strings.Contains(data, "s3cret") || strings.Contains(data, "file-key") {
+		t.Fatalf("expected the secret values left out of the ConfigMap, got:\n%s", data)
+	}
+	secret := objects["Secret/mcp-gateway-config-secrets"]
+	if secret == nil {
+		t.Fatal("expected a Secret holding the config's secret values")
+	}
+	gateway := objects["Deployment/mcp-gateway"]["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]map[string]any)[0]
+	if !reflect.DeepEqual(gateway["envFrom"], []map[string]any{{"secretRef": map[string]any{"name": "mcp-gateway-config-secrets"}}}) {
+		t.Fatalf("expected the gateway to read the Secret, got %v", gateway["envFrom"])
+	}
+
+	// The gateway resolves the references back to the original values.
+	for k, v := range secret["stringData"].(map[string]any) {
+		t.Setenv(k, v.(string))
+	}
+	runtime, err := config.Load([]byte(data))
+	if err != nil {
+		t.Fatalf("load rendered config: %v", err)
+	}
+	if runtime.Servers[0].URL != "http://weather:8000/mcp?token=s3cret" || !reflect.DeepEqual(runtime.Routes[0].Auth.APIKeys, []string{"file-key"}) {
+		t.Fatalf("expected the references resolved, got %s and %v", runtime.Servers[0].URL, runtime.Routes[0].Auth.APIKeys)
+	}
+
+	files, err := RenderHelmChart(cfg, "example/image:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(files["files/gateway.yaml"]), "s3cret") {
+		t.Fatalf("expected the secret values left out of the chart's config, got:\n%s", files["files/gateway.yaml"])
+	}
+	if !strings.Contains(string(files["templates/deployment.yaml"]), "-config-secrets") {
+		t.Fatal("expected the chart's gateway to read the config Secret")
+	}
 }
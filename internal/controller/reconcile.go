@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube"
+)
+
+// DefaultFieldManager is the server-side apply field manager used when the
+// Reconciler does not set one.
+const DefaultFieldManager = "mcp-gateway-envoy"
+
+// KubeClient is the subset of the Kubernetes API the reconciler needs;
+// *kube.Client implements it.
+type KubeClient interface {
+	Apply(ctx context.Context, obj kube.Object, fieldManager string) (kube.Object, error)
+	Get(ctx context.Context, apiVersion, kind, namespace, name string) (kube.Object, error)
+	List(ctx context.Context, apiVersion, kind, namespace, labelSelector string) ([]kube.Object, error)
+	Delete(ctx context.Context, apiVersion, kind, namespace, name string) error
+}
+
+// Reconciler converges a cluster onto the manifests rendered from config.
+type Reconciler struct {
+	Client       KubeClient
+	FieldManager string
+	Namespace    string
+	Image        string
+	// Prune deletes objects carrying this gateway's ownership labels that are
+	// no longer rendered.
+	Prune bool
+}
+
+// Drift describes a live object that no longer matches what was rendered.
+type Drift struct {
+	Object string   `json:"object"`
+	Reason string   `json:"reason"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ReconcileResult summarizes one reconcile pass. Objects are identified as
+// Kind/namespace/name (Kind/name for cluster-scoped kinds).
+type ReconcileResult struct {
+	Applied []string `json:"applied"`
+	Pruned  []string `json:"pruned,omitempty"`
+	Drifted []Drift  `json:"drifted,omitempty"`
+}
+
+// Reconcile renders cfg, records drift between the rendered and live objects,
+// server-side applies every rendered object and, when enabled, prunes owned
+// objects that are no longer rendered.
+func (r *Reconciler) Reconcile(ctx context.Context, cfg *config.Config) (ReconcileResult, error) {
+	var result ReconcileResult
+	docs, err := RenderObjects(cfg, r.Namespace, r.Image)
+	if err != nil {
+		return result, err
+	}
+	manager := r.FieldManager
+	if strings.TrimSpace(manager) == "" {
+		manager = DefaultFieldManager
+	}
+
+	desired := map[string]bool{}
+	for _, doc := range docs {
+		obj := normalize(doc)
+		apiVersion, kind, ns, name := objectCoordinates(obj)
+		id := objectID(kind, ns, name)
+		desired[id] = true
+
+		live, err := r.Client.Get(ctx, apiVersion, kind, ns, name)
+		switch {
+		case kube.IsNotFound(err):
+			result.Drifted = append(result.Drifted, Drift{Object: id, Reason: "missing"})
+		case err != nil:
+			return result, fmt.Errorf("get %s: %w", id, err)
+		default:
+			// Fields only present live (status, defaults, other managers) are
+			// not drift.
+			if changes := diffValues(secretStringData(live), obj, "", true); len(changes) > 0 {
+				result.Drifted = append(result.Drifted, Drift{Object: id, Reason: "modified", Fields: fieldPaths(changes)})
+			}
+		}
+
+		if _, err := r.Client.Apply(ctx, obj, manager); err != nil {
+			return result, fmt.Errorf("apply %s: %w", id, err)
+		}
+		result.Applied = append(result.Applied, id)
+	}
+
+	if !r.Prune {
+		return result, nil
+	}
+	selector := labelSelector(OwnerLabels(cfg))
+	for _, info := range kube.Resources {
+		// Namespaces may hold objects the gateway does not own; never prune them.
+		if !info.Namespaced {
+			continue
+		}
+		items, err := r.Client.List(ctx, info.APIVersion, info.Kind, r.Namespace, selector)
+		if kube.IsNotFound(err) {
+			// The CRD is not installed, so there is nothing to prune.
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("list %s: %w", info.Kind, err)
+		}
+		for _, item := range items {
+			_, _, ns, name := objectCoordinates(item)
+			id := objectID(info.Kind, ns, name)
+			if desired[id] {
+				continue
+			}
+			if err := r.Client.Delete(ctx, info.APIVersion, info.Kind, ns, name); err != nil && !kube.IsNotFound(err) {
+				return result, fmt.Errorf("prune %s: %w", id, err)
+			}
+			result.Pruned = append(result.Pruned, id)
+		}
+	}
+	return result, nil
+}
+
+// Run reconciles immediately and then every interval until ctx is done. load
+// is called each pass so config edits are picked up without a restart.
+func (r *Reconciler) Run(ctx context.Context, load func() (*config.Config, error), interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.runOnce(ctx, load)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reconciler) runOnce(ctx context.Context, load func() (*config.Config, error)) {
+	cfg, err := load()
+	if err != nil {
+		log.Printf("reconcile config_error=%q", err)
+		return
+	}
+	result, err := r.Reconcile(ctx, cfg)
+	if err != nil {
+		log.Printf("reconcile error=%q applied=%d", err, len(result.Applied))
+		return
+	}
+	for _, d := range result.Drifted {
+		log.Printf("reconcile drift object=%s reason=%s fields=%s", d.Object, d.Reason, strings.Join(d.Fields, ","))
+	}
+	for _, id := range result.Pruned {
+		log.Printf("reconcile pruned object=%s", id)
+	}
+	log.Printf("reconcile applied=%d drifted=%d pruned=%d", len(result.Applied), len(result.Drifted), len(result.Pruned))
+}
+
+func fieldPaths(changes []FieldChange) []string {
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// secretStringData exposes a live Secret's base64 data as stringData so it
+// compares against the rendered form.
+func secretStringData(live kube.Object) kube.Object {
+	if live["kind"] != "Secret" {
+		return live
+	}
+	data, ok := live["data"].(map[string]any)
+	if !ok {
+		return live
+	}
+	decoded := map[string]any{}
+	for k, v := range data {
+		s, _ := v.(string)
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return live
+		}
+		decoded[k] = string(b)
+	}
+	out := kube.Object{}
+	for k, v := range live {
+		out[k] = v
+	}
+	out["stringData"] = decoded
+	return out
+}
+
+// normalize converts a rendered document into the JSON value shapes the API
+// server returns (float64 numbers, []any slices).
+func normalize(doc map[string]any) kube.Object {
+	b, _ := json.Marshal(doc)
+	var out kube.Object
+	_ = json.Unmarshal(b, &out)
+	return out
+}
+
+func objectCoordinates(obj kube.Object) (apiVersion, kind, namespace, name string) {
+	apiVersion, _ = obj["apiVersion"].(string)
+	kind, _ = obj["kind"].(string)
+	meta, _ := obj["metadata"].(map[string]any)
+	namespace, _ = meta["namespace"].(string)
+	name, _ = meta["name"].(string)
+	return apiVersion, kind, namespace, name
+}
+
+func objectID(kind, namespace, name string) string {
+	if namespace == "" {
+		return kind + "/" + name
+	}
+	return kind + "/" + namespace + "/" + name
+}
+
+func labelSelector(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
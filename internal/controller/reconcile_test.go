@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube"
+	"github.com/djsam/mcp-gateway-envoy/internal/kube/kubetest"
+)
+
+func reconcileTestConfig() *config.Config {
+	return &config.Config{
+		Gateway: config.Gateway{Name: "mcp-gateway", ListenAddr: ":8080"},
+		Servers: []config.Server{{Name: "weather", Transport: "http", URL: "http://weather:8000"}},
+		Routes: []config.Route{
+			{Name: "weather", Path: "/mcp/weather", Server: "weather", Policy: config.RoutePolicy{TimeoutMs: 5000}},
+		},
+	}
+}
+
+func TestReconcileAppliesAndDetectsDrift(t *testing.T) {
+	api := kubetest.NewServer()
+	defer api.Close()
+	client, err := kube.NewClient(api.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: client, Namespace: "mcp", Image: "example/gateway:1"}
+	ctx := context.Background()
+
+	first, err := r.Reconcile(ctx, reconcileTestConfig())
+	if err != nil {
+		t.Fatalf("first reconcile: %v", err)
+	}
+	if len(first.Applied) == 0 || len(first.Drifted) != len(first.Applied) {
+		t.Fatalf("expected every object applied and reported missing, got %+v", first)
+	}
+
+	second, err := r.Reconcile(ctx, reconcileTestConfig())
+	if err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+	if len(second.Drifted) != 0 {
+		t.Fatalf("expected no drift after apply, got %+v", second.Drifted)
+	}
+
+	// Someone scales the Deployment by hand.
+	deploy := api.Object("apps/v1", "Deployment", "mcp", "mcp-gateway")
+	deploy["spec"].(map[string]any)["replicas"] = 5
+	api.Put(deploy)
+
+	third, err := r.Reconcile(ctx, reconcileTestConfig())
+	if err != nil {
+		t.Fatalf("third reconcile: %v", err)
+	}
+	if len(third.Drifted) != 1 || third.Drifted[0].Object != "Deployment/mcp/mcp-gateway" ||
+		len(third.Drifted[0].Fields) != 1 || third.Drifted[0].Fields[0] != "spec.replicas" {
+		t.Fatalf("expected spec.replicas drift on the deployment, got %+v", third.Drifted)
+	}
+	if got := api.Object("apps/v1", "Deployment", "mcp", "mcp-gateway")["spec"].(map[string]any)["replicas"]; got != float64(1) {
+		t.Fatalf("expected drift corrected to 1 replica, got %v", got)
+	}
+}
+
+func TestReconcilePrunesOwnedObjects(t *testing.T) {
+	api := kubetest.NewServer()
+	defer api.Close()
+	client, err := kube.NewClient(api.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{Client: client, Namespace: "mcp", Prune: true}
+	cfg := reconcileTestConfig()
+
+	labels := map[string]any{}
+	for k, v := range OwnerLabels(cfg) {
+		labels[k] = v
+	}
+	api.Put(kube.Object{
+		"apiVersion": gatewayAPIVersion,
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "retired", "namespace": "mcp", "labels": labels},
+	})
+	api.Put(kube.Object{
+		"apiVersion": gatewayAPIVersion,
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": "someone-else", "namespace": "mcp"},
+	})
+
+	result, err := r.Reconcile(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(result.Pruned) != 1 || result.Pruned[0] != "HTTPRoute/mcp/retired" {
+		t.Fatalf("expected only the retired route pruned, got %v", result.Pruned)
+	}
+	if api.Object(gatewayAPIVersion, "HTTPRoute", "mcp", "someone-else") == nil {
+		t.Fatal("unowned route was pruned")
+	}
+	if api.Object(gatewayAPIVersion, "HTTPRoute", "mcp", "weather") == nil {
+		t.Fatal("rendered route was pruned")
+	}
+}
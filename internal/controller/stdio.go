@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+const (
+	// adapterBinDir is the shared volume the init container copies the
+	// gateway binary into, so any server image can run "gateway adapt".
+	adapterBinDir = "/opt/mcp-gateway"
+	// sidecarBasePort is the first localhost port given to sidecar adapters.
+	sidecarBasePort = 9100
+	// standalonePort is the adapter port of standalone server Deployments.
+	standalonePort = 8080
+)
+
+// stdioWorkload is a stdio server that runs in its own container.
+type stdioWorkload struct {
+	server     config.Server
+	standalone bool
+	port       int
+}
+
+// stdioWorkloads lists stdio servers with an image, assigning sidecar ports
+// that do not collide with the gateway's own listeners.
+func stdioWorkloads(cfg *config.Config) []stdioWorkload {
+	taken := map[int]bool{parsePort(cfg.Gateway.ListenAddr): true}
+	if port, ok := adminPort(cfg); ok {
+		taken[port] = true
+	}
+	next := sidecarBasePort
+	var out []stdioWorkload
+	for _, s := range cfg.Servers {
+		if s.Transport != "stdio" || s.Image == "" {
+			continue
+		}
+		w := stdioWorkload{server: s, standalone: s.Placement == "standalone", port: standalonePort}
+		if !w.standalone {
+			for taken[next] {
+				next++
+			}
+			w.port = next
+			taken[next] = true
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// kubernetesConfig returns cfg as the in-cluster runtime should see it: stdio
+// servers with an image become HTTP servers pointing at their adapter, so
+// routes to them need no changes. An empty namespace gives standalone servers
+// a namespace-relative Service URL, for the Helm chart.
+func kubernetesConfig(cfg *config.Config, namespace string) *config.Config {
+	workloads := stdioWorkloads(cfg)
+	if len(workloads) == 0 {
+		return cfg
+	}
+	out := *cfg
+	out.Servers = append([]config.Server(nil), cfg.Servers...)
+	for _, w := range workloads {
+		for i := range out.Servers {
+			if out.Servers[i].Name != w.server.Name {
+				continue
+			}
+			out.Servers[i] = config.Server{Name: w.server.Name, Transport: "http", URL: w.url(cfg, namespace)}
+		}
+	}
+	return &out
+}
+
+func (w stdioWorkload) url(cfg *config.Config, namespace string) string {
+	switch {
+	case w.standalone && namespace == "":
+		return fmt.Sprintf("http://%s:%d", w.objectName(cfg), w.port)
+	case w.standalone:
+		return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", w.objectName(cfg), namespace, w.port)
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", w.port)
+}
+
+func (w stdioWorkload) objectName(cfg *config.Config) string {
+	return cfg.Gateway.Name + "-" + w.server.Name
+}
+
+// listenAddr is the adapter's listen address. Sidecars bind to localhost so
+// only the gateway in their pod can reach them.
+func (w stdioWorkload) listenAddr() string {
+	if w.standalone {
+		return fmt.Sprintf(":%d", w.port)
+	}
+	return fmt.Sprintf("127.0.0.1:%d", w.port)
+}
+
+// adapterContainer runs the stdio server under "gateway adapt" in its image.
+func (w stdioWorkload) adapterContainer() map[string]any {
+	command := []string{adapterBinDir + "/gateway", "adapt", "--listen", w.listenAddr(), "--name", w.server.Name}
+	for _, c := range w.server.Credentials {
+		command = append(command, "--credential", c.String())
+	}
+	command = append(command, "--", w.server.Command)
+	command = append(command, w.server.Args...)
+
+	env := []map[string]any{}
+	hasHome := false
+	for _, e := range w.server.Env {
+		if e.SecretRef != nil {
+			env = append(env, map[string]any{"name": e.Name, "valueFrom": map[string]any{
+				"secretKeyRef": map[string]any{"name": e.SecretRef.Name, "key": e.SecretRef.Key},
+			}})
+		} else {
+			env = append(env, map[string]any{"name": e.Name, "value": e.Value})
+		}
+		hasHome = hasHome || e.Name == "HOME"
+	}
+	if !hasHome {
+		// npm, pip and friends want a writable home directory.
+		env = append(env, map[string]any{"name": "HOME", "value": "/tmp"})
+	}
+
+	// The kubelet cannot reach a localhost adapter, so sidecars are probed
+	// from inside the container.
+	probe := map[string]any{
+		"exec": map[string]any{"command": []string{
+			adapterBinDir + "/gateway", "adapt", "--probe", fmt.Sprintf("http://127.0.0.1:%d/readyz", w.port),
+		}},
+		"periodSeconds": 5,
+	}
+	if w.standalone {
+		probe = map[string]any{
+			"httpGet":       map[string]any{"path": "/readyz", "port": w.port},
+			"periodSeconds": 5,
+		}
+	}
+
+	container := map[string]any{
+		"name":           "mcp-" + w.server.Name,
+		"image":          w.server.Image,
+		"command":        command,
+		"env":            env,
+		"resources":      resourceRequirements(w.server.Resources),
+		"readinessProbe": probe,
+		"securityContext": map[string]any{
+			"allowPrivilegeEscalation": false,
+			"capabilities":             map[string]any{"drop": []string{"ALL"}},
+		},
+		"volumeMounts": []map[string]any{
+			{"name": "adapter-bin", "mountPath": adapterBinDir, "readOnly": true},
+			{"name": "tmp", "mountPath": "/tmp"},
+		},
+	}
+	if w.standalone {
+		container["ports"] = []map[string]any{{"containerPort": w.port}}
+	}
+	if w.server.WorkingDir != "" {
+		container["workingDir"] = w.server.WorkingDir
+	}
+	return container
+}
+
+// installAdapterContainer copies the gateway binary into the shared volume.
+func installAdapterContainer(image string) map[string]any {
+	return map[string]any{
+		"name":         "install-adapter",
+		"image":        image,
+		"args":         []string{"adapt", "--install", adapterBinDir},
+		"volumeMounts": []map[string]any{{"name": "adapter-bin", "mountPath": adapterBinDir}},
+	}
+}
+
+// addStdioSidecars adds sidecar adapters, their init container and the
+// shared binary volume to the gateway Deployment.
+func addStdioSidecars(deployment map[string]any, cfg *config.Config, image string) {
+	var sidecars []map[string]any
+	for _, w := range stdioWorkloads(cfg) {
+		if !w.standalone {
+			sidecars = append(sidecars, w.adapterContainer())
+		}
+	}
+	if len(sidecars) == 0 {
+		return
+	}
+	pod := deployment["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+	pod["initContainers"] = []map[string]any{installAdapterContainer(image)}
+	pod["containers"] = append(pod["containers"].([]map[string]any), sidecars...)
+	pod["volumes"] = append(pod["volumes"].([]map[string]any), map[string]any{"name": "adapter-bin", "emptyDir": map[string]any{}})
+}
+
+// standaloneStdioDocs renders a Deployment, Service and NetworkPolicy per
+// standalone stdio server. Each runs one replica: adapter sessions live in
+// process memory. The adapter has no auth of its own, so the NetworkPolicy
+// admits only the gateway runtime and the Envoy proxies of its Gateway.
+func standaloneStdioDocs(cfg *config.Config, namespace, image string) []map[string]any {
+	var docs []map[string]any
+	for _, w := range stdioWorkloads(cfg) {
+		if !w.standalone {
+			continue
+		}
+		name := w.objectName(cfg)
+		labels := map[string]any{"app": name}
+		docs = append(docs,
+			map[string]any{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]any{
+					"name":      name,
+					"namespace": namespace,
+					"labels":    map[string]any{"app": name},
+				},
+				"spec": map[string]any{
+					"replicas": 1,
+					"selector": map[string]any{"matchLabels": labels},
+					"template": map[string]any{
+						"metadata": map[string]any{"labels": labels},
+						"spec": map[string]any{
+							"securityContext": map[string]any{
+								"runAsNonRoot":   true,
+								"runAsUser":      65532,
+								"runAsGroup":     65532,
+								"seccompProfile": map[string]any{"type": "RuntimeDefault"},
+							},
+							"initContainers": []map[string]any{installAdapterContainer(image)},
+							"containers":     []map[string]any{w.adapterContainer()},
+							"volumes": []map[string]any{
+								{"name": "adapter-bin", "emptyDir": map[string]any{}},
+								{"name": "tmp", "emptyDir": map[string]any{}},
+							},
+						},
+					},
+				},
+			},
+			map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata": map[string]any{
+					"name":      name,
+					"namespace": namespace,
+				},
+				"spec": map[string]any{
+					"selector": labels,
+					"ports":    []map[string]any{{"name": "http", "port": w.port, "targetPort": w.port}},
+				},
+			},
+			adapterNetworkPolicyDoc(cfg, w, namespace, labels),
+		)
+	}
+	return docs
+}
+
+// adapterNetworkPolicyDoc admits traffic to a standalone adapter only from
+// the gateway runtime pods and the Envoy proxy pods serving cfg's Gateway.
+func adapterNetworkPolicyDoc(cfg *config.Config, w stdioWorkload, namespace string, labels map[string]any) map[string]any {
+	return map[string]any{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata": map[string]any{
+			"name":      w.objectName(cfg),
+			"namespace": namespace,
+		},
+		"spec": map[string]any{
+			"podSelector": map[string]any{"matchLabels": labels},
+			"policyTypes": []string{"Ingress"},
+			"ingress": []map[string]any{{
+				"from": []map[string]any{
+					{"podSelector": map[string]any{"matchLabels": map[string]any{"app": cfg.Gateway.Name}}},
+					{
+						"namespaceSelector": map[string]any{},
+						"podSelector": map[string]any{"matchLabels": map[string]any{
+							"gateway.envoyproxy.io/owning-gateway-name":      cfg.Gateway.Name,
+							"gateway.envoyproxy.io/owning-gateway-namespace": namespace,
+						}},
+					},
+				},
+				"ports": []map[string]any{{"protocol": "TCP", "port": w.port}},
+			}},
+		},
+	}
+}
+
+// checkPodSandbox rejects sandbox settings the rendered gateway pod cannot
+// honour. It runs as a non-root user with every capability dropped, so
+// switching users or creating namespaces would fail each time the server is
+// spawned.
+func checkPodSandbox(cfg *config.Config) error {
+	for _, s := range cfg.Servers {
+		sb := s.Sandbox
+		if s.Transport != "stdio" || s.Image != "" || sb == nil {
+			continue
+		}
+		if sb.User != nil || sb.Group != nil || len(sb.Namespaces) > 0 {
+			return fmt.Errorf("server %q: sandbox user, group and namespaces need root, but the gateway pod runs as uid 65532 with all capabilities dropped; remove them or give the server an image", s.Name)
+		}
+	}
+	return nil
+}
+
+// isLoopbackURL reports whether rawURL points at the pod itself, which Envoy
+// cannot reach.
+func isLoopbackURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// envSecrets lists the Secrets referenced by the env of stdio servers the
+// gateway spawns itself, in first-use order.
+func envSecrets(cfg *config.Config) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, s := range cfg.Servers {
+		if s.Transport != "stdio" || s.Image != "" {
+			continue
+		}
+		for _, e := range s.Env {
+			if e.SecretRef != nil && !seen[e.SecretRef.Name] {
+				seen[e.SecretRef.Name] = true
+				names = append(names, e.SecretRef.Name)
+			}
+		}
+	}
+	return names
+}
+
+func secretsDir(cfg *config.Config) string {
+	if cfg.Gateway.SecretsDir != "" {
+		return cfg.Gateway.SecretsDir
+	}
+	return config.DefaultSecretsDir
+}
+
+// envSecretVolumes returns the volumes and gateway container mounts that
+// expose envSecrets under the runtime's secrets directory.
+func envSecretVolumes(cfg *config.Config) (volumes, mounts []map[string]any) {
+	for i, name := range envSecrets(cfg) {
+		volume := fmt.Sprintf("env-secret-%d", i)
+		volumes = append(volumes, map[string]any{"name": volume, "secret": map[string]any{"secretName": name}})
+		mounts = append(mounts, map[string]any{"name": volume, "mountPath": path.Join(secretsDir(cfg), name), "readOnly": true})
+	}
+	return volumes, mounts
+}
+
+// addEnvSecretVolumes mounts envSecrets into the gateway container.
+func addEnvSecretVolumes(deployment map[string]any, cfg *config.Config) {
+	volumes, mounts := envSecretVolumes(cfg)
+	if len(volumes) == 0 {
+		return
+	}
+	pod := deployment["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)
+	gateway := pod["containers"].([]map[string]any)[0]
+	gateway["volumeMounts"] = append(gateway["volumeMounts"].([]map[string]any), mounts...)
+	pod["volumes"] = append(pod["volumes"].([]map[string]any), volumes...)
+}
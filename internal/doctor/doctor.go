@@ -0,0 +1,452 @@
+// Package doctor checks that a gateway config's servers and routes work:
+// that upstreams resolve, accept connections and speak MCP, and that each
+// route answers through a running gateway.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/clients"
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/runtime"
+)
+
+// Check outcomes.
+const (
+	Pass = "pass"
+	Fail = "fail"
+	Warn = "warn"
+	Skip = "skip"
+)
+
+// Check is one line of the report.
+type Check struct {
+	Target string `json:"target"`
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Checks []Check `json:"checks"`
+	Failed int     `json:"failed"`
+}
+
+// Options configure Run.
+type Options struct {
+	// GatewayURL is where the gateway serving cfg listens. Routes are not
+	// checked when it is empty.
+	GatewayURL string
+	// APIKey and Token authenticate route checks on apiKey and jwt routes.
+	// A route's first configured key is used when APIKey is empty.
+	APIKey string
+	Token  string
+	// Header is added to route requests, e.g. credentials a stdio server
+	// reads from the session's request.
+	Header http.Header
+	// Timeout bounds the checks of each server and route (default 10s).
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+const clientName = "mcp-gateway-doctor"
+
+// Run checks every server of cfg, then every route through the gateway.
+func Run(ctx context.Context, cfg *config.Config, opts Options) Report {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+	d := &doctor{cfg: cfg, opts: opts}
+	for _, s := range cfg.Servers {
+		ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		if s.Transport == "stdio" {
+			d.checkStdio(ctx, s)
+		} else {
+			d.checkURL(ctx, s)
+		}
+		cancel()
+	}
+	if opts.GatewayURL != "" {
+		d.checkRoutes(ctx)
+	}
+	return d.report
+}
+
+type doctor struct {
+	cfg    *config.Config
+	opts   Options
+	report Report
+}
+
+func (d *doctor) add(target, check, status, detail, hint string) {
+	d.report.Checks = append(d.report.Checks, Check{Target: target, Check: check, Status: status, Detail: detail, Hint: hint})
+	if status == Fail {
+		d.report.Failed++
+	}
+}
+
+// skipRest records the checks that cannot run after an earlier failure.
+func (d *doctor) skipRest(target string, checks ...string) {
+	for _, c := range checks {
+		d.add(target, c, Skip, "earlier check failed", "")
+	}
+}
+
+func (d *doctor) checkURL(ctx context.Context, s config.Server) {
+	target := "server " + s.Name
+	u, err := url.Parse(s.URL)
+	if err != nil || u.Hostname() == "" {
+		d.add(target, "url", Fail, fmt.Sprintf("%q is not an absolute URL", s.URL), "set servers[].url to e.g. http://host:port/mcp")
+		return
+	}
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		d.add(target, "dns", Fail, err.Error(), "check the hostname in the server URL and this machine's DNS")
+		d.skipRest(target, "tcp", "initialize", "tools/list")
+		return
+	}
+	d.add(target, "dns", Pass, strings.Join(addrs, ", "), "")
+
+	address := net.JoinHostPort(u.Hostname(), port)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		hint := "check that the server is running and that nothing blocks the port"
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			hint = fmt.Sprintf("nothing listens on %s; start the server or fix the port", address)
+		}
+		d.add(target, "tcp", Fail, err.Error(), hint)
+		d.skipRest(target, "initialize", "tools/list")
+		return
+	}
+	_ = conn.Close()
+	d.add(target, "tcp", Pass, address, "")
+
+	if secure {
+		td := tls.Dialer{NetDialer: &dialer, Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err := td.DialContext(ctx, "tcp", address)
+		if err != nil {
+			d.add(target, "tls", Fail, err.Error(), "check the server's certificate chain and hostname, or use an http:// URL for a plain-text server")
+			d.skipRest(target, "initialize", "tools/list")
+			return
+		}
+		state := conn.(*tls.Conn).ConnectionState()
+		_ = conn.Close()
+		detail := tls.VersionName(state.Version)
+		if len(state.PeerCertificates) > 0 {
+			detail += ", certificate expires " + state.PeerCertificates[0].NotAfter.Format("2006-01-02")
+		}
+		d.add(target, "tls", Pass, detail, "")
+	}
+
+	routePath := ""
+	if r, ok := d.firstRoute(s.Name); ok {
+		routePath = r.UpstreamPath(r.Path)
+	}
+	hint := ""
+	switch s.Transport {
+	case "http":
+		endpoint, _ := runtime.JoinUpstreamURL(s.URL, routePath)
+		hint = fmt.Sprintf("the gateway posts to %s (server URL plus route path); servers that only speak HTTP+SSE need transport: sse", endpoint)
+	case "sse":
+		hint = "the url must be the server's event stream, which announces an endpoint event"
+	case "websocket":
+		hint = "the url must accept a WebSocket upgrade with the mcp subprotocol"
+	}
+	t, err := runtime.DialUpstream(ctx, d.cfg.Gateway, s, routePath, d.opts.Client)
+	if err != nil {
+		d.add(target, "initialize", Fail, err.Error(), hint)
+		d.skipRest(target, "tools/list")
+		return
+	}
+	defer t.Close()
+	d.handshake(ctx, target, t, hint, nil)
+}
+
+func (d *doctor) checkStdio(ctx context.Context, s config.Server) {
+	target := "server " + s.Name
+	path, err := exec.LookPath(s.Command)
+	if err != nil {
+		if s.Image != "" {
+			d.add(target, "command", Skip, fmt.Sprintf("%s is not on this machine's PATH; it runs in %s under Kubernetes", s.Command, s.Image), "")
+			return
+		}
+		d.add(target, "command", Fail, err.Error(), fmt.Sprintf("install %s or set command to an absolute path", s.Command))
+		d.skipRest(target, "start", "initialize", "tools/list")
+		return
+	}
+	d.add(target, "command", Pass, path, "")
+
+	var required []string
+	for _, c := range s.Credentials {
+		if !c.Optional {
+			required = append(required, c.String())
+		}
+	}
+	if len(required) > 0 {
+		d.add(target, "credentials", Warn, "started without per-session credentials: "+strings.Join(required, "; "),
+			"pass the headers to the route checks with --header")
+	}
+
+	cmd, err := runtime.StdioCommand(d.cfg.Gateway, s, nil)
+	if err != nil {
+		d.add(target, "start", Fail, err.Error(), "check the server's env entries and sandbox settings")
+		d.skipRest(target, "initialize", "tools/list")
+		return
+	}
+	stderr := &lastLine{}
+	cmd.Stderr = stderr
+	st, err := mcp.StartStdio(cmd)
+	if err != nil {
+		d.add(target, "start", Fail, err.Error(), "check that the command is executable and workingDir exists")
+		d.skipRest(target, "initialize", "tools/list")
+		return
+	}
+	defer st.Close()
+	d.add(target, "start", Pass, fmt.Sprintf("pid %d", st.Pid()), "")
+
+	hint := "the command must speak newline-delimited JSON-RPC on stdin and stdout"
+	if len(required) > 0 {
+		hint = "the server may need its credentials; check its route with --header instead"
+	}
+	d.handshake(ctx, target, st, hint, stderr.String)
+}
+
+// handshake initializes an MCP session over t and lists its tools. stderr,
+// if set, returns the server's last stderr line for a failed initialize.
+func (d *doctor) handshake(ctx context.Context, target string, t mcp.Transport, hint string, stderr func() string) {
+	c := mcp.NewClient(t)
+	res, err := c.Initialize(ctx, clientName, "")
+	if err != nil {
+		detail := err.Error()
+		if stderr != nil {
+			if line := stderr(); line != "" {
+				detail += "; stderr: " + line
+			}
+		}
+		d.add(target, "initialize", Fail, detail, hint)
+		d.skipRest(target, "tools/list")
+		return
+	}
+	detail := "protocol " + res.ProtocolVersion
+	if res.ServerInfo.Name != "" {
+		detail += ", " + strings.TrimSpace(res.ServerInfo.Name+" "+res.ServerInfo.Version)
+	}
+	capabilities := make([]string, 0, len(res.Capabilities))
+	for name := range res.Capabilities {
+		capabilities = append(capabilities, name)
+	}
+	sort.Strings(capabilities)
+	if len(capabilities) > 0 {
+		detail += ", capabilities: " + strings.Join(capabilities, ", ")
+	}
+	d.add(target, "initialize", Pass, detail, "")
+
+	if _, ok := res.Capabilities["tools"]; !ok {
+		d.add(target, "tools/list", Skip, "server does not advertise tools", "")
+		return
+	}
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		d.add(target, "tools/list", Fail, err.Error(), "the server advertises tools but cannot list them")
+		return
+	}
+	d.add(target, "tools/list", Pass, fmt.Sprintf("%d tools", len(tools)), "")
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "https" || scheme == "wss" {
+		return "443"
+	}
+	return "80"
+}
+
+func (d *doctor) firstRoute(server string) (config.Route, bool) {
+	for _, r := range d.cfg.Routes {
+		if r.Server == server {
+			return r, true
+		}
+	}
+	return config.Route{}, false
+}
+
+func (d *doctor) checkRoutes(ctx context.Context) {
+	base := strings.TrimSuffix(d.opts.GatewayURL, "/")
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		d.add("gateway", "reachable", Fail, fmt.Sprintf("%q is not an absolute URL", d.opts.GatewayURL), "pass --gateway-url http://host:port")
+		return
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), defaultPort(u.Scheme))
+	}
+	var dialer net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	conn, err := dialer.DialContext(dialCtx, "tcp", address)
+	cancel()
+	if err != nil {
+		d.add("gateway", "reachable", Fail, err.Error(), "start it with gateway serve, or pass --gateway-url")
+		for _, r := range d.cfg.Routes {
+			d.add("route "+r.Name, "initialize", Skip, "gateway not reachable", "")
+		}
+		return
+	}
+	_ = conn.Close()
+	d.add("gateway", "reachable", Pass, base, "")
+
+	for _, r := range d.cfg.Routes {
+		ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+		d.checkRoute(ctx, base, r)
+		cancel()
+	}
+}
+
+func (d *doctor) checkRoute(ctx context.Context, base string, r config.Route) {
+	target := "route " + r.Name
+	creds := clients.Credentials{APIKey: d.opts.APIKey, Token: d.opts.Token, Header: d.opts.Header}
+	conn, err := clients.DialRoute(ctx, d.cfg, r, base, "", creds, d.opts.Client)
+	if errors.Is(err, clients.ErrNoCredential) {
+		hint := "pass --api-key or set MCP_GATEWAY_API_KEY"
+		if clients.RouteAuth(d.cfg, r) == "jwt" {
+			hint = "pass --token or set MCP_GATEWAY_TOKEN"
+		}
+		d.add(target, "initialize", Skip, err.Error(), hint)
+		return
+	}
+	hint := d.routeHint(r, clients.RouteAuth(d.cfg, r))
+	if err != nil {
+		d.add(target, "initialize", Fail, err.Error(), hint(err))
+		d.skipRest(target, "tools/list")
+		return
+	}
+	defer conn.Close()
+
+	c := mcp.NewClient(conn)
+	res, err := c.Initialize(ctx, clientName, "")
+	if err != nil {
+		d.add(target, "initialize", Fail, err.Error(), hint(err))
+		d.skipRest(target, "tools/list")
+		return
+	}
+	d.add(target, "initialize", Pass, fmt.Sprintf("%s, %s auth, protocol %s", conn.ClientTransport, conn.Auth, res.ProtocolVersion), "")
+	if _, ok := res.Capabilities["tools"]; !ok {
+		d.add(target, "tools/list", Skip, "server does not advertise tools", "")
+		return
+	}
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		d.add(target, "tools/list", Fail, err.Error(), hint(err))
+		return
+	}
+	d.add(target, "tools/list", Pass, fmt.Sprintf("%d tools", len(tools)), "")
+}
+
+// routeHint suggests a fix for an error seen through the gateway.
+func (d *doctor) routeHint(r config.Route, authType string) func(error) string {
+	return func(err error) string {
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "401") || strings.Contains(msg, "403"):
+			if authType == "jwt" {
+				return "the gateway rejected the token; check --token against the route's issuer and audience"
+			}
+			return "the gateway rejected the credentials; check --api-key and any credentials the server expects"
+		case strings.Contains(msg, "404"):
+			return "the gateway does not serve " + r.Path + "; is it running this config?"
+		case strings.Contains(msg, "502") || strings.Contains(msg, "upstream error"):
+			return fmt.Sprintf("the gateway could not reach server %s; see its checks above", r.Server)
+		}
+		return ""
+	}
+}
+
+// lastLine keeps the last non-empty line written to it.
+type lastLine struct {
+	mu      sync.Mutex
+	partial []byte
+	last    string
+}
+
+func (l *lastLine) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.partial = append(l.partial, p...)
+	for {
+		i := strings.IndexByte(string(l.partial), '\n')
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimSpace(string(l.partial[:i])); line != "" {
+			l.last = line
+		}
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.partial) > 4096 {
+		l.partial = l.partial[len(l.partial)-4096:]
+	}
+	return len(p), nil
+}
+
+func (l *lastLine) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if line := strings.TrimSpace(string(l.partial)); line != "" {
+		return line
+	}
+	return l.last
+}
+
+// WriteText prints the report as a table, with a hint under each failure.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tCHECK\tSTATUS\tDETAIL")
+	for _, c := range r.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Target, c.Check, strings.ToUpper(c.Status), c.Detail)
+		if c.Hint != "" && (c.Status == Fail || c.Status == Warn || c.Status == Skip) {
+			fmt.Fprintf(tw, "\t\t\thint: %s\n", c.Hint)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d checks, %d failed\n", len(r.Checks), r.Failed)
+	return err
+}
+
+// WriteJSON prints the report for CI.
+func (r Report) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
@@ -0,0 +1,93 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/runtime"
+)
+
+func TestRunChecksServersAndRoutes(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mcp/weather" {
+			http.NotFound(w, r)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		msg, err := mcp.Parse(b)
+		if err != nil || !msg.IsRequest() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch msg.Method {
+		case "initialize":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":%q,"capabilities":{"tools":{}},"serverInfo":{"name":"weather","version":"1.0"}}}`, msg.ID, mcp.ProtocolVersion)
+		case "tools/list":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"tools":[{"name":"forecast"},{"name":"alerts"}]}}`, msg.ID)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{
+			{Name: "weather", Transport: "http", URL: upstream.URL},
+			{Name: "missing", Transport: "stdio", Command: "mcp-server-that-does-not-exist"},
+		},
+		Routes: []config.Route{
+			{Name: "weather", Path: "/mcp/weather", Server: "weather",
+				Auth: &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"k1"}}},
+			{Name: "missing", Path: "/mcp/missing", Server: "missing"},
+		},
+	}
+	gw := httptest.NewServer(runtime.NewServer(cfg).Handler())
+	defer gw.Close()
+
+	report := Run(context.Background(), cfg, Options{GatewayURL: gw.URL, Timeout: 5 * time.Second})
+	status := map[string]Check{}
+	for _, c := range report.Checks {
+		status[c.Target+" "+c.Check] = c
+	}
+	for key, want := range map[string]string{
+		"server weather dns":        Pass,
+		"server weather tcp":        Pass,
+		"server weather initialize": Pass,
+		"server weather tools/list": Pass,
+		"server missing command":    Fail,
+		"server missing start":      Skip,
+		"gateway reachable":         Pass,
+		"route weather initialize":  Pass,
+		"route weather tools/list":  Pass,
+		"route missing initialize":  Fail,
+	} {
+		if got := status[key].Status; got != want {
+			t.Errorf("%s: got %q, want %q (%+v)", key, got, want, status[key])
+		}
+	}
+	if d := status["server weather initialize"].Detail; !strings.Contains(d, mcp.ProtocolVersion) || !strings.Contains(d, "capabilities: tools") {
+		t.Errorf("initialize detail lacks protocol and capabilities: %q", d)
+	}
+	if d := status["route weather tools/list"].Detail; d != "2 tools" {
+		t.Errorf("route tools/list detail = %q", d)
+	}
+	if report.Failed != 2 || status["server missing command"].Hint == "" {
+		t.Errorf("expected two failures with hints, got %+v", report)
+	}
+
+	var out strings.Builder
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "hint: install mcp-server-that-does-not-exist") {
+		t.Errorf("text report lacks the fix hint:\n%s", out.String())
+	}
+}
@@ -0,0 +1,233 @@
+// Package kube is a minimal Kubernetes API client for the resource kinds the
+// gateway renders. It speaks plain REST (server-side apply, get, list,
+// delete) so the gateway does not need client-go.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Object is an unstructured Kubernetes object.
+type Object = map[string]any
+
+// ResourceInfo describes how a kind is addressed in the API.
+type ResourceInfo struct {
+	APIVersion string
+	Kind       string
+	Plural     string
+	Namespaced bool
+}
+
+// Resources lists every kind the gateway renders, in apply order.
+var Resources = []ResourceInfo{
+	{"v1", "Namespace", "namespaces", false},
+	{"v1", "ConfigMap", "configmaps", true},
+	{"v1", "Secret", "secrets", true},
+	{"networking.k8s.io/v1", "NetworkPolicy", "networkpolicies", true},
+	{"v1", "Service", "services", true},
+	{"apps/v1", "Deployment", "deployments", true},
+	{"policy/v1", "PodDisruptionBudget", "poddisruptionbudgets", true},
+	{"autoscaling/v2", "HorizontalPodAutoscaler", "horizontalpodautoscalers", true},
+	{"gateway.networking.k8s.io/v1", "Gateway", "gateways", true},
+	{"gateway.networking.k8s.io/v1", "HTTPRoute", "httproutes", true},
+	{"gateway.envoyproxy.io/v1alpha1", "Backend", "backends", true},
+	{"gateway.envoyproxy.io/v1alpha1", "BackendTrafficPolicy", "backendtrafficpolicies", true},
+	{"gateway.envoyproxy.io/v1alpha1", "SecurityPolicy", "securitypolicies", true},
+	{"gateway.envoyproxy.io/v1alpha1", "ClientTrafficPolicy", "clienttrafficpolicies", true},
+}
+
+// LookupResource returns the API mapping for apiVersion/kind.
+func LookupResource(apiVersion, kind string) (ResourceInfo, bool) {
+	for _, r := range Resources {
+		if r.APIVersion == apiVersion && r.Kind == kind {
+			return r, true
+		}
+	}
+	return ResourceInfo{}, false
+}
+
+// StatusError is a non-2xx response from the API server.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("kubernetes api: %d %s", e.Code, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API server.
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
+
+// Client talks to one API server.
+type Client struct {
+	host  string
+	token string
+	http  *http.Client
+}
+
+// NewClient builds a client from cfg.
+func NewClient(cfg *Config) (*Client, error) {
+	transport, err := cfg.transport()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		host:  strings.TrimSuffix(cfg.Host, "/"),
+		token: cfg.Token,
+		http:  &http.Client{Transport: transport},
+	}, nil
+}
+
+// ObjectPath returns the REST path of a named object, or of the collection
+// when name is empty.
+func ObjectPath(info ResourceInfo, namespace, name string) string {
+	var b strings.Builder
+	if strings.Contains(info.APIVersion, "/") {
+		b.WriteString("/apis/" + info.APIVersion)
+	} else {
+		b.WriteString("/api/" + info.APIVersion)
+	}
+	if info.Namespaced && namespace != "" {
+		b.WriteString("/namespaces/" + url.PathEscape(namespace))
+	}
+	b.WriteString("/" + info.Plural)
+	if name != "" {
+		b.WriteString("/" + url.PathEscape(name))
+	}
+	return b.String()
+}
+
+// Apply server-side applies obj as fieldManager, taking ownership of
+// conflicting fields.
+func (c *Client) Apply(ctx context.Context, obj Object, fieldManager string) (Object, error) {
+	info, ns, name, err := identify(obj)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s/%s: %w", info.Kind, name, err)
+	}
+	q := url.Values{"fieldManager": {fieldManager}, "force": {"true"}}
+	var out Object
+	err = c.do(ctx, http.MethodPatch, ObjectPath(info, ns, name)+"?"+q.Encode(), "application/apply-patch+yaml", body, &out)
+	return out, err
+}
+
+// Get fetches a single object.
+func (c *Client) Get(ctx context.Context, apiVersion, kind, namespace, name string) (Object, error) {
+	info, ok := LookupResource(apiVersion, kind)
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %s %s", apiVersion, kind)
+	}
+	var out Object
+	err := c.do(ctx, http.MethodGet, ObjectPath(info, namespace, name), "", nil, &out)
+	return out, err
+}
+
+// List returns the objects of a kind in namespace matching labelSelector.
+func (c *Client) List(ctx context.Context, apiVersion, kind, namespace, labelSelector string) ([]Object, error) {
+	info, ok := LookupResource(apiVersion, kind)
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %s %s", apiVersion, kind)
+	}
+	path := ObjectPath(info, namespace, "")
+	if labelSelector != "" {
+		path += "?" + url.Values{"labelSelector": {labelSelector}}.Encode()
+	}
+	var list struct {
+		Items []Object `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &list); err != nil {
+		return nil, err
+	}
+	for _, item := range list.Items {
+		// List responses omit per-item type metadata.
+		item["apiVersion"] = apiVersion
+		item["kind"] = kind
+	}
+	return list.Items, nil
+}
+
+// Delete removes an object, letting the garbage collector clean up dependents.
+func (c *Client) Delete(ctx context.Context, apiVersion, kind, namespace, name string) error {
+	info, ok := LookupResource(apiVersion, kind)
+	if !ok {
+		return fmt.Errorf("unsupported kind %s %s", apiVersion, kind)
+	}
+	body := []byte(`{"propagationPolicy":"Background"}`)
+	return c.do(ctx, http.MethodDelete, ObjectPath(info, namespace, name), "application/json", body, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes api %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("kubernetes api %s %s: read response: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		var status struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(b, &status)
+		if status.Message == "" {
+			status.Message = strings.TrimSpace(string(b))
+		}
+		return &StatusError{Code: resp.StatusCode, Message: status.Message}
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("kubernetes api %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// identify extracts the API mapping, namespace and name of obj.
+func identify(obj Object) (ResourceInfo, string, string, error) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	info, ok := LookupResource(apiVersion, kind)
+	if !ok {
+		return ResourceInfo{}, "", "", fmt.Errorf("unsupported kind %s %s", apiVersion, kind)
+	}
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	ns, _ := meta["namespace"].(string)
+	if name == "" {
+		return ResourceInfo{}, "", "", fmt.Errorf("%s is missing metadata.name", kind)
+	}
+	return info, ns, name, nil
+}
@@ -0,0 +1,192 @@
+// Package kubetest provides an in-memory Kubernetes API server stand-in for
+// tests. It understands the REST paths of kube.Resources: server-side apply
+// (PATCH), get, list with equality label selectors, and delete.
+package kubetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/kube"
+)
+
+// Server is a fake API server backed by a map of objects.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]kube.Object
+	// Requests records "METHOD path" for every call, for assertions.
+	Requests []string
+}
+
+// NewServer starts a fake API server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{objects: map[string]kube.Object{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Config returns client settings pointing at the fake server.
+func (s *Server) Config() *kube.Config {
+	return &kube.Config{Host: s.URL}
+}
+
+// Put stores obj directly, bypassing apply (for seeding drift or strays).
+func (s *Server) Put(obj kube.Object) {
+	info, _ := kube.LookupResource(obj["apiVersion"].(string), obj["kind"].(string))
+	meta := obj["metadata"].(map[string]any)
+	ns, _ := meta["namespace"].(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[kube.ObjectPath(info, ns, meta["name"].(string))] = roundTrip(obj)
+}
+
+// Object returns the stored object at the given coordinates, or nil.
+func (s *Server) Object(apiVersion, kind, namespace, name string) kube.Object {
+	info, _ := kube.LookupResource(apiVersion, kind)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[kube.ObjectPath(info, namespace, name)]
+}
+
+// Len returns the number of stored objects.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Requests = append(s.Requests, r.Method+" "+r.URL.Path)
+
+	info, ns, name, ok := parsePath(r.URL.Path)
+	if !ok {
+		writeStatus(w, http.StatusNotFound, "the server could not find the requested resource")
+		return
+	}
+	key := kube.ObjectPath(info, ns, name)
+
+	switch {
+	case r.Method == http.MethodPatch && name != "":
+		if r.Header.Get("Content-Type") != "application/apply-patch+yaml" || r.URL.Query().Get("fieldManager") == "" {
+			writeStatus(w, http.StatusBadRequest, "expected server-side apply with a field manager")
+			return
+		}
+		var obj kube.Object
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &obj); err != nil {
+			writeStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.objects[key] = obj
+		writeJSON(w, obj)
+	case r.Method == http.MethodGet && name != "":
+		obj, ok := s.objects[key]
+		if !ok {
+			writeStatus(w, http.StatusNotFound, fmt.Sprintf("%s %q not found", info.Plural, name))
+			return
+		}
+		writeJSON(w, obj)
+	case r.Method == http.MethodGet:
+		selector := parseSelector(r.URL.Query().Get("labelSelector"))
+		prefix := key + "/"
+		keys := make([]string, 0, len(s.objects))
+		for k := range s.objects {
+			if strings.HasPrefix(k, prefix) && !strings.Contains(strings.TrimPrefix(k, prefix), "/") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		items := []kube.Object{}
+		for _, k := range keys {
+			if matches(s.objects[k], selector) {
+				items = append(items, s.objects[k])
+			}
+		}
+		writeJSON(w, map[string]any{"items": items})
+	case r.Method == http.MethodDelete && name != "":
+		if _, ok := s.objects[key]; !ok {
+			writeStatus(w, http.StatusNotFound, fmt.Sprintf("%s %q not found", info.Plural, name))
+			return
+		}
+		delete(s.objects, key)
+		writeJSON(w, map[string]any{"status": "Success"})
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// parsePath maps a REST path back to its resource, namespace and name.
+func parsePath(path string) (kube.ResourceInfo, string, string, bool) {
+	for _, info := range kube.Resources {
+		base := "/api/" + info.APIVersion
+		if strings.Contains(info.APIVersion, "/") {
+			base = "/apis/" + info.APIVersion
+		}
+		if !strings.HasPrefix(path, base+"/") {
+			continue
+		}
+		rest := strings.Split(strings.TrimPrefix(path, base+"/"), "/")
+		ns := ""
+		if info.Namespaced && len(rest) >= 2 && rest[0] == "namespaces" {
+			ns, rest = rest[1], rest[2:]
+		}
+		if len(rest) == 0 || rest[0] != info.Plural || len(rest) > 2 {
+			continue
+		}
+		name := ""
+		if len(rest) == 2 {
+			name = rest[1]
+		}
+		return info, ns, name, true
+	}
+	return kube.ResourceInfo{}, "", "", false
+}
+
+func parseSelector(s string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
+func matches(obj kube.Object, selector map[string]string) bool {
+	meta, _ := obj["metadata"].(map[string]any)
+	labels, _ := meta["labels"].(map[string]any)
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func roundTrip(obj kube.Object) kube.Object {
+	b, _ := json.Marshal(obj)
+	var out kube.Object
+	_ = json.Unmarshal(b, &out)
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"kind": "Status", "code": code, "message": message})
+}
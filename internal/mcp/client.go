@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ProtocolVersion is the MCP revision the gateway's client requests.
+const ProtocolVersion = "2025-06-18"
+
+// Client issues JSON-RPC requests over a Transport and waits for the matching
+// responses. It makes one call at a time; messages that answer nothing it
+// sent (server requests, notifications) go to OnMessage, or are dropped.
+type Client struct {
+	t Transport
+
+	// OnMessage, if set, receives messages that arrive while a call is
+	// waiting and are not its response, such as progress notifications.
+	OnMessage func(Message)
+
+	mu     sync.Mutex
+	nextID int
+}
+
+// NewClient wraps t. Closing the transport is left to the caller.
+func NewClient(t Transport) *Client {
+	return &Client{t: t}
+}
+
+// InitializeResult is the part of the initialize response the gateway reads.
+type InitializeResult struct {
+	ProtocolVersion string                     `json:"protocolVersion"`
+	Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	ServerInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// Tool is one entry of a tools/list result.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Initialize performs the initialize handshake, including the initialized
+// notification.
+func (c *Client) Initialize(ctx context.Context, clientName, clientVersion string) (InitializeResult, error) {
+	var res InitializeResult
+	raw, err := c.Call(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": clientName, "version": clientVersion},
+	})
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return res, fmt.Errorf("decode initialize result: %w", err)
+	}
+	return res, c.Notify(ctx, "notifications/initialized", nil)
+}
+
+// ListTools returns every tool, following pagination cursors.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		raw, err := c.Call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("decode tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// ToolResult is the result of tools/call.
+type ToolResult struct {
+	Content           []json.RawMessage `json:"content"`
+	StructuredContent json.RawMessage   `json:"structuredContent,omitempty"`
+	IsError           bool              `json:"isError,omitempty"`
+}
+
+// CallTool invokes a tool with the given arguments.
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]any) (ToolResult, error) {
+	var res ToolResult
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+	raw, err := c.Call(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments})
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return res, fmt.Errorf("decode tools/call result: %w", err)
+	}
+	return res, nil
+}
+
+// Call sends a request and returns its result. A JSON-RPC error response is
+// returned as *Error.
+func (c *Client) Call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	msg := Message{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s params: %w", method, err)
+		}
+		msg.Params = b
+	}
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.t.Send(ctx, raw); err != nil {
+		return nil, err
+	}
+	for {
+		select {
+		case in, ok := <-c.t.Messages():
+			if !ok {
+				if err := c.t.Err(); err != nil {
+					return nil, err
+				}
+				return nil, ErrClosed
+			}
+			resp, err := Parse(in)
+			if err != nil {
+				continue
+			}
+			if !resp.IsResponse() || resp.IDKey() != string(id) {
+				if c.OnMessage != nil {
+					c.OnMessage(resp)
+				}
+				continue
+			}
+			if resp.Error != nil {
+				return nil, resp.Error
+			}
+			return resp.Result, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Notify sends a notification.
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	msg := Message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("encode %s params: %w", method, err)
+		}
+		msg.Params = b
+	}
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.t.Send(ctx, raw)
+}
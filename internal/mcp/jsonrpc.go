@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a JSON-RPC 2.0 envelope. Params and results are kept raw so the
+// gateway can forward them without re-encoding.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// Parse decodes a single JSON-RPC message.
+func Parse(raw []byte) (Message, error) {
+	var m Message
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return Message{}, fmt.Errorf("parse jsonrpc message: %w", err)
+	}
+	return m, nil
+}
+
+// IsRequest reports whether the message expects a response.
+func (m Message) IsRequest() bool { return m.Method != "" && hasID(m.ID) }
+
+// IsNotification reports whether the message is a one-way notification.
+func (m Message) IsNotification() bool { return m.Method != "" && !hasID(m.ID) }
+
+// IsResponse reports whether the message answers an earlier request.
+func (m Message) IsResponse() bool { return m.Method == "" && hasID(m.ID) }
+
+// IDKey returns a comparable form of the message id for correlating responses.
+func (m Message) IDKey() string { return string(bytes.TrimSpace(m.ID)) }
+
+func hasID(id json.RawMessage) bool {
+	id = bytes.TrimSpace(id)
+	return len(id) > 0 && !bytes.Equal(id, []byte("null"))
+}
+
+// SplitBatch returns the individual messages of a JSON-RPC batch, or the
+// message itself when raw is not a batch.
+func SplitBatch(raw []byte) ([]json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty jsonrpc payload")
+	}
+	if raw[0] != '[' {
+		return []json.RawMessage{json.RawMessage(raw)}, nil
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		return nil, fmt.Errorf("parse jsonrpc batch: %w", err)
+	}
+	return batch, nil
+}
+
+// ErrorResponse builds an encoded JSON-RPC error response for id.
+func ErrorResponse(id json.RawMessage, code int, message string) json.RawMessage {
+	if !hasID(id) {
+		id = json.RawMessage("null")
+	}
+	b, _ := json.Marshal(Message{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &Error{Code: code, Message: message},
+	})
+	return b
+}
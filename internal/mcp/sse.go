@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Event is a single server-sent event.
+type Event struct {
+	ID    string
+	Event string
+	Data  []byte
+}
+
+// EventReader parses a text/event-stream body.
+type EventReader struct {
+	r *bufio.Reader
+}
+
+func NewEventReader(r io.Reader) *EventReader {
+	return &EventReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next returns the next dispatched event. Comment lines and events without
+// data are skipped, as the SSE spec requires.
+func (er *EventReader) Next() (Event, error) {
+	var ev Event
+	var data bytes.Buffer
+	hasData := false
+	for {
+		line, err := er.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return Event{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if hasData {
+				ev.Data = data.Bytes()
+				return ev, nil
+			}
+			ev = Event{}
+			if err == io.EOF {
+				return Event{}, io.EOF
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "id":
+			ev.ID = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+		if err == io.EOF {
+			if hasData {
+				ev.Data = data.Bytes()
+				return ev, nil
+			}
+			return Event{}, io.EOF
+		}
+	}
+}
+
+// WriteEvent writes ev in text/event-stream framing and flushes w when possible.
+func WriteEvent(w io.Writer, ev Event) error {
+	var b strings.Builder
+	if ev.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", ev.ID)
+	}
+	if ev.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", ev.Event)
+	}
+	for _, line := range strings.Split(string(ev.Data), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
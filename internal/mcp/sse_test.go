@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEventReader(t *testing.T) {
+	stream := ": comment\n" +
+		"event: endpoint\n" +
+		"data: /messages?sessionId=1\n\n" +
+		"data: {\"a\":1,\n" +
+		"data: \"b\":2}\n\n" +
+		"event: message\r\n" +
+		"data: tail"
+	er := NewEventReader(strings.NewReader(stream))
+
+	ev, err := er.Next()
+	if err != nil || ev.Event != "endpoint" || string(ev.Data) != "/messages?sessionId=1" {
+		t.Fatalf("unexpected first event: %+v err=%v", ev, err)
+	}
+	ev, err = er.Next()
+	if err != nil || ev.Event != "" || string(ev.Data) != "{\"a\":1,\n\"b\":2}" {
+		t.Fatalf("unexpected multi-line event: %+v err=%v", ev, err)
+	}
+	ev, err = er.Next()
+	if err != nil || ev.Event != "message" || string(ev.Data) != "tail" {
+		t.Fatalf("unexpected unterminated event: %+v err=%v", ev, err)
+	}
+	if _, err := er.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestWriteEventRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteEvent(&buf, Event{Event: "message", Data: []byte("line1\nline2")}); err != nil {
+		t.Fatalf("write event: %v", err)
+	}
+	ev, err := NewEventReader(&buf).Next()
+	if err != nil || ev.Event != "message" || string(ev.Data) != "line1\nline2" {
+		t.Fatalf("round trip mismatch: %+v err=%v", ev, err)
+	}
+}
@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioStopGrace is how long a child gets to exit after stdin closes.
+const stdioStopGrace = 2 * time.Second
+
+// StdioTransport exchanges newline-delimited JSON-RPC messages with a child
+// process over its stdin and stdout.
+type StdioTransport struct {
+	*stream
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	exited chan struct{}
+
+	writeMu sync.Mutex
+}
+
+// StartStdio starts cmd and begins reading messages from its stdout. The
+// caller owns the rest of cmd's setup (environment, directory, stderr).
+func StartStdio(cmd *exec.Cmd) (*StdioTransport, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cmd.Path, err)
+	}
+
+	t := &StdioTransport{
+		stream: newStream(),
+		cmd:    cmd,
+		stdin:  stdin,
+		exited: make(chan struct{}),
+	}
+	t.spawn(func() {
+		br := bufio.NewReaderSize(stdout, 64*1024)
+		for {
+			line, err := br.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				if !t.deliver(json.RawMessage(line)) {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		_, _ = io.Copy(io.Discard, stdout)
+		waitErr := cmd.Wait()
+		close(t.exited)
+		if waitErr == nil {
+			waitErr = fmt.Errorf("process exited")
+		}
+		t.shutdown(fmt.Errorf("stdio server exited: %w", waitErr))
+	})
+	return t, nil
+}
+
+// Pid returns the child's process id.
+func (t *StdioTransport) Pid() int { return t.cmd.Process.Pid }
+
+// Exited is closed once the child has been reaped.
+func (t *StdioTransport) Exited() <-chan struct{} { return t.exited }
+
+// ExitCode returns the child's exit code, or -1 while it is running or when
+// it was killed by a signal.
+func (t *StdioTransport) ExitCode() int {
+	select {
+	case <-t.exited:
+		return t.cmd.ProcessState.ExitCode()
+	default:
+		return -1
+	}
+}
+
+func (t *StdioTransport) Send(_ context.Context, msg json.RawMessage) error {
+	if t.isClosed() {
+		if err := t.Err(); err != nil {
+			return err
+		}
+		return ErrClosed
+	}
+	msg = bytes.TrimSpace(msg)
+	if bytes.ContainsAny(msg, "\r\n") {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, msg); err != nil {
+			return fmt.Errorf("compact message: %w", err)
+		}
+		msg = compact.Bytes()
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(append([]byte(nil), msg...), '\n')); err != nil {
+		return fmt.Errorf("write stdin: %w", err)
+	}
+	return nil
+}
+
+// Close closes the child's stdin and kills it if it has not exited within a
+// short grace period.
+func (t *StdioTransport) Close() error {
+	t.shutdown(ErrClosed)
+	t.writeMu.Lock()
+	_ = t.stdin.Close()
+	t.writeMu.Unlock()
+	select {
+	case <-t.exited:
+	case <-time.After(stdioStopGrace):
+		_ = t.cmd.Process.Kill()
+		<-t.exited
+	}
+	return nil
+}
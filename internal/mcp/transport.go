@@ -0,0 +1,398 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SessionHeader carries the streamable HTTP session id.
+const SessionHeader = "Mcp-Session-Id"
+
+// ErrClosed is reported by transports after Close.
+var ErrClosed = errors.New("transport closed")
+
+// Transport carries JSON-RPC messages to one upstream MCP server on behalf of
+// one client session. Responses and server-initiated messages arrive on
+// Messages, which is closed once the transport ends; Err then reports why.
+type Transport interface {
+	Send(ctx context.Context, msg json.RawMessage) error
+	Messages() <-chan json.RawMessage
+	Err() error
+	Close() error
+}
+
+// stream is the delivery half shared by every transport implementation.
+type stream struct {
+	msgs chan json.RawMessage
+	done chan struct{}
+
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+	err    error
+}
+
+func newStream() *stream {
+	return &stream{
+		msgs: make(chan json.RawMessage, 64),
+		done: make(chan struct{}),
+	}
+}
+
+func (s *stream) Messages() <-chan json.RawMessage { return s.msgs }
+
+func (s *stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// deliver hands msg to the consumer unless the stream has shut down.
+func (s *stream) deliver(msg json.RawMessage) bool {
+	select {
+	case s.msgs <- msg:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// spawn runs fn as a tracked producer; Messages is not closed until every
+// producer has returned.
+func (s *stream) spawn(fn func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// shutdown records err (the first one wins) and closes the stream.
+func (s *stream) shutdown(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+	close(s.done)
+	go func() {
+		s.wg.Wait()
+		close(s.msgs)
+	}()
+}
+
+func (s *stream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// HTTPTransport speaks the streamable HTTP transport: each message is POSTed
+// and the reply is either a JSON body or an SSE stream.
+type HTTPTransport struct {
+	*stream
+	url    string
+	client *http.Client
+	header http.Header
+
+	sessionMu sync.Mutex
+	sessionID string
+}
+
+// NewHTTPTransport returns a transport that POSTs to endpoint. header is
+// attached to every request (for example upstream credentials).
+func NewHTTPTransport(endpoint string, client *http.Client, header http.Header) *HTTPTransport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPTransport{
+		stream: newStream(),
+		url:    endpoint,
+		client: client,
+		header: header.Clone(),
+	}
+}
+
+// SessionID returns the session id issued by the upstream, if any.
+func (t *HTTPTransport) SessionID() string {
+	t.sessionMu.Lock()
+	defer t.sessionMu.Unlock()
+	return t.sessionID
+}
+
+func (t *HTTPTransport) Send(ctx context.Context, msg json.RawMessage) error {
+	if t.isClosed() {
+		return ErrClosed
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	setHeader(req, t.header)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if id := t.SessionID(); id != "" {
+		req.Header.Set(SessionHeader, id)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post upstream: %w", err)
+	}
+	if id := resp.Header.Get(SessionHeader); id != "" {
+		t.sessionMu.Lock()
+		t.sessionID = id
+		t.sessionMu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		return fmt.Errorf("upstream returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode == http.StatusAccepted || resp.ContentLength == 0 {
+		_ = resp.Body.Close()
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !t.spawn(func() {
+		defer resp.Body.Close()
+		if mediaType == "text/event-stream" {
+			t.readEvents(resp.Body)
+			return
+		}
+		t.readJSON(resp.Body)
+	}) {
+		_ = resp.Body.Close()
+		return ErrClosed
+	}
+	return nil
+}
+
+func (t *HTTPTransport) readJSON(body io.Reader) {
+	b, err := io.ReadAll(body)
+	if err != nil || len(bytes.TrimSpace(b)) == 0 {
+		return
+	}
+	msgs, err := SplitBatch(b)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		if !t.deliver(m) {
+			return
+		}
+	}
+}
+
+func (t *HTTPTransport) readEvents(body io.Reader) {
+	er := NewEventReader(body)
+	for {
+		ev, err := er.Next()
+		if err != nil {
+			return
+		}
+		if ev.Event != "" && ev.Event != "message" {
+			continue
+		}
+		if !t.deliver(json.RawMessage(ev.Data)) {
+			return
+		}
+	}
+}
+
+// Close ends the transport and asks the upstream to drop its session.
+func (t *HTTPTransport) Close() error {
+	if id := t.SessionID(); id != "" && !t.isClosed() {
+		if req, err := http.NewRequest(http.MethodDelete, t.url, nil); err == nil {
+			setHeader(req, t.header)
+			req.Header.Set(SessionHeader, id)
+			if resp, err := t.client.Do(req); err == nil {
+				_ = resp.Body.Close()
+			}
+		}
+	}
+	t.shutdown(ErrClosed)
+	return nil
+}
+
+// SSETransport speaks the legacy HTTP+SSE transport: a long-lived GET stream
+// announces a message endpoint, and client messages are POSTed to it.
+type SSETransport struct {
+	*stream
+	client   *http.Client
+	header   http.Header
+	endpoint string
+	cancel   context.CancelFunc
+}
+
+// DialSSE opens the event stream at streamURL and waits for the endpoint event.
+func DialSSE(ctx context.Context, streamURL string, client *http.Client, header http.Header) (*SSETransport, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse sse url: %w", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	setHeader(req, header)
+	req.Header.Set("Accept", "text/event-stream")
+
+	type dialResult struct {
+		resp *http.Response
+		err  error
+	}
+	ch := make(chan dialResult, 1)
+	go func() {
+		resp, err := client.Do(req)
+		ch <- dialResult{resp, err}
+	}()
+	var resp *http.Response
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			cancel()
+			return nil, fmt.Errorf("open sse stream: %w", res.err)
+		}
+		resp = res.resp
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("open sse stream: upstream returned %s", resp.Status)
+	}
+
+	er := NewEventReader(resp.Body)
+	endpointCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			ev, err := er.Next()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if ev.Event == "endpoint" {
+				endpointCh <- strings.TrimSpace(string(ev.Data))
+				return
+			}
+		}
+	}()
+
+	var rawEndpoint string
+	select {
+	case rawEndpoint = <-endpointCh:
+	case err := <-errCh:
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("read sse endpoint event: %w", err)
+	case <-ctx.Done():
+		_ = resp.Body.Close()
+		cancel()
+		return nil, ctx.Err()
+	}
+	ref, err := url.Parse(rawEndpoint)
+	if err != nil {
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("parse sse endpoint %q: %w", rawEndpoint, err)
+	}
+
+	t := &SSETransport{
+		stream:   newStream(),
+		client:   client,
+		header:   header.Clone(),
+		endpoint: base.ResolveReference(ref).String(),
+		cancel:   cancel,
+	}
+	t.spawn(func() {
+		defer resp.Body.Close()
+		for {
+			ev, err := er.Next()
+			if err != nil {
+				t.shutdown(fmt.Errorf("upstream sse stream ended: %w", err))
+				return
+			}
+			if ev.Event != "" && ev.Event != "message" {
+				continue
+			}
+			if !t.deliver(json.RawMessage(ev.Data)) {
+				return
+			}
+		}
+	})
+	return t, nil
+}
+
+// Endpoint returns the resolved message endpoint announced by the upstream.
+func (t *SSETransport) Endpoint() string { return t.endpoint }
+
+func (t *SSETransport) Send(ctx context.Context, msg json.RawMessage) error {
+	if t.isClosed() {
+		return ErrClosed
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	setHeader(req, t.header)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post sse endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("sse endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (t *SSETransport) Close() error {
+	t.shutdown(ErrClosed)
+	t.cancel()
+	return nil
+}
+
+// setHeader adds header to req. A Host entry sets the request's Host, which
+// net/http otherwise takes from the URL.
+func setHeader(req *http.Request, header http.Header) {
+	for k, v := range header {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			if len(v) > 0 {
+				req.Host = v[0]
+			}
+			continue
+		}
+		req.Header[k] = v
+	}
+}
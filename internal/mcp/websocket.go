@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/websocket"
+)
+
+// WebSocketTransport carries one JSON-RPC message per websocket text frame.
+type WebSocketTransport struct {
+	*stream
+	conn *websocket.Conn
+}
+
+// DialWebSocket connects to a ws:// or wss:// MCP endpoint. A positive
+// pingInterval keeps the connection alive and detects dead peers.
+func DialWebSocket(ctx context.Context, rawURL string, header http.Header, maxMessageBytes int64, pingInterval time.Duration) (*WebSocketTransport, error) {
+	conn, err := websocket.Dial(ctx, rawURL, withSubprotocol(header))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(maxMessageBytes)
+
+	t := &WebSocketTransport{stream: newStream(), conn: conn}
+	t.spawn(func() {
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.shutdown(fmt.Errorf("upstream websocket closed: %w", err))
+				return
+			}
+			if !t.deliver(json.RawMessage(msg)) {
+				return
+			}
+		}
+	})
+	go func() {
+		if err := conn.KeepAlive(pingInterval); err != nil {
+			t.shutdown(fmt.Errorf("upstream websocket: %w", err))
+		}
+	}()
+	return t, nil
+}
+
+func withSubprotocol(header http.Header) http.Header {
+	h := header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	if h.Get("Sec-WebSocket-Protocol") == "" {
+		h.Set("Sec-WebSocket-Protocol", "mcp")
+	}
+	return h
+}
+
+func (t *WebSocketTransport) Send(_ context.Context, msg json.RawMessage) error {
+	if t.isClosed() {
+		return ErrClosed
+	}
+	return t.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+func (t *WebSocketTransport) Close() error {
+	t.shutdown(ErrClosed)
+	return t.conn.Close()
+}
@@ -0,0 +1,189 @@
+// Package mock is a scriptable MCP server for local development and tests.
+// Its tools, resources and prompts are declared in YAML, with responses
+// rendered from Go templates, and it can add latency and inject errors.
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config declares what the mock server offers.
+type Config struct {
+	Name         string `yaml:"name"`
+	Version      string `yaml:"version,omitempty"`
+	Instructions string `yaml:"instructions,omitempty"`
+	// LatencyMs delays every response unless an entry sets its own.
+	LatencyMs int `yaml:"latencyMs,omitempty"`
+	// Fault applies to every request unless an entry sets its own.
+	Fault *Fault `yaml:"fault,omitempty"`
+	// Data is exposed to templates as .data.
+	Data      map[string]any `yaml:"data,omitempty"`
+	Tools     []Tool         `yaml:"tools,omitempty"`
+	Resources []Resource     `yaml:"resources,omitempty"`
+	Prompts   []Prompt       `yaml:"prompts,omitempty"`
+}
+
+// Tool is a tool and its response. Text and Structured are templates over
+// .args and .data; without Text, the content is Structured as JSON.
+type Tool struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description,omitempty"`
+	InputSchema map[string]any `yaml:"inputSchema,omitempty"`
+	Text        string         `yaml:"text,omitempty"`
+	Structured  any            `yaml:"structured,omitempty"`
+	IsError     bool           `yaml:"isError,omitempty"`
+	LatencyMs   int            `yaml:"latencyMs,omitempty"`
+	Fault       *Fault         `yaml:"fault,omitempty"`
+}
+
+// Resource is a readable resource; Text is a template over .uri and .data.
+type Resource struct {
+	URI         string `yaml:"uri"`
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	MimeType    string `yaml:"mimeType,omitempty"`
+	Text        string `yaml:"text"`
+	LatencyMs   int    `yaml:"latencyMs,omitempty"`
+	Fault       *Fault `yaml:"fault,omitempty"`
+}
+
+// Prompt is a prompt template; message texts are templates over .args and
+// .data.
+type Prompt struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description,omitempty"`
+	Arguments   []PromptArgument `yaml:"arguments,omitempty"`
+	Messages    []PromptMessage  `yaml:"messages"`
+	LatencyMs   int              `yaml:"latencyMs,omitempty"`
+	Fault       *Fault           `yaml:"fault,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+type PromptMessage struct {
+	Role string `yaml:"role"`
+	Text string `yaml:"text"`
+}
+
+// Fault fails a share of requests. By default the failure is a JSON-RPC
+// error; with ToolError a tool call instead returns a result with isError.
+type Fault struct {
+	// Rate is the share of requests that fail, from 0 to 1.
+	Rate      float64 `yaml:"rate"`
+	Code      int     `yaml:"code,omitempty"`
+	Message   string  `yaml:"message,omitempty"`
+	ToolError bool    `yaml:"toolError,omitempty"`
+}
+
+// LoadFile loads and validates a mock config from a YAML file.
+func LoadFile(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mock config: %w", err)
+	}
+	return Load(b)
+}
+
+// Load parses and validates mock config YAML.
+func Load(b []byte) (*Config, error) {
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parse mock config yaml: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks names are set and unique and that values are in range.
+func (c Config) Validate() error {
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := validateTiming("mock", c.LatencyMs, c.Fault); err != nil {
+		return err
+	}
+	seen := map[string]struct{}{}
+	for _, t := range c.Tools {
+		if strings.TrimSpace(t.Name) == "" {
+			return fmt.Errorf("tools[].name is required")
+		}
+		if _, ok := seen[t.Name]; ok {
+			return fmt.Errorf("duplicate tool name: %s", t.Name)
+		}
+		seen[t.Name] = struct{}{}
+		if err := validateTiming("tool "+t.Name, t.LatencyMs, t.Fault); err != nil {
+			return err
+		}
+	}
+	seen = map[string]struct{}{}
+	for _, r := range c.Resources {
+		if strings.TrimSpace(r.URI) == "" {
+			return fmt.Errorf("resources[].uri is required")
+		}
+		if _, ok := seen[r.URI]; ok {
+			return fmt.Errorf("duplicate resource uri: %s", r.URI)
+		}
+		seen[r.URI] = struct{}{}
+		if err := validateTiming("resource "+r.URI, r.LatencyMs, r.Fault); err != nil {
+			return err
+		}
+	}
+	seen = map[string]struct{}{}
+	for _, p := range c.Prompts {
+		if strings.TrimSpace(p.Name) == "" {
+			return fmt.Errorf("prompts[].name is required")
+		}
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("duplicate prompt name: %s", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		for _, m := range p.Messages {
+			if m.Role != "user" && m.Role != "assistant" {
+				return fmt.Errorf("prompt %s message role must be user or assistant", p.Name)
+			}
+		}
+		if err := validateTiming("prompt "+p.Name, p.LatencyMs, p.Fault); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTiming(what string, latencyMs int, f *Fault) error {
+	if latencyMs < 0 {
+		return fmt.Errorf("%s latencyMs must be >= 0", what)
+	}
+	if f != nil && (f.Rate < 0 || f.Rate > 1) {
+		return fmt.Errorf("%s fault rate must be between 0 and 1", what)
+	}
+	return nil
+}
+
+// Default is served when no config is given: an echo tool.
+var Default = Config{
+	Name:    "mock",
+	Version: "0.1.0",
+	Tools: []Tool{{
+		Name:        "echo",
+		Description: "Returns its text argument.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"text": map[string]any{"type": "string"}},
+			"required":   []any{"text"},
+		},
+		Text: "{{ .args.text }}",
+	}},
+}
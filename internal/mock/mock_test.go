@@ -0,0 +1,132 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+const definition = `
+name: weather
+version: 1.2.3
+data:
+  conditions: {oslo: snow}
+tools:
+  - name: forecast
+    inputSchema: {type: object, required: [city]}
+    text: "{{ index .data.conditions (lower .args.city) }} in {{ .args.city }}"
+    structured: {city: "{{ .args.city }}", days: [1, 2]}
+  - name: broken
+    fault: {rate: 1, code: -32001, message: feed down}
+  - name: flaky
+    fault: {rate: 1, toolError: true}
+resources:
+  - {uri: "weather://stations", mimeType: application/json, text: "{{ toJson .data.conditions }}"}
+prompts:
+  - name: pack
+    arguments: [{name: city, required: true}]
+    messages: [{role: user, text: "Pack for {{ .args.city }}"}]
+`
+
+func TestMockServesDefinitionOverHTTPAndStdio(t *testing.T) {
+	cfg, err := Load([]byte(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := New(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	check := func(t *testing.T, client *mcp.Client) {
+		res, err := client.Initialize(ctx, "test", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ServerInfo.Version != "1.2.3" || len(res.Capabilities) != 3 {
+			t.Fatalf("unexpected initialize result: %+v", res)
+		}
+		tool, err := client.CallTool(ctx, "forecast", map[string]any{"city": "Oslo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(tool.Content[0]) != `{"text":"snow in Oslo","type":"text"}` || string(tool.StructuredContent) != `{"city":"Oslo","days":[1,2]}` {
+			t.Fatalf("unexpected forecast result: %s %s", tool.Content, tool.StructuredContent)
+		}
+		var rpcErr *mcp.Error
+		if _, err := client.CallTool(ctx, "forecast", nil); !errors.As(err, &rpcErr) || rpcErr.Code != mcp.CodeInvalidParams {
+			t.Fatalf("expected invalid params for a missing argument, got %v", err)
+		}
+		if _, err := client.CallTool(ctx, "broken", nil); !errors.As(err, &rpcErr) || rpcErr.Code != -32001 || rpcErr.Message != "feed down" {
+			t.Fatalf("expected the injected error, got %v", err)
+		}
+		if tool, err := client.CallTool(ctx, "flaky", nil); err != nil || !tool.IsError {
+			t.Fatalf("expected an isError tool result, got %+v, %v", tool, err)
+		}
+		raw, err := client.Call(ctx, "resources/read", map[string]any{"uri": "weather://stations"})
+		if err != nil || !json.Valid(raw) || string(raw) != `{"contents":[{"mimeType":"application/json","text":"{\"oslo\":\"snow\"}","uri":"weather://stations"}]}` {
+			t.Fatalf("unexpected resource: %s, %v", raw, err)
+		}
+		raw, err = client.Call(ctx, "prompts/get", map[string]any{"name": "pack", "arguments": map[string]any{"city": "Oslo"}})
+		if err != nil || string(raw) != `{"description":"","messages":[{"content":{"text":"Pack for Oslo","type":"text"},"role":"user"}]}` {
+			t.Fatalf("unexpected prompt: %s, %v", raw, err)
+		}
+	}
+
+	t.Run("http", func(t *testing.T) {
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+		transport := mcp.NewHTTPTransport(ts.URL+"/any/path", nil, nil)
+		defer transport.Close()
+		check(t, mcp.NewClient(transport))
+	})
+
+	t.Run("stdio", func(t *testing.T) {
+		inR, inW := io.Pipe()
+		outR, outW := io.Pipe()
+		go func() {
+			_ = server.ServeStdio(ctx, inR, outW)
+			outW.Close()
+		}()
+		defer inW.Close()
+		check(t, mcp.NewClient(newPipeTransport(inW, outR)))
+	})
+}
+
+// pipeTransport is a client transport over the stdio server's pipes.
+type pipeTransport struct {
+	w    io.Writer
+	msgs chan json.RawMessage
+}
+
+func newPipeTransport(w io.Writer, r io.Reader) *pipeTransport {
+	t := &pipeTransport{w: w, msgs: make(chan json.RawMessage, 16)}
+	go func() {
+		defer close(t.msgs)
+		dec := json.NewDecoder(r)
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return
+			}
+			t.msgs <- raw
+		}
+	}()
+	return t
+}
+
+func (t *pipeTransport) Send(_ context.Context, msg json.RawMessage) error {
+	_, err := t.w.Write(append(msg, '\n'))
+	return err
+}
+func (t *pipeTransport) Messages() <-chan json.RawMessage { return t.msgs }
+func (t *pipeTransport) Err() error                       { return nil }
+func (t *pipeTransport) Close() error                     { return nil }
@@ -0,0 +1,543 @@
+package mock
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+// supportedVersions are the protocol revisions the mock accepts from a
+// client; anything else is answered with mcp.ProtocolVersion.
+var supportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// Server answers MCP requests from a Config. It is safe for concurrent use
+// and holds no per-session state, so one Server can back many clients.
+type Server struct {
+	cfg       Config
+	tools     map[string]*compiledTool
+	resources map[string]*compiledResource
+	prompts   map[string]*compiledPrompt
+
+	mu   sync.Mutex
+	rand *mathrand.Rand
+}
+
+type compiledTool struct {
+	Tool
+	text       *template.Template
+	structured any // the config value with each string replaced by a template
+}
+
+type compiledResource struct {
+	Resource
+	text *template.Template
+}
+
+type compiledPrompt struct {
+	Prompt
+	texts []*template.Template
+}
+
+// New compiles the templates of cfg.
+func New(cfg Config) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:       cfg,
+		tools:     map[string]*compiledTool{},
+		resources: map[string]*compiledResource{},
+		prompts:   map[string]*compiledPrompt{},
+		rand:      mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+	}
+	for _, t := range cfg.Tools {
+		ct := &compiledTool{Tool: t}
+		var err error
+		if t.Text != "" {
+			if ct.text, err = parseTemplate("tool "+t.Name, t.Text); err != nil {
+				return nil, err
+			}
+		}
+		if ct.structured, err = compileValue("tool "+t.Name, t.Structured); err != nil {
+			return nil, err
+		}
+		s.tools[t.Name] = ct
+	}
+	for _, r := range cfg.Resources {
+		tmpl, err := parseTemplate("resource "+r.URI, r.Text)
+		if err != nil {
+			return nil, err
+		}
+		s.resources[r.URI] = &compiledResource{Resource: r, text: tmpl}
+	}
+	for _, p := range cfg.Prompts {
+		cp := &compiledPrompt{Prompt: p}
+		for i, m := range p.Messages {
+			tmpl, err := parseTemplate(fmt.Sprintf("prompt %s message %d", p.Name, i), m.Text)
+			if err != nil {
+				return nil, err
+			}
+			cp.texts = append(cp.texts, tmpl)
+		}
+		s.prompts[p.Name] = cp
+	}
+	return s, nil
+}
+
+// Handle answers one JSON-RPC message or batch. It returns nil when
+// nothing needs answering (notifications and responses).
+func (s *Server) Handle(ctx context.Context, raw json.RawMessage) json.RawMessage {
+	msgs, err := mcp.SplitBatch(raw)
+	if err != nil {
+		return mcp.ErrorResponse(nil, mcp.CodeParseError, err.Error())
+	}
+	isBatch := bytes.HasPrefix(bytes.TrimSpace(raw), []byte("["))
+	var replies []json.RawMessage
+	for _, m := range msgs {
+		if reply := s.handleOne(ctx, m); reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	switch {
+	case len(replies) == 0:
+		return nil
+	case !isBatch:
+		return replies[0]
+	}
+	b, _ := json.Marshal(replies)
+	return b
+}
+
+func (s *Server) handleOne(ctx context.Context, raw json.RawMessage) json.RawMessage {
+	msg, err := mcp.Parse(raw)
+	if err != nil {
+		return mcp.ErrorResponse(nil, mcp.CodeParseError, err.Error())
+	}
+	if !msg.IsRequest() {
+		return nil
+	}
+	result, rpcErr := s.dispatch(ctx, msg)
+	if rpcErr != nil {
+		b, _ := json.Marshal(mcp.Message{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr})
+		return b
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return mcp.ErrorResponse(msg.ID, mcp.CodeInternalError, err.Error())
+	}
+	out, _ := json.Marshal(mcp.Message{JSONRPC: "2.0", ID: msg.ID, Result: b})
+	return out
+}
+
+func (s *Server) dispatch(ctx context.Context, msg mcp.Message) (any, *mcp.Error) {
+	var params struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		Name            string         `json:"name"`
+		URI             string         `json:"uri"`
+		Arguments       map[string]any `json:"arguments"`
+	}
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &mcp.Error{Code: mcp.CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(params.ProtocolVersion), nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools := make([]map[string]any, 0, len(s.cfg.Tools))
+		for _, t := range s.cfg.Tools {
+			schema := t.InputSchema
+			if schema == nil {
+				schema = map[string]any{"type": "object"}
+			}
+			tools = append(tools, map[string]any{"name": t.Name, "description": t.Description, "inputSchema": schema})
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		t, ok := s.tools[params.Name]
+		if !ok {
+			return nil, &mcp.Error{Code: mcp.CodeInvalidParams, Message: "unknown tool: " + params.Name}
+		}
+		if f := s.wait(ctx, t.LatencyMs, t.Fault); f != nil {
+			if f.ToolError {
+				return toolResult(faultMessage(f), nil, true), nil
+			}
+			return nil, faultError(f)
+		}
+		return t.call(s.cfg.Data, params.Arguments)
+	case "resources/list":
+		resources := make([]map[string]any, 0, len(s.cfg.Resources))
+		for _, r := range s.cfg.Resources {
+			resources = append(resources, map[string]any{"uri": r.URI, "name": r.Name, "description": r.Description, "mimeType": r.MimeType})
+		}
+		return map[string]any{"resources": resources}, nil
+	case "resources/read":
+		r, ok := s.resources[params.URI]
+		if !ok {
+			return nil, &mcp.Error{Code: -32002, Message: "resource not found: " + params.URI}
+		}
+		if f := s.wait(ctx, r.LatencyMs, r.Fault); f != nil {
+			return nil, faultError(f)
+		}
+		text, err := render(r.text, map[string]any{"uri": r.URI, "data": s.cfg.Data})
+		if err != nil {
+			return nil, &mcp.Error{Code: mcp.CodeInternalError, Message: err.Error()}
+		}
+		return map[string]any{"contents": []map[string]any{{"uri": r.URI, "mimeType": r.MimeType, "text": text}}}, nil
+	case "prompts/list":
+		prompts := make([]map[string]any, 0, len(s.cfg.Prompts))
+		for _, p := range s.cfg.Prompts {
+			prompts = append(prompts, map[string]any{"name": p.Name, "description": p.Description, "arguments": p.Arguments})
+		}
+		return map[string]any{"prompts": prompts}, nil
+	case "prompts/get":
+		p, ok := s.prompts[params.Name]
+		if !ok {
+			return nil, &mcp.Error{Code: mcp.CodeInvalidParams, Message: "unknown prompt: " + params.Name}
+		}
+		if f := s.wait(ctx, p.LatencyMs, p.Fault); f != nil {
+			return nil, faultError(f)
+		}
+		return p.get(s.cfg.Data, params.Arguments)
+	default:
+		return nil, &mcp.Error{Code: mcp.CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+func (s *Server) initialize(requested string) map[string]any {
+	version := mcp.ProtocolVersion
+	if supportedVersions[requested] {
+		version = requested
+	}
+	capabilities := map[string]any{}
+	if len(s.cfg.Tools) > 0 {
+		capabilities["tools"] = map[string]any{}
+	}
+	if len(s.cfg.Resources) > 0 {
+		capabilities["resources"] = map[string]any{}
+	}
+	if len(s.cfg.Prompts) > 0 {
+		capabilities["prompts"] = map[string]any{}
+	}
+	result := map[string]any{
+		"protocolVersion": version,
+		"capabilities":    capabilities,
+		"serverInfo":      map[string]any{"name": s.cfg.Name, "version": s.cfg.Version},
+	}
+	if s.cfg.Instructions != "" {
+		result["instructions"] = s.cfg.Instructions
+	}
+	return result
+}
+
+// wait applies the entry's latency, falling back to the config's, and
+// returns the fault to inject, if any.
+func (s *Server) wait(ctx context.Context, latencyMs int, f *Fault) *Fault {
+	if latencyMs == 0 {
+		latencyMs = s.cfg.LatencyMs
+	}
+	if latencyMs > 0 {
+		timer := time.NewTimer(time.Duration(latencyMs) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	if f == nil {
+		f = s.cfg.Fault
+	}
+	if f == nil || f.Rate <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	roll := s.rand.Float64()
+	s.mu.Unlock()
+	if roll >= f.Rate {
+		return nil
+	}
+	return f
+}
+
+func faultMessage(f *Fault) string {
+	if f.Message != "" {
+		return f.Message
+	}
+	return "injected fault"
+}
+
+func faultError(f *Fault) *mcp.Error {
+	code := f.Code
+	if code == 0 {
+		code = mcp.CodeInternalError
+	}
+	return &mcp.Error{Code: code, Message: faultMessage(f)}
+}
+
+func (t *compiledTool) call(data map[string]any, args map[string]any) (any, *mcp.Error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	if required, ok := t.InputSchema["required"].([]any); ok {
+		for _, r := range required {
+			if name, _ := r.(string); name != "" {
+				if _, ok := args[name]; !ok {
+					return nil, &mcp.Error{Code: mcp.CodeInvalidParams, Message: "missing required argument: " + name}
+				}
+			}
+		}
+	}
+	vars := map[string]any{"args": args, "data": data}
+	structured, err := renderValue(t.structured, vars)
+	if err != nil {
+		return nil, &mcp.Error{Code: mcp.CodeInternalError, Message: err.Error()}
+	}
+	text := ""
+	if t.text != nil {
+		if text, err = render(t.text, vars); err != nil {
+			return nil, &mcp.Error{Code: mcp.CodeInternalError, Message: err.Error()}
+		}
+	} else if structured != nil {
+		b, _ := json.Marshal(structured)
+		text = string(b)
+	}
+	return toolResult(text, structured, t.IsError), nil
+}
+
+func toolResult(text string, structured any, isError bool) map[string]any {
+	result := map[string]any{"content": []map[string]any{{"type": "text", "text": text}}}
+	if structured != nil {
+		result["structuredContent"] = structured
+	}
+	if isError {
+		result["isError"] = true
+	}
+	return result
+}
+
+func (p *compiledPrompt) get(data map[string]any, args map[string]any) (any, *mcp.Error) {
+	for _, a := range p.Arguments {
+		if _, ok := args[a.Name]; a.Required && !ok {
+			return nil, &mcp.Error{Code: mcp.CodeInvalidParams, Message: "missing required argument: " + a.Name}
+		}
+	}
+	vars := map[string]any{"args": args, "data": data}
+	messages := make([]map[string]any, 0, len(p.texts))
+	for i, tmpl := range p.texts {
+		text, err := render(tmpl, vars)
+		if err != nil {
+			return nil, &mcp.Error{Code: mcp.CodeInternalError, Message: err.Error()}
+		}
+		messages = append(messages, map[string]any{
+			"role":    p.Messages[i].Role,
+			"content": map[string]any{"type": "text", "text": text},
+		})
+	}
+	return map[string]any{"description": p.Description, "messages": messages}, nil
+}
+
+// ServeHTTP speaks streamable HTTP on every path: POSTed messages are
+// answered with a JSON body. Sessions are issued but not tracked.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "the mock server does not offer a GET stream", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg, err := mcp.Parse(body); err == nil && msg.Method == "initialize" {
+		w.Header().Set(mcp.SessionHeader, newSessionID())
+	} else if id := r.Header.Get(mcp.SessionHeader); id != "" {
+		w.Header().Set(mcp.SessionHeader, id)
+	}
+	reply := s.Handle(r.Context(), body)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(reply)
+}
+
+// ServeStdio reads newline-delimited messages from in and writes replies
+// to out until in ends or ctx is done. Requests are answered concurrently,
+// so a slow tool does not hold up the rest.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	var (
+		wg      sync.WaitGroup
+		writeMu sync.Mutex
+	)
+	defer wg.Wait()
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		raw := append(json.RawMessage(nil), line...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := s.Handle(ctx, raw)
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			_, _ = out.Write(append(reply, '\n'))
+		}()
+	}
+	return scanner.Err()
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// templateFuncs are available in every response template.
+var templateFuncs = template.FuncMap{
+	"toJson": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"contains": strings.Contains,
+	// search returns the items of list whose fields contain query,
+	// ignoring case.
+	"search": func(list []any, query string, fields ...string) []any {
+		query = strings.ToLower(strings.TrimSpace(query))
+		matches := []any{}
+		if query == "" {
+			return matches
+		}
+		for _, item := range list {
+			m, _ := item.(map[string]any)
+			for _, f := range fields {
+				if strings.Contains(strings.ToLower(fmt.Sprint(m[f])), query) {
+					matches = append(matches, item)
+					break
+				}
+			}
+		}
+		return matches
+	},
+	// find returns the first item of list whose field equals value.
+	"find": func(list []any, field string, value any) any {
+		for _, item := range list {
+			if m, _ := item.(map[string]any); m != nil && fmt.Sprint(m[field]) == fmt.Sprint(value) {
+				return m
+			}
+		}
+		return nil
+	},
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func render(tmpl *template.Template, vars map[string]any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// compileValue replaces every string in v with its template.
+func compileValue(name string, v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return parseTemplate(name, v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			c, err := compileValue(name, e)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = c
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			c, err := compileValue(name, e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func renderValue(v any, vars map[string]any) (any, error) {
+	switch v := v.(type) {
+	case *template.Template:
+		return render(v, vars)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			r, err := renderValue(e, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			r, err := renderValue(e, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,230 @@
+// Package recording reads and writes JSONL recordings of a route's JSON-RPC
+// traffic, and replays them: against an upstream, diffing its responses, or
+// as a fake upstream.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directions of an Entry.
+const (
+	FromClient = "client" // sent to the upstream
+	FromServer = "server" // received from the upstream
+)
+
+// Entry is one recorded JSON-RPC message, one per line of a recording.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Route     string    `json:"route"`
+	Session   string    `json:"session,omitempty"`
+	Direction string    `json:"direction"`
+	// Stream is set for messages that arrived in an SSE stream.
+	Stream bool `json:"stream,omitempty"`
+	// ElapsedMs is, for a response, the time since its request was sent.
+	ElapsedMs float64         `json:"elapsedMs,omitempty"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// Read parses a recording.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// ReadFile parses the recording at path.
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Writer appends redacted entries to a recording file. It is safe for
+// concurrent use and times responses against their requests.
+type Writer struct {
+	redactor *Redactor
+
+	mu      sync.Mutex
+	f       *os.File
+	pending map[string]time.Time // session + request id -> sent
+}
+
+// Create opens path for appending, creating it and its directory if
+// needed. keys adds object keys to redact, as for NewRedactor.
+func Create(path string, keys []string) (*Writer, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create recording directory: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	return &Writer{redactor: NewRedactor(keys), f: f, pending: map[string]time.Time{}}, nil
+}
+
+// Write records raw, a message or batch, as entries like e.
+func (w *Writer) Write(e Entry, raw []byte) error {
+	msgs := []json.RawMessage{json.RawMessage(bytes.TrimSpace(raw))}
+	if bytes.HasPrefix(msgs[0], []byte("[")) {
+		if err := json.Unmarshal(msgs[0], &msgs); err != nil {
+			return fmt.Errorf("parse recorded batch: %w", err)
+		}
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, m := range msgs {
+		entry := e
+		entry.Message = w.redactor.Redact(m)
+		w.time(&entry)
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := w.f.Write(append(b, '\n')); err != nil {
+			return fmt.Errorf("write recording: %w", err)
+		}
+	}
+	return nil
+}
+
+// time remembers when requests were sent and sets ElapsedMs on responses.
+func (w *Writer) time(e *Entry) {
+	var m struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if json.Unmarshal(e.Message, &m) != nil || len(m.ID) == 0 || string(m.ID) == "null" {
+		return
+	}
+	key := e.Session + " " + string(m.ID)
+	switch {
+	case e.Direction == FromClient && m.Method != "":
+		w.pending[key] = e.Time
+	case e.Direction == FromServer && m.Method == "":
+		if sent, ok := w.pending[key]; ok {
+			e.ElapsedMs = float64(e.Time.Sub(sent).Microseconds()) / 1000
+			delete(w.pending, key)
+		}
+	}
+}
+
+// Close closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
+
+// Redacted replaces redacted values.
+const Redacted = "(redacted)"
+
+// secretKeys are always redacted, compared lowercase without '-' and '_'.
+var secretKeys = []string{"token", "secret", "password", "passwd", "apikey", "authorization", "credential", "credentials", "privatekey", "cookie"}
+
+// Redactor replaces the values of secret-looking object keys anywhere in
+// a message.
+type Redactor struct {
+	keys map[string]bool
+}
+
+// NewRedactor redacts keys that end in token, secret, password, apikey,
+// authorization, credential, privatekey or cookie, plus the given keys.
+// Keys are compared case-insensitively, ignoring '-' and '_'.
+func NewRedactor(keys []string) *Redactor {
+	r := &Redactor{keys: map[string]bool{}}
+	for _, k := range keys {
+		r.keys[normalizeKey(k)] = true
+	}
+	return r
+}
+
+func normalizeKey(k string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(k))
+}
+
+func (r *Redactor) redacts(key string) bool {
+	k := normalizeKey(key)
+	if r.keys[k] {
+		return true
+	}
+	// progressToken correlates notifications; it is not a secret.
+	if k == "progresstoken" {
+		return false
+	}
+	for _, s := range secretKeys {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns raw with secret values replaced. Invalid JSON is returned
+// unchanged.
+func (r *Redactor) Redact(raw json.RawMessage) json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return raw
+	}
+	if !r.walk(v) {
+		return raw
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+// walk redacts v in place, reporting whether anything changed.
+func (r *Redactor) walk(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if r.redacts(k) {
+				v[k] = Redacted
+				changed = true
+				continue
+			}
+			changed = r.walk(e) || changed
+		}
+	case []any:
+		for _, e := range v {
+			changed = r.walk(e) || changed
+		}
+	}
+	return changed
+}
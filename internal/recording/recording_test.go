@@ -0,0 +1,105 @@
+package recording
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/mock"
+)
+
+func TestRecordReplayAndServe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec", "weather.jsonl")
+	w, err := Create(path, []string{"city"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := time.Now()
+	for _, m := range []struct {
+		dir, raw string
+		at       time.Duration
+	}{
+		{FromClient, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{}}}`, 0},
+		{FromServer, `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},"serverInfo":{"name":"weather","version":"1"}}}`, time.Millisecond},
+		{FromClient, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, 2 * time.Millisecond},
+		{FromClient, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"forecast","arguments":{"day":"mon","apiToken":"s3cret"}}}`, 3 * time.Millisecond},
+		{FromServer, `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":7,"progress":1}}`, 4 * time.Millisecond},
+		{FromServer, `{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"sunny"}]}}`, 23 * time.Millisecond},
+	} {
+		if err := w.Write(Entry{Time: sent.Add(m.at), Route: "weather", Session: "s1", Direction: m.dir}, []byte(m.raw)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d", len(entries))
+	}
+	if got := string(entries[3].Message); !strings.Contains(got, `"apiToken":"(redacted)"`) || strings.Contains(got, "s3cret") {
+		t.Fatalf("secret not redacted: %s", got)
+	}
+	if got := string(entries[4].Message); !strings.Contains(got, `"progressToken":7`) {
+		t.Fatalf("progressToken should be kept: %s", got)
+	}
+	if entries[5].ElapsedMs != 20 {
+		t.Fatalf("expected the response 20ms after its request, got %v", entries[5].ElapsedMs)
+	}
+	xs := Exchanges(entries)
+	if len(xs) != 3 || xs[2].Method() != "tools/call" || len(xs[2].Notifications) != 1 || xs[2].Response == nil {
+		t.Fatalf("unexpected exchanges: %+v", xs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	replayAgainst := func(text string) ReplayResult {
+		t.Helper()
+		server, err := mock.New(mock.Config{Name: "weather", Version: "1", Tools: []mock.Tool{{Name: "forecast", Text: text}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		upstream := httptest.NewServer(server)
+		defer upstream.Close()
+		result, err := Replay(ctx, entries, func(context.Context) (mcp.Transport, error) {
+			return mcp.NewHTTPTransport(upstream.URL, nil, nil), nil
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if result := replayAgainst("sunny"); result.Sessions != 1 || result.Requests != 2 || len(result.Differences) != 0 {
+		t.Fatalf("expected a clean replay, got %+v", result)
+	}
+	result := replayAgainst("rain")
+	if len(result.Differences) != 1 || result.Differences[0].Path != "result.content[0].text" || result.Differences[0].Got != "rain" {
+		t.Fatalf("expected one text difference, got %+v", result.Differences)
+	}
+
+	fake := httptest.NewServer(NewUpstream(entries))
+	defer fake.Close()
+	transport := mcp.NewHTTPTransport(fake.URL+"/mcp", nil, nil)
+	defer transport.Close()
+	client := mcp.NewClient(transport)
+	var streamed []string
+	client.OnMessage = func(m mcp.Message) { streamed = append(streamed, m.Method) }
+	if _, err := client.Initialize(ctx, "test", ""); err != nil {
+		t.Fatal(err)
+	}
+	tool, err := client.CallTool(ctx, "forecast", map[string]any{"day": "tue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tool.Content) != 1 || string(tool.Content[0]) != `{"type":"text","text":"sunny"}` || len(streamed) != 1 {
+		t.Fatalf("unexpected replayed result %s with notifications %v", tool.Content, streamed)
+	}
+}
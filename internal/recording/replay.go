@@ -0,0 +1,250 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+// Exchange is a recorded request with the messages it produced.
+type Exchange struct {
+	Session string
+	Request Entry
+	// Notifications are the server notifications recorded between the
+	// request and its response in the same session.
+	Notifications []Entry
+	// Response is nil when the recording has no response.
+	Response *Entry
+	method   string
+	params   string // canonical JSON
+}
+
+// Method returns the request's method.
+func (x Exchange) Method() string { return x.method }
+
+// Exchanges pairs each recorded client request with its response. Client
+// notifications are returned as exchanges without a response.
+func Exchanges(entries []Entry) []*Exchange {
+	var out []*Exchange
+	open := map[string]*Exchange{}      // session + id -> exchange awaiting a response
+	waiting := map[string][]*Exchange{} // session -> exchanges awaiting a response, oldest first
+	for _, e := range entries {
+		msg, err := mcp.Parse(e.Message)
+		if err != nil {
+			continue
+		}
+		key := e.Session + " " + msg.IDKey()
+		switch {
+		case e.Direction == FromClient && msg.Method != "":
+			x := &Exchange{Session: e.Session, Request: e, method: msg.Method, params: canonical(msg.Params)}
+			out = append(out, x)
+			if msg.IsRequest() {
+				open[key] = x
+				waiting[e.Session] = append(waiting[e.Session], x)
+			}
+		case e.Direction == FromServer && msg.IsResponse():
+			if x, ok := open[key]; ok {
+				entry := e
+				x.Response = &entry
+				delete(open, key)
+				pending := waiting[e.Session]
+				for i, p := range pending {
+					if p == x {
+						waiting[e.Session] = append(pending[:i:i], pending[i+1:]...)
+						break
+					}
+				}
+			}
+		case e.Direction == FromServer && msg.IsNotification():
+			// Attribute it to the oldest request still waiting in its session.
+			if pending := waiting[e.Session]; len(pending) > 0 {
+				pending[0].Notifications = append(pending[0].Notifications, e)
+			}
+		}
+	}
+	return out
+}
+
+// canonical re-encodes JSON with sorted keys so equal params compare equal.
+func canonical(raw json.RawMessage) string {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// Difference is a response that no longer matches the recording.
+type Difference struct {
+	Session  string          `json:"session,omitempty"`
+	Method   string          `json:"method"`
+	ID       json.RawMessage `json:"id"`
+	Path     string          `json:"path"`
+	Recorded any             `json:"recorded"`
+	Got      any             `json:"got"`
+}
+
+func (d Difference) String() string {
+	rec, _ := json.Marshal(d.Recorded)
+	got, _ := json.Marshal(d.Got)
+	return fmt.Sprintf("%s id=%s %s: recorded %s, got %s", d.Method, d.ID, d.Path, rec, got)
+}
+
+// ReplayResult summarizes a replay.
+type ReplayResult struct {
+	Sessions    int          `json:"sessions"`
+	Requests    int          `json:"requests"`
+	Differences []Difference `json:"differences"`
+}
+
+// Replay resends each recorded session's client messages over a fresh
+// transport from dial, in order, and compares every response with the
+// recorded one. Object keys in ignore (e.g. "timestamp") are not compared.
+func Replay(ctx context.Context, entries []Entry, dial func(context.Context) (mcp.Transport, error), ignore []string) (ReplayResult, error) {
+	var result ReplayResult
+	ignored := map[string]bool{}
+	for _, k := range ignore {
+		ignored[k] = true
+	}
+	var order []string
+	bySession := map[string][]*Exchange{}
+	for _, x := range Exchanges(entries) {
+		if _, ok := bySession[x.Session]; !ok {
+			order = append(order, x.Session)
+		}
+		bySession[x.Session] = append(bySession[x.Session], x)
+	}
+	for _, session := range order {
+		result.Sessions++
+		t, err := dial(ctx)
+		if err != nil {
+			return result, fmt.Errorf("session %s: %w", session, err)
+		}
+		err = replaySession(ctx, t, bySession[session], ignored, &result)
+		_ = t.Close()
+		if err != nil {
+			return result, fmt.Errorf("session %s: %w", session, err)
+		}
+	}
+	return result, nil
+}
+
+func replaySession(ctx context.Context, t mcp.Transport, exchanges []*Exchange, ignored map[string]bool, result *ReplayResult) error {
+	for _, x := range exchanges {
+		msg, _ := mcp.Parse(x.Request.Message)
+		if err := t.Send(ctx, x.Request.Message); err != nil {
+			return fmt.Errorf("send %s: %w", x.method, err)
+		}
+		if !msg.IsRequest() {
+			continue
+		}
+		result.Requests++
+		got, err := awaitResponse(ctx, t, msg.IDKey())
+		if err != nil {
+			return fmt.Errorf("await %s response: %w", x.method, err)
+		}
+		if x.Response == nil {
+			continue
+		}
+		var recorded, actual any
+		_ = json.Unmarshal(x.Response.Message, &recorded)
+		_ = json.Unmarshal(got, &actual)
+		for _, d := range diff("", recorded, actual, ignored) {
+			d.Session, d.Method, d.ID = x.Session, x.method, msg.ID
+			result.Differences = append(result.Differences, d)
+		}
+	}
+	return nil
+}
+
+func awaitResponse(ctx context.Context, t mcp.Transport, id string) (json.RawMessage, error) {
+	for {
+		select {
+		case raw, ok := <-t.Messages():
+			if !ok {
+				if err := t.Err(); err != nil {
+					return nil, err
+				}
+				return nil, mcp.ErrClosed
+			}
+			if msg, err := mcp.Parse(raw); err == nil && msg.IsResponse() && msg.IDKey() == id {
+				return raw, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// diff lists the paths where got differs from recorded. Values recorded as
+// redacted match anything.
+func diff(path string, recorded, got any, ignored map[string]bool) []Difference {
+	if recorded == Redacted {
+		return nil
+	}
+	switch r := recorded.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range r {
+			keys[k] = true
+		}
+		for k := range g {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			// The top-level id is the replayed request's own.
+			if !ignored[k] && (path != "" || k != "id") {
+				sorted = append(sorted, k)
+			}
+		}
+		sort.Strings(sorted)
+		var out []Difference
+		for _, k := range sorted {
+			out = append(out, diff(joinPath(path, k), r[k], g[k], ignored)...)
+		}
+		return out
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(r) {
+			break
+		}
+		var out []Difference
+		for i := range r {
+			out = append(out, diff(fmt.Sprintf("%s[%d]", path, i), r[i], g[i], ignored)...)
+		}
+		return out
+	default:
+		if reflect.DeepEqual(recorded, got) {
+			return nil
+		}
+	}
+	if path == "" {
+		path = "."
+	}
+	return []Difference{{Path: path, Recorded: recorded, Got: got}}
+}
+
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		key = fmt.Sprintf("%q", key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
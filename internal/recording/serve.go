@@ -0,0 +1,169 @@
+package recording
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+// Upstream serves a recording as a streamable HTTP MCP server on every path.
+// Each request is answered with the recorded response to the same method
+// and params, taking unused exchanges first and falling back to the method
+// alone. Notifications recorded before a response are streamed ahead of it
+// as SSE when the client accepts it.
+type Upstream struct {
+	// Timing delays each response by its recorded elapsed time.
+	Timing bool
+
+	mu        sync.Mutex
+	exchanges []*Exchange
+	used      map[*Exchange]bool
+}
+
+// NewUpstream serves the exchanges of entries.
+func NewUpstream(entries []Entry) *Upstream {
+	u := &Upstream{used: map[*Exchange]bool{}}
+	for _, x := range Exchanges(entries) {
+		if x.Response != nil {
+			u.exchanges = append(u.exchanges, x)
+		}
+	}
+	return u
+}
+
+// match picks the exchange that answers a request.
+func (u *Upstream) match(method, params string) *Exchange {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var sameMethod, reuse *Exchange
+	for _, x := range u.exchanges {
+		if x.method != method {
+			continue
+		}
+		if !u.used[x] && x.params == params {
+			u.used[x] = true
+			return x
+		}
+		if sameMethod == nil && !u.used[x] {
+			sameMethod = x
+		}
+		if reuse == nil || x.params == params {
+			reuse = x
+		}
+	}
+	if sameMethod != nil {
+		u.used[sameMethod] = true
+		return sameMethod
+	}
+	return reuse
+}
+
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "a replayed upstream does not offer a GET stream", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	msgs, err := mcp.SplitBatch(body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write(mcp.ErrorResponse(nil, mcp.CodeParseError, err.Error()))
+		return
+	}
+
+	var replies, notifications []json.RawMessage
+	var delay time.Duration
+	for _, raw := range msgs {
+		msg, err := mcp.Parse(raw)
+		if err != nil {
+			replies = append(replies, mcp.ErrorResponse(nil, mcp.CodeParseError, err.Error()))
+			continue
+		}
+		if !msg.IsRequest() {
+			continue
+		}
+		if msg.Method == "initialize" {
+			w.Header().Set(mcp.SessionHeader, newSessionID())
+		}
+		x := u.match(msg.Method, canonical(msg.Params))
+		if x == nil {
+			replies = append(replies, mcp.ErrorResponse(msg.ID, mcp.CodeInternalError, fmt.Sprintf("no recorded response for %s", msg.Method)))
+			continue
+		}
+		for _, n := range x.Notifications {
+			notifications = append(notifications, n.Message)
+		}
+		replies = append(replies, withID(x.Response.Message, msg.ID))
+		if d := time.Duration(x.Response.ElapsedMs * float64(time.Millisecond)); u.Timing && d > delay {
+			delay = d
+		}
+	}
+	if id := r.Header.Get(mcp.SessionHeader); id != "" && w.Header().Get(mcp.SessionHeader) == "" {
+		w.Header().Set(mcp.SessionHeader, id)
+	}
+	if len(replies) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if len(notifications) > 0 && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, m := range append(notifications, replies...) {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", m)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(replies) == 1 && !strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		_, _ = w.Write(replies[0])
+		return
+	}
+	b, _ := json.Marshal(replies)
+	_, _ = w.Write(b)
+}
+
+// withID returns a recorded response answering request id instead.
+func withID(raw json.RawMessage, id json.RawMessage) json.RawMessage {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return raw
+	}
+	m["id"] = id
+	b, err := json.Marshal(m)
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+// credentialError rejects a session whose opening request lacks a required
+// credential.
+type credentialError struct{ msg string }
+
+func (e *credentialError) Error() string { return e.msg }
+
+// sessionErrorStatus maps an openSession error to an HTTP status.
+func sessionErrorStatus(err error) int {
+	var ce *credentialError
+	if errors.As(err, &ce) {
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadGateway
+}
+
+func sessionErrorMessage(err error) string {
+	if sessionErrorStatus(err) == http.StatusUnauthorized {
+		return err.Error()
+	}
+	return "upstream error: " + err.Error()
+}
+
+// sessionCredentials extracts the server's credentials from the request that
+// opens a session, as env entries and initialize params by dotted path.
+func sessionCredentials(server config.Server, r *http.Request) ([]string, map[string]string, error) {
+	var env []string
+	params := map[string]string{}
+	for _, c := range server.Credentials {
+		var (
+			value  string
+			source string
+		)
+		if c.FromHeader != "" {
+			value, source = r.Header.Get(c.FromHeader), "header "+c.FromHeader
+		} else {
+			value, source = bearerClaim(r, c.FromClaim), "token claim "+c.FromClaim
+		}
+		if value == "" {
+			if c.Optional {
+				continue
+			}
+			return nil, nil, &credentialError{msg: fmt.Sprintf("server %s requires %s", server.Name, source)}
+		}
+		if c.ToEnv != "" {
+			env = append(env, c.ToEnv+"="+value)
+		} else {
+			params[c.ToInitParam] = value
+		}
+	}
+	return env, params, nil
+}
+
+// bearerClaim returns a top-level claim of the request's bearer JWT, with
+// non-string claims as JSON. The signature is not checked: gateway serve
+// does not verify tokens. Validation allows fromClaim only on servers whose
+// routes all use jwt auth, which Envoy verifies in front of the gateway.
+func bearerClaim(r *http.Request, name string) string {
+	token, ok := strings.CutPrefix(strings.TrimSpace(r.Header.Get("Authorization")), "Bearer ")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	raw, ok := claims[name]
+	if !ok {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// initParamsTransport adds credential values to the session's initialize
+// request before it reaches the upstream.
+type initParamsTransport struct {
+	mcp.Transport
+	params map[string]string
+}
+
+func (t *initParamsTransport) Send(ctx context.Context, raw json.RawMessage) error {
+	msg, err := mcp.Parse(raw)
+	if err != nil || msg.Method != "initialize" {
+		return t.Transport.Send(ctx, raw)
+	}
+	params := map[string]any{}
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return fmt.Errorf("initialize params: %w", err)
+		}
+	}
+	for path, value := range t.params {
+		node := params
+		keys := strings.Split(path, ".")
+		for _, k := range keys[:len(keys)-1] {
+			child, ok := node[k].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[k] = child
+			}
+			node = child
+		}
+		node[keys[len(keys)-1]] = value
+	}
+	if msg.Params, err = json.Marshal(params); err != nil {
+		return err
+	}
+	if raw, err = json.Marshal(msg); err != nil {
+		return err
+	}
+	return t.Transport.Send(ctx, raw)
+}
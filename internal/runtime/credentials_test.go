@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+func TestStdioEnvAndCredentials(t *testing.T) {
+	secrets := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(secrets, "github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(secrets, "github", "token"), []byte("from-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+
+	server := helperStdioServer(t, "git")
+	server.WorkingDir = workDir
+	server.Env = []config.EnvVar{{Name: "TEST_TOKEN", SecretRef: &config.SecretRef{Name: "github", Key: "token"}}}
+	withCredentials := server
+	withCredentials.Name = "git-per-user"
+	withCredentials.Env = nil
+	withCredentials.Credentials = []config.Credential{
+		{FromHeader: "X-GitHub-Token", ToEnv: "TEST_TOKEN"},
+		{FromClaim: "sub", ToInitParam: "_meta.user"},
+	}
+	gw := httptest.NewServer(http.HandlerFunc(NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0", SecretsDir: secrets},
+		Servers: []config.Server{server, withCredentials},
+		Routes: []config.Route{
+			{Name: "shared", Path: "/mcp/shared", Server: "git"},
+			{Name: "personal", Path: "/mcp/personal", Server: "git-per-user"},
+		},
+	}).handleRequest))
+	defer gw.Close()
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`
+	body := string(mustReadAll(t, postJSON(t, gw.URL+"/mcp/shared", "", initialize).Body))
+	if !strings.Contains(body, `"token":"from-secret"`) || !strings.Contains(body, `"cwd":"`+workDir+`"`) {
+		t.Fatalf("expected secret env and working directory, got %s", body)
+	}
+
+	if resp := postJSON(t, gw.URL+"/mcp/personal", "", initialize); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without the credential header, got %d", resp.StatusCode)
+	}
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`))
+	req, _ := http.NewRequest(http.MethodPost, gw.URL+"/mcp/personal", strings.NewReader(initialize))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Token", "ghp_alice")
+	req.Header.Set("Authorization", "Bearer e30."+claims+".sig")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body = string(mustReadAll(t, resp.Body))
+	if !strings.Contains(body, `"token":"ghp_alice"`) || !strings.Contains(body, `"_meta":{"user":"alice"}`) ||
+		!strings.Contains(body, `"protocolVersion":"2025-06-18"`) {
+		t.Fatalf("expected per-request credentials in env and initialize params, got %s", body)
+	}
+}
@@ -0,0 +1,89 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metrics is a minimal Prometheus-compatible counter registry.
+type metrics struct {
+	mu       sync.Mutex
+	counters map[string]map[string]uint64
+	help     map[string]string
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		counters: map[string]map[string]uint64{},
+		help: map[string]string{
+			"gateway_requests_total":       "HTTP requests matched to a route.",
+			"gateway_messages_total":       "JSON-RPC messages relayed on bridged sessions.",
+			"gateway_policy_denials_total": "Client messages rejected by route policy.",
+		},
+	}
+}
+
+// inc increments name for the given label key/value pairs.
+func (m *metrics) inc(name string, labels ...string) {
+	key := seriesKey(labels)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	series, ok := m.counters[name]
+	if !ok {
+		series = map[string]uint64{}
+		m.counters[name] = series
+	}
+	series[key]++
+}
+
+func (m *metrics) get(name string, labels ...string) uint64 {
+	key := seriesKey(labels)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[name][key]
+}
+
+func seriesKey(labels []string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(labels); i += 2 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", labels[i], labels[i+1])
+	}
+	return b.String()
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.counters))
+	for name := range m.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if help := m.help[name]; help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+		}
+		fmt.Fprintf(w, "# TYPE %s counter\n", name)
+		series := m.counters[name]
+		keys := make([]string, 0, len(series))
+		for k := range series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, k, series[k])
+		}
+	}
+}
+
+func (m *metrics) handler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.writeTo(w)
+}
@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+// codePolicyDenied is the JSON-RPC error code for messages the gateway refuses.
+const codePolicyDenied = -32001
+
+// admitMessage applies route policy to a single client message. When the
+// message is refused, the returned response (nil for notifications) is what
+// the client should receive instead.
+func (s *Server) admitMessage(route config.Route, raw json.RawMessage) (json.RawMessage, bool) {
+	msg, err := mcp.Parse(raw)
+	if err != nil {
+		return mcp.ErrorResponse(nil, mcp.CodeParseError, err.Error()), false
+	}
+	deny := func(reason, message string) (json.RawMessage, bool) {
+		s.metrics.inc("gateway_policy_denials_total", "route", route.Name, "reason", reason)
+		if !msg.IsRequest() {
+			return nil, false
+		}
+		return mcp.ErrorResponse(msg.ID, codePolicyDenied, message), false
+	}
+
+	if msg.Method == "tools/call" && len(route.Policy.AllowedTools) > 0 {
+		var params struct {
+			Name string `json:"name"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		if !containsString(route.Policy.AllowedTools, params.Name) {
+			return deny("tool", fmt.Sprintf("tool %q is not allowed on route %q", params.Name, route.Name))
+		}
+	}
+	return nil, true
+}
+
+// admitBody applies admitMessage to every message in an HTTP request body and
+// returns the first refusal.
+func (s *Server) admitBody(route config.Route, body []byte) (json.RawMessage, bool) {
+	if len(route.Policy.AllowedTools) == 0 {
+		return nil, true
+	}
+	raws, err := mcp.SplitBatch(body)
+	if err != nil {
+		return nil, true
+	}
+	for _, raw := range raws {
+		if resp, ok := s.admitMessage(route, raw); !ok {
+			if resp == nil {
+				resp = mcp.ErrorResponse(nil, codePolicyDenied, "message refused by route policy")
+			}
+			return resp, false
+		}
+	}
+	return nil, true
+}
+
+// liveRoute returns route as the running config has it now. It reports
+// false when a reload removed the route or changed its auth, which ends
+// connections authenticated under the old rules.
+func (s *Server) liveRoute(route config.Route) (config.Route, bool) {
+	for _, r := range s.state.Load().routes {
+		if r.Name == route.Name {
+			return r, reflect.DeepEqual(r.Auth, route.Auth)
+		}
+	}
+	return config.Route{}, false
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/recording"
+)
+
+// maxRecordedBody bounds how much of a JSON response is buffered for the
+// recording; larger bodies are passed through unrecorded.
+const maxRecordedBody = 16 << 20
+
+// recorders opens each recorded route's file on first use.
+type recorders struct {
+	mu      sync.Mutex
+	entries map[string]recorder
+}
+
+// recorder is a route's writer and the record block it was opened for; w
+// is nil when the file could not be opened.
+type recorder struct {
+	record config.Record
+	w      *recording.Writer
+}
+
+func newRecorders() *recorders {
+	return &recorders{entries: map[string]recorder{}}
+}
+
+// get returns the route's writer, or nil when the route is not recorded or
+// its file cannot be opened. A writer opened for a different record block
+// is closed and replaced.
+func (rs *recorders) get(route config.Route) *recording.Writer {
+	if route.Record == nil {
+		return nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if e, ok := rs.entries[route.Name]; ok {
+		if reflect.DeepEqual(e.record, *route.Record) {
+			return e.w
+		}
+		rs.close(route.Name)
+	}
+	w, err := recording.Create(route.Record.File, route.Record.Redact)
+	if err != nil {
+		log.Printf("record_error route=%s err=%q", route.Name, err)
+	} else {
+		log.Printf("record_start route=%s file=%s", route.Name, route.Record.File)
+	}
+	rs.entries[route.Name] = recorder{record: *route.Record, w: w}
+	return w
+}
+
+// prune closes the writers of routes that routes no longer records, or
+// records under a different block, so a reload stops writing the old file.
+func (rs *recorders) prune(routes []config.Route) {
+	current := map[string]*config.Record{}
+	for _, r := range routes {
+		current[r.Name] = r.Record
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for name, e := range rs.entries {
+		if r := current[name]; r == nil || !reflect.DeepEqual(e.record, *r) {
+			rs.close(name)
+		}
+	}
+}
+
+// close closes and forgets the route's writer; rs.mu must be held.
+func (rs *recorders) close(name string) {
+	if w := rs.entries[name].w; w != nil {
+		_ = w.Close()
+		log.Printf("record_stop route=%s file=%s", name, rs.entries[name].record.File)
+	}
+	delete(rs.entries, name)
+}
+
+func record(w *recording.Writer, e recording.Entry, raw []byte) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return
+	}
+	// Sessions opened before a reload may still hold a writer it closed.
+	if err := w.Write(e, raw); err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Printf("record_error route=%s err=%q", e.Route, err)
+	}
+}
+
+// recordingTransport records what a gateway-held session exchanges with
+// its upstream.
+type recordingTransport struct {
+	mcp.Transport
+	w       *recording.Writer
+	route   string
+	session string
+	stream  bool
+
+	msgs      chan json.RawMessage
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newRecordingTransport(t mcp.Transport, w *recording.Writer, route string, stream bool) *recordingTransport {
+	return &recordingTransport{
+		Transport: t,
+		w:         w,
+		route:     route,
+		stream:    stream,
+		msgs:      make(chan json.RawMessage),
+		done:      make(chan struct{}),
+	}
+}
+
+// run starts relaying upstream messages once the session id is known.
+func (t *recordingTransport) run(session string) {
+	t.session = session
+	go func() {
+		defer close(t.msgs)
+		for raw := range t.Transport.Messages() {
+			record(t.w, recording.Entry{Route: t.route, Session: t.session, Direction: recording.FromServer, Stream: t.stream}, raw)
+			select {
+			case t.msgs <- raw:
+			case <-t.done:
+				return
+			}
+		}
+	}()
+}
+
+func (t *recordingTransport) Send(ctx context.Context, raw json.RawMessage) error {
+	record(t.w, recording.Entry{Route: t.route, Session: t.session, Direction: recording.FromClient}, raw)
+	return t.Transport.Send(ctx, raw)
+}
+
+func (t *recordingTransport) Messages() <-chan json.RawMessage { return t.msgs }
+
+func (t *recordingTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	return t.Transport.Close()
+}
+
+// recordProxied records a reverse-proxied exchange: the request body now,
+// and the response as it is read by the client.
+func recordProxied(w *recording.Writer, route string, reqBody []byte, sent time.Time, resp *http.Response) {
+	session := resp.Request.Header.Get(mcp.SessionHeader)
+	if session == "" {
+		session = resp.Header.Get(mcp.SessionHeader)
+	}
+	record(w, recording.Entry{Time: sent, Route: route, Session: session, Direction: recording.FromClient}, reqBody)
+
+	entry := recording.Entry{Route: route, Session: session, Direction: recording.FromServer}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		pr, pw := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			er := mcp.NewEventReader(pr)
+			for {
+				ev, err := er.Next()
+				if err != nil {
+					_ = pr.CloseWithError(err)
+					return
+				}
+				if ev.Event == "" || ev.Event == "message" {
+					e := entry
+					e.Stream = true
+					record(w, e, ev.Data)
+				}
+			}
+		}()
+		resp.Body = &teeReadCloser{rc: resp.Body, onData: func(b []byte) { _, _ = pw.Write(b) }, onClose: func() {
+			_ = pw.Close()
+			<-done
+		}}
+		return
+	}
+	var buf bytes.Buffer
+	resp.Body = &teeReadCloser{
+		rc: resp.Body,
+		onData: func(b []byte) {
+			if buf.Len() <= maxRecordedBody {
+				buf.Write(b)
+			}
+		},
+		onClose: func() {
+			if buf.Len() <= maxRecordedBody {
+				record(w, entry, buf.Bytes())
+			}
+		},
+	}
+}
+
+// teeReadCloser passes what is read to onData and calls onClose once, at
+// EOF or Close.
+type teeReadCloser struct {
+	rc      io.ReadCloser
+	onData  func([]byte)
+	onClose func()
+	once    sync.Once
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.rc.Read(p)
+	if n > 0 {
+		t.onData(p[:n])
+	}
+	if err != nil {
+		t.once.Do(t.onClose)
+	}
+	return n, err
+}
+
+func (t *teeReadCloser) Close() error {
+	t.once.Do(t.onClose)
+	return t.rc.Close()
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/recording"
+)
+
+func TestRecordedRoutes(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progressToken\":1}}\n\n" +
+			"event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"ok\":true}}\n\n"))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	proxied := filepath.Join(dir, "remote.jsonl")
+	held := filepath.Join(dir, "local.jsonl")
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "remote", Transport: "http", URL: upstream.URL}, helperStdioServer(t, "local")},
+		Routes: []config.Route{
+			{Name: "remote", Path: "/mcp/remote", Server: "remote", Record: &config.Record{File: proxied}},
+			{Name: "local", Path: "/mcp/local", Server: "local", Record: &config.Record{File: held, Redact: []string{"path"}}},
+		},
+	}
+	s := NewServer(cfg)
+	gw := httptest.NewServer(http.HandlerFunc(s.handleRequest))
+	defer gw.Close()
+
+	resp := postJSON(t, gw.URL+"/mcp/remote", "", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"x","arguments":{"password":"hunter2"}}}`)
+	mustReadAll(t, resp.Body)
+	entries, err := recording.ReadFile(proxied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Direction != recording.FromClient || !entries[2].Stream || entries[2].ElapsedMs <= 0 {
+		t.Fatalf("unexpected proxied recording: %+v", entries)
+	}
+	if strings.Contains(string(entries[0].Message), "hunter2") {
+		t.Fatalf("password recorded: %s", entries[0].Message)
+	}
+
+	// A reload that moves the recording closes the old file.
+	moved := filepath.Join(dir, "moved.jsonl")
+	next := *cfg
+	next.Routes = append([]config.Route(nil), cfg.Routes...)
+	next.Routes[0].Record = &config.Record{File: moved}
+	s.Reload(&next)
+	resp = postJSON(t, gw.URL+"/mcp/remote", "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	mustReadAll(t, resp.Body)
+	if entries, err := recording.ReadFile(proxied); err != nil || len(entries) != 3 {
+		t.Fatalf("expected the old recording left alone, got %d entries err=%v", len(entries), err)
+	}
+	if entries, err := recording.ReadFile(moved); err != nil || len(entries) != 3 {
+		t.Fatalf("expected the exchange in the new recording, got %d entries err=%v", len(entries), err)
+	}
+
+	resp = postJSON(t, gw.URL+"/mcp/local", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	sessionID := resp.Header.Get(mcp.SessionHeader)
+	resp = postJSON(t, gw.URL+"/mcp/local", sessionID, `{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"path":"/etc/hosts"}}`)
+	mustReadAll(t, resp.Body)
+	entries, err = recording.ReadFile(held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[3].Session != sessionID || entries[3].Direction != recording.FromServer {
+		t.Fatalf("unexpected session recording: %+v", entries)
+	}
+	if strings.Contains(string(entries[2].Message), "/etc/hosts") || strings.Contains(string(entries[3].Message), "/etc/hosts") {
+		t.Fatalf("configured key not redacted: %s %s", entries[2].Message, entries[3].Message)
+	}
+}
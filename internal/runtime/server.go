@@ -2,7 +2,6 @@ package runtime
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -12,32 +11,120 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/websocket"
 )
 
 // Server runs the local gateway HTTP runtime.
 type Server struct {
-	cfg       *config.Config
-	routes    []config.Route
+	state     atomic.Pointer[serverState]
 	logBodies bool
+	client    *http.Client
+	sessions  *sessionStore
+	metrics   *metrics
+	stdio     *stdioRegistry
+	recorders *recorders
 }
 
-func NewServer(cfg *config.Config) *Server {
-	routes := append([]config.Route(nil), cfg.Routes...)
-	sort.Slice(routes, func(i, j int) bool {
+// serverState is the config a Server is running, swapped as a whole on
+// reload.
+type serverState struct {
+	cfg *config.Config
+	// routes carry their effective auth and policy, longest path first.
+	routes []config.Route
+}
+
+func newServerState(cfg *config.Config) *serverState {
+	routes := make([]config.Route, len(cfg.Routes))
+	for i, r := range cfg.Routes {
+		routes[i] = cfg.ResolveRoute(r)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
 		return len(routes[i].Path) > len(routes[j].Path)
 	})
-	return &Server{
-		cfg:       cfg,
-		routes:    routes,
+	return &serverState{cfg: cfg, routes: routes}
+}
+
+func NewServer(cfg *config.Config) *Server {
+	s := &Server{
 		logBodies: strings.EqualFold(os.Getenv("GATEWAY_LOG_BODIES"), "true"),
+		client:    &http.Client{},
+		sessions:  newSessionStore(),
+		metrics:   newMetrics(),
+		stdio:     newStdioRegistry(),
+		recorders: newRecorders(),
 	}
+	s.state.Store(newServerState(cfg))
+	return s
 }
 
-func (s *Server) ListenAndServe() error {
+// config returns the config the server is running.
+func (s *Server) config() *config.Config {
+	return s.state.Load().cfg
+}
+
+// Reload switches the server to cfg. Requests already in flight and open
+// sessions keep the route they started with, and sessions on removed routes
+// are closed, as are recordings the new config drops or moves; listen and admin addresses only change on restart.
+func (s *Server) Reload(cfg *config.Config) {
+	old := s.state.Swap(newServerState(cfg)).cfg
+	routes := map[string]bool{}
+	for _, r := range cfg.Routes {
+		routes[r.Name] = true
+	}
+	s.sessions.closeWhere(func(sess *session) bool { return !routes[sess.route] })
+	s.recorders.prune(cfg.Routes)
+	if old.Gateway.ListenAddr != cfg.Gateway.ListenAddr || old.Gateway.AdminAddr != cfg.Gateway.AdminAddr {
+		log.Printf("config_reload_warning msg=%q", "listenAddr and adminAddr changes take effect on restart")
+	}
+	log.Printf("config_reloaded servers=%d routes=%d", len(cfg.Servers), len(cfg.Routes))
+}
+
+// Handler serves MCP routes plus the health endpoints, as on
+// gateway.listenAddr. Metrics are only served on the admin listener.
+func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
+	s.registerHealth(mux)
+	mux.HandleFunc("/", s.handleRequest)
+	return loggingMiddleware(mux)
+}
+
+func (s *Server) ListenAndServe() error {
+	cfg := s.config()
+	server := &http.Server{
+		Addr:              cfg.Gateway.ListenAddr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	errs := make(chan error, 2)
+	if addr := cfg.Gateway.AdminAddr; strings.TrimSpace(addr) != "" && addr != cfg.Gateway.ListenAddr {
+		// Probes and scrapers hit the admin listener so they stay off the
+		// MCP traffic port and out of its access logs.
+		admin := http.NewServeMux()
+		s.registerHealth(admin)
+		// Only here: metrics and stderr can carry route names and secrets,
+		// and the MCP port faces clients.
+		admin.HandleFunc("/metrics", s.metrics.handler)
+		admin.HandleFunc("/admin/stdio", s.stdio.handler)
+		adminServer := &http.Server{Addr: addr, Handler: admin, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			log.Printf("gateway %q admin listening on %s", cfg.Gateway.Name, addr)
+			errs <- adminServer.ListenAndServe()
+		}()
+	}
+	go func() {
+		log.Printf("gateway %q listening on %s", cfg.Gateway.Name, cfg.Gateway.ListenAddr)
+		errs <- server.ListenAndServe()
+	}()
+	return <-errs
+}
+
+// registerHealth adds the health endpoints to mux.
+func (s *Server) registerHealth(mux *http.ServeMux) {
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok\n"))
@@ -46,20 +133,10 @@ func (s *Server) ListenAndServe() error {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ready\n"))
 	})
-	mux.HandleFunc("/", s.handleRequest)
-
-	h := loggingMiddleware(mux)
-	server := &http.Server{
-		Addr:              s.cfg.Gateway.ListenAddr,
-		Handler:           h,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
-	log.Printf("gateway %q listening on %s", s.cfg.Gateway.Name, s.cfg.Gateway.ListenAddr)
-	return server.ListenAndServe()
 }
 
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
-	route, ok := s.matchRoute(r.URL.Path)
+	route, ok := s.matchRoute(r.Host, r.URL.Path)
 	if !ok {
 		http.NotFound(w, r)
 		return
@@ -69,37 +146,67 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	reqBodyPreview, reqBodyTruncated := s.captureRequestBody(r)
-	if s.logBodies && reqBodyPreview != "" {
-		log.Printf("mcp_request route=%s path=%s body=%q truncated=%t", route.Name, r.URL.Path, reqBodyPreview, reqBodyTruncated)
-	}
+	s.metrics.inc("gateway_requests_total", "route", route.Name, "method", r.Method)
 	server, ok := s.lookupServer(route.Server)
 	if !ok {
 		http.Error(w, "route server not found", http.StatusBadGateway)
 		return
 	}
+	if route.AcceptsClientTransport("websocket") && r.URL.Path == route.Path && websocket.IsUpgrade(r) {
+		s.serveWebSocket(route, server, w, r)
+		return
+	}
+	body, reqBodyPreview, reqBodyTruncated := s.captureRequestBody(r)
+	if s.logBodies && reqBodyPreview != "" {
+		log.Printf("mcp_request route=%s path=%s body=%q truncated=%t", route.Name, r.URL.Path, reqBodyPreview, reqBodyTruncated)
+	}
+	if resp, ok := s.admitBody(route, body); !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write(resp)
+		return
+	}
+
+	if route.AcceptsClientTransport("sse") {
+		switch r.URL.Path {
+		case route.Path + "/sse":
+			s.serveLegacyStream(route, server, w, r)
+			return
+		case route.Path + "/messages":
+			s.serveLegacyMessage(route, w, r)
+			return
+		}
+	}
 
+	if !route.AcceptsClientTransport("http") {
+		http.Error(w, "route does not accept streamable HTTP clients", http.StatusNotFound)
+		return
+	}
 	switch server.Transport {
 	case "http":
-		s.proxyHTTP(route, server.URL, w, r)
-	case "stdio":
-		s.respondNotImplemented(route, server, w)
+		s.proxyHTTP(route, server.URL, body, w, r)
+	case "sse", "websocket", "stdio":
+		s.bridgeSession(route, server, w, r)
 	default:
 		http.Error(w, "unsupported server transport", http.StatusBadGateway)
 	}
 }
 
-func (s *Server) matchRoute(path string) (config.Route, bool) {
-	for _, r := range s.routes {
-		if strings.HasPrefix(path, r.Path) {
-			return r, true
+// matchRoute picks the route whose hosts match host most specifically and,
+// among those, the one with the longest path prefix.
+func (s *Server) matchRoute(host, path string) (config.Route, bool) {
+	var match config.Route
+	best := -1
+	for _, r := range s.state.Load().routes {
+		if rank := r.MatchHost(host); rank > best && strings.HasPrefix(path, r.Path) {
+			match, best = r, rank
 		}
 	}
-	return config.Route{}, false
+	return match, best >= 0
 }
 
 func (s *Server) lookupServer(name string) (config.Server, bool) {
-	for _, server := range s.cfg.Servers {
+	for _, server := range s.config().Servers {
 		if server.Name == name {
 			return server, true
 		}
@@ -107,28 +214,20 @@ func (s *Server) lookupServer(name string) (config.Server, bool) {
 	return config.Server{}, false
 }
 
+// enforceAuth checks r against the auth of route, which is resolved when
+// the config is loaded.
 func (s *Server) enforceAuth(route config.Route, r *http.Request) error {
-	authType := routeAuthType(s.cfg, route)
-	switch authType {
+	auth := route.Auth
+	switch auth.Type {
 	case "none":
 		return nil
 	case "apiKey":
-		header := "X-API-Key"
-		keys := []string{}
-		if route.Auth != nil {
-			if strings.TrimSpace(route.Auth.HeaderName) != "" {
-				header = route.Auth.HeaderName
-			}
-			keys = route.Auth.APIKeys
-		}
-		v := r.Header.Get(header)
+		v := r.Header.Get(auth.HeaderName)
 		if v == "" {
 			return fmt.Errorf("missing API key")
 		}
-		if len(keys) == 0 {
-			return nil
-		}
-		for _, k := range keys {
+		// Without a key list there is nothing to match; fail closed.
+		for _, k := range auth.APIKeys {
 			if v == k {
 				return nil
 			}
@@ -141,23 +240,33 @@ func (s *Server) enforceAuth(route config.Route, r *http.Request) error {
 		}
 		return nil
 	default:
-		return fmt.Errorf("unsupported auth type %q", authType)
+		return fmt.Errorf("unsupported auth type %q", auth.Type)
 	}
 }
 
-func (s *Server) proxyHTTP(route config.Route, rawURL string, w http.ResponseWriter, r *http.Request) {
+func (s *Server) proxyHTTP(route config.Route, rawURL string, body []byte, w http.ResponseWriter, r *http.Request) {
 	target, err := url.Parse(rawURL)
 	if err != nil {
 		http.Error(w, "invalid upstream URL", http.StatusBadGateway)
 		return
 	}
+	if route.Rewrite != nil {
+		r = r.Clone(r.Context())
+		r.URL.Path, r.URL.RawPath = route.UpstreamPath(r.URL.Path), ""
+	}
 	proxy := httputil.NewSingleHostReverseProxy(target)
-	if s.logBodies {
+	rec, sent := s.recorders.get(route), time.Now()
+	if s.logBodies || rec != nil {
 		proxy.ModifyResponse = func(resp *http.Response) error {
-			resp.Body = newLoggingReadCloser(resp.Body, 16*1024, func(preview string, truncated bool) {
-				log.Printf("mcp_response route=%s status=%d content_type=%q body=%q truncated=%t",
-					route.Name, resp.StatusCode, resp.Header.Get("Content-Type"), preview, truncated)
-			})
+			if rec != nil {
+				recordProxied(rec, route.Name, body, sent, resp)
+			}
+			if s.logBodies {
+				resp.Body = newLoggingReadCloser(resp.Body, 16*1024, func(preview string, truncated bool) {
+					log.Printf("mcp_response route=%s status=%d content_type=%q body=%q truncated=%t",
+						route.Name, resp.StatusCode, resp.Header.Get("Content-Type"), preview, truncated)
+				})
+			}
 			return nil
 		}
 	}
@@ -167,33 +276,6 @@ func (s *Server) proxyHTTP(route config.Route, rawURL string, w http.ResponseWri
 	proxy.ServeHTTP(w, r)
 }
 
-func (s *Server) respondNotImplemented(route config.Route, server config.Server, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	_ = json.NewEncoder(w).Encode(map[string]any{
-		"error":     "stdio transport bridge not yet wired",
-		"route":     route.Name,
-		"server":    server.Name,
-		"transport": server.Transport,
-	})
-}
-
-func routeAuthType(cfg *config.Config, route config.Route) string {
-	if route.Auth == nil {
-		if cfg.Auth.RequireAuth {
-			return "apiKey"
-		}
-		return "none"
-	}
-	if strings.TrimSpace(route.Auth.Type) == "" {
-		if cfg.Auth.RequireAuth {
-			return "apiKey"
-		}
-		return "none"
-	}
-	return route.Auth.Type
-}
-
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -212,19 +294,19 @@ func normalizeRequestPath(r *http.Request, routePath string) {
 	}
 }
 
-func (s *Server) captureRequestBody(r *http.Request) (string, bool) {
+func (s *Server) captureRequestBody(r *http.Request) ([]byte, string, bool) {
 	if r.Body == nil {
-		return "", false
+		return nil, "", false
 	}
 	b, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("failed to read request body: %v", err)
-		return "", false
+		return nil, "", false
 	}
 	_ = r.Body.Close()
 	r.Body = io.NopCloser(bytes.NewReader(b))
 	preview, truncated := capBytes(b, 16*1024)
-	return strings.TrimSpace(preview), truncated
+	return b, strings.TrimSpace(preview), truncated
 }
 
 func capBytes(b []byte, max int) (string, bool) {
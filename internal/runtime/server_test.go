@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/djsam/mcp-gateway-envoy/internal/config"
@@ -99,3 +100,120 @@ func TestTrailingSlashNormalized(t *testing.T) {
 		t.Fatalf("unexpected body: %s", string(body))
 	}
 }
+
+func TestReloadSwitchesRoutes(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("upstream-ok"))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: upstream.URL}},
+		Routes:  []config.Route{{Name: "r1", Path: "/mcp/old", Server: "s1"}},
+	}
+	s := NewServer(cfg)
+	next := *cfg
+	next.Routes = []config.Route{{Name: "r1", Path: "/mcp/new", Server: "s1"}}
+	s.Reload(&next)
+
+	for path, want := range map[string]int{"/mcp/old": http.StatusNotFound, "/mcp/new": http.StatusOK} {
+		rr := httptest.NewRecorder()
+		s.handleRequest(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+}
+
+func TestDefaultAuthProvider(t *testing.T) {
+	cfg := &config.Config{
+		Gateway:       config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Auth:          config.AuthDefaults{RequireAuth: true, DefaultProvider: "keys"},
+		AuthProviders: []config.AuthProvider{{Name: "keys", RouteAuth: config.RouteAuth{Type: "apiKey", HeaderName: "X-Key", APIKeys: []string{"secret"}}}},
+		Servers:       []config.Server{{Name: "s1", Transport: "stdio", Command: "echo"}},
+		Routes:        []config.Route{{Name: "r1", Path: "/mcp", Server: "s1"}},
+	}
+	s := NewServer(cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+	req.Header.Set("X-Key", "anything")
+	rr := httptest.NewRecorder()
+	s.handleRequest(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the default provider's key list to reject, got %d", rr.Code)
+	}
+}
+
+func TestRequireAuthWithoutKeysDenies(t *testing.T) {
+	s := NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Auth:    config.AuthDefaults{RequireAuth: true},
+		Servers: []config.Server{{Name: "s1", Transport: "stdio", Command: "echo"}},
+		Routes:  []config.Route{{Name: "r1", Path: "/mcp", Server: "s1"}},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+	req.Header.Set(config.DefaultAPIKeyHeader, "anything")
+	rr := httptest.NewRecorder()
+	s.handleRequest(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected apiKey auth without keys to reject, got %d", rr.Code)
+	}
+}
+
+func TestRouteRejectsUnacceptedHTTPClients(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("upstream-ok"))
+	}))
+	defer upstream.Close()
+
+	s := NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: upstream.URL}},
+		Routes: []config.Route{
+			{Name: "ws", Path: "/mcp/ws", Server: "s1", ClientTransports: []string{"websocket"}},
+			{Name: "legacy", Path: "/mcp/legacy", Server: "s1", ClientTransports: []string{"sse"}},
+		},
+	})
+	for _, path := range []string{"/mcp/ws", "/mcp/legacy"} {
+		rr := httptest.NewRecorder()
+		s.handleRequest(rr, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for a streamable HTTP client, got %d %q", path, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestHostRoutingAndRewrite(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: upstream.URL}},
+		Routes: []config.Route{
+			{Name: "any", Path: "/mcp", Server: "s1"},
+			{Name: "weather", Path: "/mcp", Server: "s1", Hosts: []string{"weather.mcp.example.com"}, Rewrite: &config.Rewrite{Path: "/weather"}},
+			{Name: "fs", Path: "/mcp/fs", Server: "s1", Hosts: []string{"*.mcp.example.com"}, Rewrite: &config.Rewrite{ReplacePrefix: "/mcp"}},
+		},
+	}
+	s := NewServer(cfg)
+
+	for _, tc := range []struct{ host, path, want string }{
+		{"weather.mcp.example.com:8080", "/mcp", "/weather"},
+		{"fs.mcp.example.com", "/mcp/fs/tools", "/mcp/tools"},
+		{"fs.mcp.example.com", "/mcp", "/mcp"},
+		{"other.example.com", "/mcp/fs", "/mcp/fs"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		req.Host = tc.host
+		rr := httptest.NewRecorder()
+		s.handleRequest(rr, req)
+		if rr.Code != http.StatusOK || rr.Body.String() != tc.want {
+			t.Fatalf("%s%s: expected upstream path %s, got %d %q", tc.host, tc.path, tc.want, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,552 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/sandbox"
+)
+
+// session binds one client session to an upstream transport. The gateway owns
+// it so that clients and upstreams can speak different transports.
+type session struct {
+	id        string
+	route     string
+	transport mcp.Transport
+
+	// unsolicited receives upstream messages that no in-flight request is
+	// waiting for: server requests, notifications, and every reply when the
+	// client reads responses from a stream (legacy SSE).
+	unsolicited chan json.RawMessage
+	// lossy drops unsolicited messages nobody is reading instead of stalling
+	// the upstream.
+	lossy bool
+
+	// diagnose explains how the upstream ended, or returns "" (stdio only).
+	diagnose func() string
+
+	// ctx lives as long as the session; replies to send are read on it.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[string]chan json.RawMessage
+	// inflight holds the requests sent with send, so they can still be
+	// answered if the upstream dies first or the route timeout passes.
+	inflight map[string]*inflightRequest
+	// expired holds the ids answered with a timeout; their late replies
+	// are dropped.
+	expired map[string]bool
+	// holders counts the requests and client connections using the
+	// session; lastUsed is when the last one let go.
+	holders  int
+	lastUsed time.Time
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// inflightRequest is a request sent with send that has no reply yet.
+type inflightRequest struct {
+	id    json.RawMessage
+	timer *time.Timer
+}
+
+func newSession(route string, t mcp.Transport, lossy bool) *session {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &session{
+		id:          newSessionID(),
+		route:       route,
+		transport:   t,
+		unsolicited: make(chan json.RawMessage, 64),
+		lossy:       lossy,
+		ctx:         ctx,
+		cancel:      cancel,
+		pending:     map[string]chan json.RawMessage{},
+		inflight:    map[string]*inflightRequest{},
+		expired:     map[string]bool{},
+		lastUsed:    time.Now(),
+		closing:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// dispatch routes upstream messages to waiting requests or the unsolicited
+// queue until the transport ends.
+func (s *session) dispatch(onDone func()) {
+	defer func() {
+		close(s.done)
+		if onDone != nil {
+			onDone()
+		}
+	}()
+	for raw := range s.transport.Messages() {
+		msg, err := mcp.Parse(raw)
+		if err == nil && msg.IsResponse() {
+			key := msg.IDKey()
+			s.mu.Lock()
+			ch, ok := s.pending[key]
+			delete(s.pending, key)
+			if req, sent := s.inflight[key]; sent {
+				req.stop()
+				delete(s.inflight, key)
+			}
+			expired := s.expired[key]
+			delete(s.expired, key)
+			s.mu.Unlock()
+			if ok {
+				ch <- raw
+				continue
+			}
+			if expired {
+				log.Printf("session_drop route=%s session=%s reason=late_reply id=%s", s.route, s.id, key)
+				continue
+			}
+		}
+		if !s.queue(raw) {
+			return
+		}
+	}
+	s.failInflight()
+}
+
+// queue delivers an unsolicited message, reporting false once the session
+// is closing.
+func (s *session) queue(raw json.RawMessage) bool {
+	if s.lossy {
+		select {
+		case s.unsolicited <- raw:
+		default:
+			log.Printf("session_drop route=%s session=%s reason=no_reader", s.route, s.id)
+		}
+		return true
+	}
+	select {
+	case s.unsolicited <- raw:
+		return true
+	case <-s.closing:
+		return false
+	}
+}
+
+// failInflight answers requests the upstream took down with it.
+func (s *session) failInflight() {
+	select {
+	case <-s.closing:
+		return
+	default:
+	}
+	s.mu.Lock()
+	ids := make([]json.RawMessage, 0, len(s.inflight))
+	for key, req := range s.inflight {
+		req.stop()
+		ids = append(ids, req.id)
+		delete(s.inflight, key)
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+	message := "upstream error: " + s.endError().Error()
+	for _, id := range ids {
+		if !s.queue(mcp.ErrorResponse(id, mcp.CodeInternalError, message)) {
+			return
+		}
+	}
+}
+
+// endError explains why the upstream ended.
+func (s *session) endError() error {
+	if s.diagnose != nil {
+		if d := s.diagnose(); d != "" {
+			return errors.New(d)
+		}
+	}
+	if err := s.transport.Err(); err != nil && err != mcp.ErrClosed {
+		return err
+	}
+	return fmt.Errorf("upstream session closed")
+}
+
+// send forwards client messages whose responses are read from unsolicited,
+// remembering requests so a crash can still answer them. Replies are read
+// on the session's context, so they may arrive after send returns; a request
+// left unanswered for timeout (when positive) is answered with an error.
+func (s *session) send(raw json.RawMessage, timeout time.Duration) error {
+	var keys []string
+	if raws, err := mcp.SplitBatch(raw); err == nil {
+		s.mu.Lock()
+		for _, r := range raws {
+			msg, err := mcp.Parse(r)
+			if err != nil || !msg.IsRequest() {
+				continue
+			}
+			key := msg.IDKey()
+			if old, ok := s.inflight[key]; ok {
+				old.stop()
+			}
+			req := &inflightRequest{id: msg.ID}
+			if timeout > 0 {
+				req.timer = time.AfterFunc(timeout, func() { s.expire(key, req) })
+			}
+			s.inflight[key] = req
+			delete(s.expired, key)
+			keys = append(keys, key)
+		}
+		s.mu.Unlock()
+	}
+	err := s.transport.Send(s.ctx, raw)
+	if err != nil {
+		s.mu.Lock()
+		for _, key := range keys {
+			if req, ok := s.inflight[key]; ok {
+				req.stop()
+				delete(s.inflight, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// expire answers req, sent with send, once the route timeout has passed
+// without a reply.
+func (s *session) expire(key string, req *inflightRequest) {
+	s.mu.Lock()
+	if s.inflight[key] != req {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.inflight, key)
+	s.expired[key] = true
+	s.mu.Unlock()
+	s.queue(mcp.ErrorResponse(req.id, mcp.CodeInternalError, "upstream error: "+context.DeadlineExceeded.Error()))
+}
+
+func (r *inflightRequest) stop() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+}
+
+// roundTrip sends a request and waits for the upstream response with the same id.
+func (s *session) roundTrip(ctx context.Context, raw json.RawMessage, key string) (json.RawMessage, error) {
+	ch := make(chan json.RawMessage, 1)
+	s.mu.Lock()
+	s.pending[key] = ch
+	s.mu.Unlock()
+	forget := func() {
+		s.mu.Lock()
+		delete(s.pending, key)
+		s.mu.Unlock()
+	}
+
+	if err := s.transport.Send(ctx, raw); err != nil {
+		forget()
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		forget()
+		return nil, ctx.Err()
+	case <-s.done:
+		forget()
+		return nil, s.endError()
+	}
+}
+
+// hold marks the session in use until the returned func is called, so the
+// store does not reap it.
+func (s *session) hold() (release func()) {
+	s.mu.Lock()
+	s.holders++
+	s.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.holders--
+			s.lastUsed = time.Now()
+			s.mu.Unlock()
+		})
+	}
+}
+
+// idle reports whether nobody has held the session for ttl.
+func (s *session) idle(now time.Time, ttl time.Duration) bool {
+	select {
+	case <-s.closing:
+		return false
+	default:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.holders == 0 && now.Sub(s.lastUsed) >= ttl
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+		_ = s.transport.Close()
+		s.cancel()
+	})
+}
+
+// sessionIdleTTL is how long a session may go unused before it is closed.
+// Streamable HTTP clients can open a session and never DELETE it.
+const sessionIdleTTL = 10 * time.Minute
+
+// sessionStore tracks gateway-held sessions by id and closes the ones left
+// idle for idleTTL.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	idleTTL  time.Duration
+	// reaper runs while the store holds sessions.
+	reaper *time.Timer
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: map[string]*session{}, idleTTL: sessionIdleTTL}
+}
+
+func (st *sessionStore) add(s *session) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sessions[s.id] = s
+	if st.reaper == nil {
+		st.reaper = time.AfterFunc(st.idleTTL/2, st.reap)
+	}
+}
+
+// reap closes idle sessions, rescheduling itself while sessions remain.
+func (st *sessionStore) reap() {
+	now := time.Now()
+	st.mu.Lock()
+	var idle []*session
+	for _, s := range st.sessions {
+		if s.idle(now, st.idleTTL) {
+			idle = append(idle, s)
+		}
+	}
+	if len(st.sessions) > len(idle) {
+		st.reaper.Reset(st.idleTTL / 2)
+	} else {
+		st.reaper = nil
+	}
+	st.mu.Unlock()
+	for _, s := range idle {
+		log.Printf("session_reaped route=%s session=%s reason=idle", s.route, s.id)
+		s.close()
+	}
+}
+
+// closeWhere closes the sessions for which match reports true.
+func (st *sessionStore) closeWhere(match func(*session) bool) {
+	st.mu.Lock()
+	var matched []*session
+	for _, s := range st.sessions {
+		if match(s) {
+			matched = append(matched, s)
+		}
+	}
+	st.mu.Unlock()
+	for _, s := range matched {
+		s.close()
+	}
+}
+
+func (st *sessionStore) get(id string) (*session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.sessions[id]
+	return s, ok
+}
+
+func (st *sessionStore) remove(id string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.sessions, id)
+}
+
+// StdioCommand builds the child process for a stdio server. The child
+// inherits the gateway's environment plus the server's env entries and
+// extra, unless the server is sandboxed, which starts from an allowlist.
+func StdioCommand(gw config.Gateway, server config.Server, extra []string) (*exec.Cmd, error) {
+	env, err := server.ResolveEnv(gw)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, extra...)
+	if server.Sandbox != nil {
+		sb := *server.Sandbox
+		if sb.WorkingDir == "" {
+			sb.WorkingDir = server.WorkingDir
+		}
+		cmd, err := sandbox.Command(sb, env, server.Command, server.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox server %s: %w", server.Name, err)
+		}
+		return cmd, nil
+	}
+	cmd := exec.Command(server.Command, server.Args...)
+	cmd.Dir = server.WorkingDir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd, nil
+}
+
+// startStdio spawns a stdio server for the session opened by r, passing it
+// the request's credentials, and registers it for stderr capture.
+func (s *Server) startStdio(server config.Server, r *http.Request) (mcp.Transport, *stdioProcess, error) {
+	env, params, err := sessionCredentials(server, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd, err := StdioCommand(s.config().Gateway, server, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	proc := &stdioProcess{server: server.Name, startedAt: time.Now()}
+	cmd.Stderr = proc
+	st, err := mcp.StartStdio(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	proc.mu.Lock()
+	proc.pid, proc.transport = st.Pid(), st
+	proc.mu.Unlock()
+	s.stdio.started(proc)
+
+	var t mcp.Transport = &stdioProcessTransport{StdioTransport: st, proc: proc}
+	if len(params) > 0 {
+		t = &initParamsTransport{Transport: t, params: params}
+	}
+	return t, proc, nil
+}
+
+// openSession dials the route's upstream with the transport it speaks and
+// starts dispatching its messages. r is the client request opening the session.
+func (s *Server) openSession(r *http.Request, route config.Route, server config.Server, lossy bool) (*session, error) {
+	ctx := r.Context()
+	var (
+		t    mcp.Transport
+		proc *stdioProcess
+		err  error
+	)
+	if server.Transport == "stdio" {
+		t, proc, err = s.startStdio(server, r)
+	} else {
+		t, err = DialUpstream(ctx, s.config().Gateway, server, route.UpstreamPath(route.Path), s.client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rt *recordingTransport
+	if rec := s.recorders.get(route); rec != nil {
+		rt = newRecordingTransport(t, rec, route.Name, server.Transport == "sse")
+		t = rt
+	}
+
+	sess := newSession(route.Name, t, lossy)
+	if rt != nil {
+		rt.run(sess.id)
+	}
+	if proc != nil {
+		proc.attach(sess.id)
+		sess.diagnose = proc.diagnostic
+	}
+	s.sessions.add(sess)
+	go sess.dispatch(func() { s.sessions.remove(sess.id) })
+	log.Printf("session_open route=%s session=%s upstream=%s", route.Name, sess.id, server.Transport)
+	return sess, nil
+}
+
+// DialUpstream connects to server the way the gateway does for a session on
+// a route at routePath. stdio servers are spawned without per-request
+// credentials and with stderr discarded.
+func DialUpstream(ctx context.Context, gw config.Gateway, server config.Server, routePath string, client *http.Client) (mcp.Transport, error) {
+	var (
+		t   mcp.Transport
+		err error
+	)
+	switch server.Transport {
+	case "http":
+		endpoint, joinErr := JoinUpstreamURL(server.URL, routePath)
+		if joinErr != nil {
+			return nil, joinErr
+		}
+		t = mcp.NewHTTPTransport(endpoint, client, nil)
+	case "sse":
+		t, err = mcp.DialSSE(ctx, server.URL, client, nil)
+	case "websocket":
+		maxBytes, pingInterval := webSocketLimits(gw)
+		t, err = mcp.DialWebSocket(ctx, server.URL, nil, maxBytes, pingInterval)
+	case "stdio":
+		cmd, cmdErr := StdioCommand(gw, server, nil)
+		if cmdErr != nil {
+			return nil, cmdErr
+		}
+		t, err = mcp.StartStdio(cmd)
+	default:
+		err = fmt.Errorf("unsupported server transport %q", server.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// JoinUpstreamURL mirrors the reverse proxy: the route's upstream path is
+// appended to the server URL path.
+func JoinUpstreamURL(rawURL, routePath string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid upstream URL: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(routePath, "/")
+	return u.String(), nil
+}
+
+// routeContext applies the route timeout to a single upstream exchange.
+func routeContext(ctx context.Context, route config.Route) (context.Context, context.CancelFunc) {
+	if timeout := routeTimeout(route); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// routeTimeout is the route's upstream timeout, or 0 for none.
+func routeTimeout(route config.Route) time.Duration {
+	return time.Duration(route.Policy.TimeoutMs) * time.Millisecond
+}
+
+func writeJSONRPCError(w http.ResponseWriter, status int, id json.RawMessage, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(mcp.ErrorResponse(id, code, message))
+}
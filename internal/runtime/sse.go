@@ -0,0 +1,212 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+const sseKeepAlive = 25 * time.Second
+
+// serveLegacyStream handles GET <route>/sse for legacy HTTP+SSE clients. The
+// gateway opens an upstream session in whatever transport the server speaks
+// and relays every upstream message onto the event stream.
+func (s *Server) serveLegacyStream(route config.Route, server config.Server, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	sess, err := s.openSession(r, route, server, false)
+	if err != nil {
+		http.Error(w, sessionErrorMessage(err), sessionErrorStatus(err))
+		return
+	}
+	defer sess.close()
+	defer sess.hold()()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	endpoint := route.Path + "/messages?sessionId=" + sess.id
+	if err := mcp.WriteEvent(w, mcp.Event{Event: "endpoint", Data: []byte(endpoint)}); err != nil {
+		return
+	}
+	s.pumpEvents(sess, w, r)
+}
+
+// serveLegacyMessage handles POST <route>/messages?sessionId=... by forwarding
+// the message into the session opened by serveLegacyStream. Replies are
+// delivered on the event stream, so the POST itself is only acknowledged.
+func (s *Server) serveLegacyMessage(route config.Route, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.sessions.get(r.URL.Query().Get("sessionId"))
+	if !ok || sess.route != route.Name {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	defer sess.hold()()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	if err := sess.send(body, routeTimeout(route)); err != nil {
+		http.Error(w, "upstream error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte("Accepted"))
+}
+
+// bridgeSession serves streamable HTTP clients on routes whose upstream needs
+// a gateway-held session (for example a legacy SSE-only server). The gateway
+// issues its own Mcp-Session-Id and correlates responses by JSON-RPC id.
+func (s *Server) bridgeSession(route config.Route, server config.Server, w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(mcp.SessionHeader)
+	switch r.Method {
+	case http.MethodDelete:
+		if sess, ok := s.sessions.get(id); ok && sess.route == route.Name {
+			sess.close()
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodGet:
+		sess, ok := s.sessions.get(id)
+		if !ok || sess.route != route.Name {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		if _, ok := w.(http.Flusher); !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		defer sess.hold()()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		s.pumpEvents(sess, w, r)
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONRPCError(w, http.StatusBadRequest, nil, mcp.CodeParseError, "read body")
+		return
+	}
+	raws, err := mcp.SplitBatch(body)
+	if err != nil {
+		writeJSONRPCError(w, http.StatusBadRequest, nil, mcp.CodeParseError, err.Error())
+		return
+	}
+	msgs := make([]mcp.Message, len(raws))
+	initialize := false
+	for i, raw := range raws {
+		if msgs[i], err = mcp.Parse(raw); err != nil {
+			writeJSONRPCError(w, http.StatusBadRequest, nil, mcp.CodeParseError, err.Error())
+			return
+		}
+		initialize = initialize || msgs[i].Method == "initialize"
+	}
+
+	var sess *session
+	if id == "" {
+		if !initialize {
+			writeJSONRPCError(w, http.StatusBadRequest, msgs[0].ID, mcp.CodeInvalidRequest, "missing "+mcp.SessionHeader)
+			return
+		}
+		if sess, err = s.openSession(r, route, server, true); err != nil {
+			writeJSONRPCError(w, sessionErrorStatus(err), msgs[0].ID, mcp.CodeInternalError, sessionErrorMessage(err))
+			return
+		}
+	} else {
+		var ok bool
+		if sess, ok = s.sessions.get(id); !ok || sess.route != route.Name {
+			writeJSONRPCError(w, http.StatusNotFound, msgs[0].ID, mcp.CodeInvalidRequest, "unknown session")
+			return
+		}
+	}
+	defer sess.hold()()
+
+	ctx, cancel := routeContext(r.Context(), route)
+	defer cancel()
+	var responses []json.RawMessage
+	for i, raw := range raws {
+		if !msgs[i].IsRequest() {
+			if err := sess.transport.Send(ctx, raw); err != nil {
+				writeJSONRPCError(w, http.StatusBadGateway, nil, mcp.CodeInternalError, "upstream error: "+err.Error())
+				return
+			}
+			continue
+		}
+		resp, err := sess.roundTrip(ctx, raw, msgs[i].IDKey())
+		if err != nil {
+			resp = mcp.ErrorResponse(msgs[i].ID, mcp.CodeInternalError, "upstream error: "+err.Error())
+		}
+		responses = append(responses, resp)
+	}
+
+	w.Header().Set(mcp.SessionHeader, sess.id)
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(raws) == 1 {
+		_, _ = w.Write(responses[0])
+		return
+	}
+	_ = json.NewEncoder(w).Encode(responses)
+}
+
+// pumpEvents writes unsolicited session messages to an SSE response until
+// the client disconnects or the upstream session ends.
+func (s *Server) pumpEvents(sess *session, w http.ResponseWriter, r *http.Request) {
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-sess.unsolicited:
+			if err := mcp.WriteEvent(w, mcp.Event{Event: "message", Data: msg}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		case <-sess.done:
+			for {
+				select {
+				case msg := <-sess.unsolicited:
+					if err := mcp.WriteEvent(w, mcp.Event{Event: "message", Data: msg}); err != nil {
+						return
+					}
+				default:
+					log.Printf("session_closed route=%s session=%s", sess.route, sess.id)
+					return
+				}
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+func TestLegacySSEClientToStreamableUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mcp" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		msg, _ := mcp.Parse(mustReadAll(t, r.Body))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"echo":%q}}`, msg.ID, msg.Method)
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(http.HandlerFunc(NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: upstream.URL}},
+		Routes:  []config.Route{{Name: "r1", Path: "/mcp", Server: "s1", ClientTransports: []string{"http", "sse"}}},
+	}).handleRequest))
+	defer gw.Close()
+
+	resp, err := http.Get(gw.URL + "/mcp/sse")
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	events := mcp.NewEventReader(resp.Body)
+	ev, err := events.Next()
+	if err != nil || ev.Event != "endpoint" {
+		t.Fatalf("expected endpoint event, got %+v err=%v", ev, err)
+	}
+
+	post, err := http.Post(gw.URL+string(ev.Data), "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	if err != nil {
+		t.Fatalf("post message: %v", err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", post.StatusCode)
+	}
+
+	ev, err = events.Next()
+	if err != nil {
+		t.Fatalf("read message event: %v", err)
+	}
+	if ev.Event != "message" || !strings.Contains(string(ev.Data), `"echo":"tools/list"`) {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+// streamingUpstream is a streamable HTTP server that answers every request
+// on an SSE stream, after the headers and a pause, except "slow", which it
+// never answers.
+func streamingUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg, _ := mcp.Parse(mustReadAll(t, r.Body))
+		if !msg.IsRequest() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if msg.Method == "slow" {
+			<-r.Context().Done()
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		_ = mcp.WriteEvent(w, mcp.Event{Event: "message", Data: []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"echo":%q}}`, msg.ID, msg.Method))})
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestLegacySSEClientGetsStreamedReply(t *testing.T) {
+	upstream := streamingUpstream(t)
+	gw := httptest.NewServer(http.HandlerFunc(NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: upstream.URL}},
+		Routes: []config.Route{{
+			Name: "r1", Path: "/mcp", Server: "s1", ClientTransports: []string{"sse"},
+			Policy: config.RoutePolicy{TimeoutMs: 500},
+		}},
+	}).handleRequest))
+	defer gw.Close()
+
+	// A lost reply fails the read instead of hanging the test.
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(gw.URL + "/mcp/sse")
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	events := mcp.NewEventReader(resp.Body)
+	ev, err := events.Next()
+	if err != nil || ev.Event != "endpoint" {
+		t.Fatalf("expected endpoint event, got %+v err=%v", ev, err)
+	}
+	for _, tc := range []struct{ req, want string }{
+		{`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, `"echo":"tools/list"`},
+		{`{"jsonrpc":"2.0","id":2,"method":"slow"}`, `"error"`},
+	} {
+		post, err := http.Post(gw.URL+string(ev.Data), "application/json", strings.NewReader(tc.req))
+		if err != nil {
+			t.Fatalf("post message: %v", err)
+		}
+		post.Body.Close()
+		msg, err := events.Next()
+		if err != nil || !strings.Contains(string(msg.Data), tc.want) {
+			t.Fatalf("%s: expected %s on the stream, got %q err=%v", tc.req, tc.want, msg.Data, err)
+		}
+	}
+}
+
+func TestStreamableClientToLegacySSEUpstream(t *testing.T) {
+	streams := make(chan chan []byte, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/sse":
+			out := make(chan []byte, 4)
+			streams <- out
+			w.Header().Set("Content-Type", "text/event-stream")
+			_ = mcp.WriteEvent(w, mcp.Event{Event: "endpoint", Data: []byte("/messages?sessionId=abc")})
+			for {
+				select {
+				case msg := <-out:
+					_ = mcp.WriteEvent(w, mcp.Event{Event: "message", Data: msg})
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/messages":
+			msg, _ := mcp.Parse(mustReadAll(t, r.Body))
+			w.WriteHeader(http.StatusAccepted)
+			if msg.IsRequest() {
+				out := <-streams
+				streams <- out
+				out <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"method":%q}}`, msg.ID, msg.Method))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	gw := httptest.NewServer(http.HandlerFunc(NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "legacy", Transport: "sse", URL: upstream.URL + "/sse"}},
+		Routes:  []config.Route{{Name: "r1", Path: "/mcp", Server: "legacy"}},
+	}).handleRequest))
+	defer gw.Close()
+
+	resp := postJSON(t, gw.URL+"/mcp", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	sessionID := resp.Header.Get(mcp.SessionHeader)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("expected 200 with session id, got %d %q", resp.StatusCode, sessionID)
+	}
+	var init mcp.Message
+	if err := json.Unmarshal(mustReadAll(t, resp.Body), &init); err != nil || string(init.ID) != "1" {
+		t.Fatalf("unexpected initialize response: %+v err=%v", init, err)
+	}
+
+	resp = postJSON(t, gw.URL+"/mcp", sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for notification, got %d", resp.StatusCode)
+	}
+
+	resp = postJSON(t, gw.URL+"/mcp", sessionID, `{"jsonrpc":"2.0","id":"two","method":"tools/list"}`)
+	body := string(mustReadAll(t, resp.Body))
+	if !strings.Contains(body, `"id":"two"`) || !strings.Contains(body, `"method":"tools/list"`) {
+		t.Fatalf("unexpected tools/list response: %s", body)
+	}
+
+	resp = postJSON(t, gw.URL+"/mcp", "unknown", `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown session, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, gw.URL+"/mcp", nil)
+	req.Header.Set(mcp.SessionHeader, sessionID)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	del.Body.Close()
+	if del.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 for session delete, got %d", del.StatusCode)
+	}
+}
+
+// closingSSEUpstream is a legacy SSE server that answers on the latest
+// stream and reports on closed when a stream's client goes away.
+func closingSSEUpstream(t *testing.T) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+	streams := make(chan chan []byte, 1)
+	closed := make(chan struct{}, 4)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			out := make(chan []byte, 4)
+			select {
+			case <-streams:
+			default:
+			}
+			streams <- out
+			w.Header().Set("Content-Type", "text/event-stream")
+			_ = mcp.WriteEvent(w, mcp.Event{Event: "endpoint", Data: []byte("/messages")})
+			for {
+				select {
+				case msg := <-out:
+					_ = mcp.WriteEvent(w, mcp.Event{Event: "message", Data: msg})
+				case <-r.Context().Done():
+					closed <- struct{}{}
+					return
+				}
+			}
+		}
+		msg, _ := mcp.Parse(mustReadAll(t, r.Body))
+		w.WriteHeader(http.StatusAccepted)
+		if msg.IsRequest() {
+			out := <-streams
+			streams <- out
+			out <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{}}`, msg.ID))
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream, closed
+}
+
+func TestAbandonedSessionsAreClosed(t *testing.T) {
+	upstream, closed := closingSSEUpstream(t)
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "legacy", Transport: "sse", URL: upstream.URL + "/sse"}},
+		Routes:  []config.Route{{Name: "r1", Path: "/mcp", Server: "legacy"}},
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	waitClosed := func(why string) {
+		t.Helper()
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the upstream stream closed %s", why)
+		}
+	}
+
+	idle := NewServer(cfg)
+	idle.sessions.idleTTL = 100 * time.Millisecond
+	gw := httptest.NewServer(http.HandlerFunc(idle.handleRequest))
+	defer gw.Close()
+	if resp := postJSON(t, gw.URL+"/mcp", "", initialize); resp.Header.Get(mcp.SessionHeader) == "" {
+		t.Fatalf("expected a session, got %d", resp.StatusCode)
+	}
+	waitClosed("once the session sat idle")
+
+	reloaded := NewServer(cfg)
+	gw2 := httptest.NewServer(http.HandlerFunc(reloaded.handleRequest))
+	defer gw2.Close()
+	if resp := postJSON(t, gw2.URL+"/mcp", "", initialize); resp.Header.Get(mcp.SessionHeader) == "" {
+		t.Fatalf("expected a session, got %d", resp.StatusCode)
+	}
+	next := *cfg
+	next.Routes = []config.Route{{Name: "r2", Path: "/mcp2", Server: "legacy"}}
+	reloaded.Reload(&next)
+	waitClosed("when a reload removed its route")
+}
+
+func postJSON(t *testing.T, url, sessionID, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if sessionID != "" {
+		req.Header.Set(mcp.SessionHeader, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func mustReadAll(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,277 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+const (
+	// stderrRingLines bounds the stderr lines kept per process.
+	stderrRingLines = 200
+	// stderrMaxLine truncates very long stderr lines.
+	stderrMaxLine = 4096
+	// exitedProcessesKept bounds the exited processes kept per server for
+	// the admin API.
+	exitedProcessesKept = 5
+	// diagnosticLines is how much stderr a crash report sent to clients quotes.
+	diagnosticLines = 3
+)
+
+// stdioProcess is one spawned stdio server. It is the child's stderr: each
+// line is logged and kept in a ring buffer for the admin API and crash
+// reports.
+type stdioProcess struct {
+	server    string
+	transport *mcp.StdioTransport
+	startedAt time.Time
+
+	mu       sync.Mutex
+	pid      int
+	session  string
+	attached bool
+	partial  []byte
+	lines    []string
+	next     int
+	closing  bool
+	exited   bool
+	exitCode int
+	exitedAt time.Time
+}
+
+func (p *stdioProcess) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partial = append(p.partial, b...)
+	for {
+		i := strings.IndexByte(string(p.partial), '\n')
+		if i < 0 {
+			break
+		}
+		p.addLine(string(p.partial[:i]))
+		p.partial = p.partial[i+1:]
+	}
+	if len(p.partial) > stderrMaxLine {
+		p.addLine(string(p.partial))
+		p.partial = nil
+	}
+	return len(b), nil
+}
+
+// addLine stores a line and logs it once the process is attached to its
+// session; earlier lines are logged by attach.
+func (p *stdioProcess) addLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if len(line) > stderrMaxLine {
+		line = line[:stderrMaxLine]
+	}
+	if len(p.lines) < stderrRingLines {
+		p.lines = append(p.lines, line)
+	} else {
+		p.lines[p.next] = line
+		p.next = (p.next + 1) % stderrRingLines
+	}
+	if p.attached {
+		p.log(line)
+	}
+}
+
+func (p *stdioProcess) log(line string) {
+	log.Printf("stdio_stderr server=%s pid=%d session=%s line=%q", p.server, p.pid, p.session, line)
+}
+
+// attach records the session the process serves and flushes the stderr
+// lines written before it was known.
+func (p *stdioProcess) attach(session string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.session = session
+	p.attached = true
+	for _, line := range p.tailLocked(len(p.lines)) {
+		p.log(line)
+	}
+}
+
+// tail returns up to n of the most recent stderr lines, oldest first.
+func (p *stdioProcess) tail(n int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tailLocked(n)
+}
+
+func (p *stdioProcess) tailLocked(n int) []string {
+	ordered := append(append([]string(nil), p.lines[p.next:]...), p.lines[:p.next]...)
+	if n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// diagnostic describes how the process ended, quoting its last stderr lines,
+// or returns "" while it is still running.
+func (p *stdioProcess) diagnostic() string {
+	select {
+	case <-p.transport.Exited():
+	default:
+		return ""
+	}
+	p.mu.Lock()
+	pid := p.pid
+	p.mu.Unlock()
+	msg := fmt.Sprintf("stdio server %s (pid %d) was killed", p.server, pid)
+	if code := p.transport.ExitCode(); code >= 0 {
+		msg = fmt.Sprintf("stdio server %s (pid %d) exited with status %d", p.server, pid, code)
+	}
+	var quoted []string
+	for _, line := range p.tail(diagnosticLines) {
+		if len(line) > 200 {
+			line = line[:200] + "..."
+		}
+		quoted = append(quoted, line)
+	}
+	if len(quoted) > 0 {
+		msg += "; stderr: " + strings.Join(quoted, " | ")
+	}
+	return msg
+}
+
+// stdioProcessTransport marks the process as stopped by the gateway when
+// its session closes, so the exit is not counted as a crash.
+type stdioProcessTransport struct {
+	*mcp.StdioTransport
+	proc *stdioProcess
+}
+
+func (t *stdioProcessTransport) Close() error {
+	t.proc.mu.Lock()
+	t.proc.closing = true
+	t.proc.mu.Unlock()
+	return t.StdioTransport.Close()
+}
+
+// stdioRegistry tracks spawned stdio servers for the admin API.
+type stdioRegistry struct {
+	mu      sync.Mutex
+	servers map[string]*stdioServerState
+}
+
+type stdioServerState struct {
+	// crashed is set while the latest process ended without the gateway
+	// stopping it; the next start then counts as a restart.
+	crashed   bool
+	crashes   int
+	restarts  int
+	processes []*stdioProcess
+}
+
+func newStdioRegistry() *stdioRegistry {
+	return &stdioRegistry{servers: map[string]*stdioServerState{}}
+}
+
+// started registers a running process and watches for its exit.
+func (reg *stdioRegistry) started(p *stdioProcess) {
+	reg.mu.Lock()
+	st := reg.servers[p.server]
+	if st == nil {
+		st = &stdioServerState{}
+		reg.servers[p.server] = st
+	}
+	if st.crashed {
+		st.restarts++
+		st.crashed = false
+	}
+	st.processes = append(st.processes, p)
+	reg.mu.Unlock()
+
+	go func() {
+		<-p.transport.Exited()
+		code := p.transport.ExitCode()
+		p.mu.Lock()
+		p.exited, p.exitCode, p.exitedAt = true, code, time.Now()
+		crashed, pid, session := !p.closing, p.pid, p.session
+		p.mu.Unlock()
+		log.Printf("stdio_exit server=%s pid=%d session=%s code=%d crashed=%t", p.server, pid, session, code, crashed)
+		reg.exited(p, crashed)
+	}()
+}
+
+func (reg *stdioRegistry) exited(p *stdioProcess, crashed bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	st := reg.servers[p.server]
+	if crashed {
+		st.crashes++
+		st.crashed = true
+	}
+	// Keep running processes and the most recent exited ones.
+	var kept []*stdioProcess
+	exited := 0
+	for i := len(st.processes) - 1; i >= 0; i-- {
+		q := st.processes[i]
+		q.mu.Lock()
+		done := q.exited
+		q.mu.Unlock()
+		if done {
+			if exited++; exited > exitedProcessesKept {
+				continue
+			}
+		}
+		kept = append([]*stdioProcess{q}, kept...)
+	}
+	st.processes = kept
+}
+
+// stdioProcessStatus is one process as served by /admin/stdio.
+type stdioProcessStatus struct {
+	PID       int        `json:"pid"`
+	Session   string     `json:"session,omitempty"`
+	Running   bool       `json:"running"`
+	ExitCode  *int       `json:"exitCode,omitempty"`
+	StartedAt time.Time  `json:"startedAt"`
+	ExitedAt  *time.Time `json:"exitedAt,omitempty"`
+	Stderr    []string   `json:"stderr"`
+}
+
+type stdioServerStatus struct {
+	Name      string               `json:"name"`
+	Restarts  int                  `json:"restarts"`
+	Crashes   int                  `json:"crashes"`
+	Processes []stdioProcessStatus `json:"processes"`
+}
+
+// handler serves the state of every stdio server the gateway has spawned.
+func (reg *stdioRegistry) handler(w http.ResponseWriter, _ *http.Request) {
+	reg.mu.Lock()
+	names := make([]string, 0, len(reg.servers))
+	for name := range reg.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []stdioServerStatus{}
+	for _, name := range names {
+		st := reg.servers[name]
+		status := stdioServerStatus{Name: name, Restarts: st.restarts, Crashes: st.crashes, Processes: []stdioProcessStatus{}}
+		for _, p := range st.processes {
+			p.mu.Lock()
+			ps := stdioProcessStatus{PID: p.pid, Session: p.session, Running: !p.exited, StartedAt: p.startedAt, Stderr: p.tailLocked(len(p.lines))}
+			if p.exited {
+				code, at := p.exitCode, p.exitedAt
+				ps.ExitCode, ps.ExitedAt = &code, &at
+			}
+			p.mu.Unlock()
+			status.Processes = append(status.Processes, ps)
+		}
+		out = append(out, status)
+	}
+	reg.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"servers": out})
+}
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+)
+
+func TestStdioCrashReport(t *testing.T) {
+	s := NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{helperStdioServer(t, "local")},
+		Routes:  []config.Route{{Name: "fs", Path: "/mcp/fs", Server: "local"}},
+	})
+	gw := httptest.NewServer(http.HandlerFunc(s.handleRequest))
+	defer gw.Close()
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	sessionID := postJSON(t, gw.URL+"/mcp/fs", "", initialize).Header.Get("Mcp-Session-Id")
+	body := string(mustReadAll(t, postJSON(t, gw.URL+"/mcp/fs", sessionID, `{"jsonrpc":"2.0","id":2,"method":"crash"}`).Body))
+	if !strings.Contains(body, `"code":-32603`) || !strings.Contains(body, "exited with status 3") || !strings.Contains(body, "panic: boom") {
+		t.Fatalf("expected a JSON-RPC error with a crash diagnostic, got %s", body)
+	}
+
+	adminStatus := func() (status struct{ Servers []stdioServerStatus }) {
+		rec := httptest.NewRecorder()
+		s.stdio.handler(rec, httptest.NewRequest(http.MethodGet, "/admin/stdio", nil))
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+	// The exit is recorded asynchronously; wait for it before restarting.
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if st := adminStatus(); len(st.Servers) == 1 && st.Servers[0].Crashes == 1 {
+			break
+		}
+	}
+	postJSON(t, gw.URL+"/mcp/fs", "", initialize)
+
+	status := adminStatus()
+	if len(status.Servers) != 1 || status.Servers[0].Crashes != 1 || status.Servers[0].Restarts != 1 || len(status.Servers[0].Processes) != 2 {
+		t.Fatalf("expected one crash and one restart, got %+v", status)
+	}
+	crashed := status.Servers[0].Processes[0]
+	if crashed.Running || crashed.ExitCode == nil || *crashed.ExitCode != 3 || crashed.Session != sessionID ||
+		len(crashed.Stderr) == 0 || crashed.Stderr[len(crashed.Stderr)-1] != "panic: boom" {
+		t.Fatalf("unexpected crashed process status: %+v", crashed)
+	}
+}
+
+// TestStdioExitRacesAttach attaches a session while the exit watcher is
+// still reporting the exit; run it with -race.
+func TestStdioExitRacesAttach(t *testing.T) {
+	t.Setenv("GATEWAY_TEST_STDIO_SERVER", "1")
+	reg := newStdioRegistry()
+	p := &stdioProcess{server: "quick", startedAt: time.Now()}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperStdioServer$")
+	cmd.Stderr = p
+	st, err := mcp.StartStdio(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.pid, p.transport = st.Pid(), st
+	p.mu.Unlock()
+	reg.started(p)
+
+	// Holding the registry parks the watcher after it logs the exit, so
+	// the attach below is not ordered after that log line.
+	reg.mu.Lock()
+	_ = st.Close() // the helper exits when its stdin closes
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		p.mu.Lock()
+		exited := p.exited
+		p.mu.Unlock()
+		if exited {
+			break
+		}
+		if time.Now().After(deadline) {
+			reg.mu.Unlock()
+			t.Fatal("process did not exit")
+		}
+	}
+	p.attach("late")
+	reg.mu.Unlock()
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/websocket"
+)
+
+const (
+	defaultWebSocketMaxMessageBytes = websocket.DefaultMaxMessageBytes
+	defaultWebSocketPingInterval    = 30 * time.Second
+)
+
+// serveWebSocket upgrades a client connection on a route accepting websocket
+// and bridges it to the route's upstream, one JSON-RPC message per frame.
+// Policy is applied to every client message, not only to the upgrade, using
+// the route as the running config has it; a reload that removes the route or
+// changes its auth closes the connection with 1008.
+func (s *Server) serveWebSocket(route config.Route, server config.Server, w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, "mcp")
+	if err != nil {
+		log.Printf("websocket_upgrade_failed route=%s err=%v", route.Name, err)
+		return
+	}
+	maxBytes, pingInterval := webSocketLimits(s.config().Gateway)
+	conn.SetReadLimit(maxBytes)
+
+	sess, err := s.openSession(r, route, server, false)
+	if err != nil {
+		log.Printf("websocket_upstream_failed route=%s err=%v", route.Name, err)
+		if sessionErrorStatus(err) == http.StatusUnauthorized {
+			_ = conn.CloseWith(websocket.ClosePolicy, err.Error())
+			return
+		}
+		_ = conn.CloseWith(1011, "upstream unavailable")
+		return
+	}
+	defer sess.close()
+	defer sess.hold()()
+	go func() {
+		if err := conn.KeepAlive(pingInterval); err != nil {
+			log.Printf("websocket_keepalive route=%s session=%s err=%v", route.Name, sess.id, err)
+			sess.close()
+		}
+	}()
+
+	// Upstream -> client.
+	go func() {
+		for {
+			select {
+			case msg := <-sess.unsolicited:
+				s.metrics.inc("gateway_messages_total", "route", route.Name, "transport", "websocket", "direction", "upstream")
+				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					sess.close()
+					return
+				}
+			case <-sess.done:
+				if _, ok := s.liveRoute(route); !ok {
+					_ = conn.CloseWith(websocket.ClosePolicy, "route removed")
+					return
+				}
+				_ = conn.CloseWith(websocket.CloseGoingAway, "upstream session ended")
+				return
+			}
+		}
+	}()
+
+	// Client -> upstream.
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if !errors.As(err, &ce) {
+				log.Printf("websocket_read route=%s session=%s err=%v", route.Name, sess.id, err)
+			}
+			_ = conn.Close()
+			return
+		}
+		s.metrics.inc("gateway_messages_total", "route", route.Name, "transport", "websocket", "direction", "client")
+		if s.logBodies {
+			preview, truncated := capBytes(msg, 16*1024)
+			log.Printf("mcp_request route=%s transport=websocket body=%q truncated=%t", route.Name, preview, truncated)
+		}
+		current, ok := s.liveRoute(route)
+		if !ok {
+			s.metrics.inc("gateway_policy_denials_total", "route", route.Name, "reason", "auth")
+			_ = conn.CloseWith(websocket.ClosePolicy, "route auth changed")
+			return
+		}
+		if resp, ok := s.admitMessage(current, msg); !ok {
+			if resp != nil {
+				_ = conn.WriteMessage(websocket.TextMessage, resp)
+			}
+			continue
+		}
+		if err := sess.send(msg, routeTimeout(current)); err != nil {
+			log.Printf("websocket_upstream_send route=%s session=%s err=%v", route.Name, sess.id, err)
+			_ = conn.CloseWith(1011, "upstream error")
+			return
+		}
+	}
+}
+
+func webSocketLimits(gw config.Gateway) (int64, time.Duration) {
+	maxBytes := int64(defaultWebSocketMaxMessageBytes)
+	if v := gw.WebSocket.MaxMessageBytes; v > 0 {
+		maxBytes = v
+	}
+	interval := defaultWebSocketPingInterval
+	if v := gw.WebSocket.PingIntervalMs; v > 0 {
+		interval = time.Duration(v) * time.Millisecond
+	}
+	return maxBytes, interval
+}
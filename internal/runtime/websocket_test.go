@@ -0,0 +1,221 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+	"github.com/djsam/mcp-gateway-envoy/internal/mcp"
+	"github.com/djsam/mcp-gateway-envoy/internal/websocket"
+)
+
+// TestHelperStdioServer is not a real test: when GATEWAY_TEST_STDIO_SERVER is
+// set it runs as a stdio MCP server that echoes each request's method, its
+// params, $TEST_TOKEN and its working directory. The method "crash" makes
+// it write to stderr and exit with status 3.
+func TestHelperStdioServer(t *testing.T) {
+	if os.Getenv("GATEWAY_TEST_STDIO_SERVER") != "1" {
+		t.Skip("helper process")
+	}
+	wd, _ := os.Getwd()
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		msg, err := mcp.Parse(scanner.Bytes())
+		if err != nil || !msg.IsRequest() {
+			continue
+		}
+		if msg.Method == "crash" {
+			fmt.Fprintln(os.Stderr, "panic: boom")
+			os.Exit(3)
+		}
+		params := msg.Params
+		if len(params) == 0 {
+			params = []byte("null")
+		}
+		fmt.Printf(`{"jsonrpc":"2.0","id":%s,"result":{"method":%q,"params":%s,"token":%q,"cwd":%q}}`+"\n",
+			msg.ID, msg.Method, params, os.Getenv("TEST_TOKEN"), wd)
+	}
+	os.Exit(0)
+}
+
+func helperStdioServer(t *testing.T, name string) config.Server {
+	t.Helper()
+	t.Setenv("GATEWAY_TEST_STDIO_SERVER", "1")
+	return config.Server{
+		Name:      name,
+		Transport: "stdio",
+		Command:   os.Args[0],
+		Args:      []string{"-test.run=^TestHelperStdioServer$"},
+	}
+}
+
+func TestWebSocketClientToStdioUpstream(t *testing.T) {
+	s := NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{helperStdioServer(t, "local")},
+		Routes: []config.Route{{
+			Name:             "fs",
+			Path:             "/mcp/fs",
+			Server:           "local",
+			ClientTransports: []string{"websocket"},
+			Policy:           config.RoutePolicy{AllowedTools: []string{"read_file"}},
+		}},
+	})
+	gw := httptest.NewServer(http.HandlerFunc(s.handleRequest))
+	defer gw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(gw.URL, "http")+"/mcp/fs", nil)
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	defer conn.Close()
+
+	exchange := func(req string) string {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(req)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		_, resp, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return string(resp)
+	}
+
+	if resp := exchange(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`); !strings.Contains(resp, `"method":"initialize"`) {
+		t.Fatalf("unexpected initialize response: %s", resp)
+	}
+	if resp := exchange(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete_everything"}}`); !strings.Contains(resp, `"code":-32001`) {
+		t.Fatalf("expected policy denial, got: %s", resp)
+	}
+	if resp := exchange(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"read_file"}}`); !strings.Contains(resp, `"method":"tools/call"`) {
+		t.Fatalf("expected allowed tool call to reach upstream, got: %s", resp)
+	}
+
+	if got := s.metrics.get("gateway_policy_denials_total", "route", "fs", "reason", "tool"); got != 1 {
+		t.Fatalf("expected 1 tool denial, got %d", got)
+	}
+	if got := s.metrics.get("gateway_messages_total", "route", "fs", "transport", "websocket", "direction", "client"); got != 3 {
+		t.Fatalf("expected 3 client messages, got %d", got)
+	}
+}
+
+func TestStreamableClientToStdioUpstream(t *testing.T) {
+	gw := httptest.NewServer(http.HandlerFunc(NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{helperStdioServer(t, "local")},
+		Routes:  []config.Route{{Name: "fs", Path: "/mcp/fs", Server: "local"}},
+	}).handleRequest))
+	defer gw.Close()
+
+	resp := postJSON(t, gw.URL+"/mcp/fs", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	sessionID := resp.Header.Get(mcp.SessionHeader)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("expected 200 with session id, got %d %q", resp.StatusCode, sessionID)
+	}
+	resp = postJSON(t, gw.URL+"/mcp/fs", sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if body := string(mustReadAll(t, resp.Body)); !strings.Contains(body, `"method":"tools/list"`) {
+		t.Fatalf("unexpected tools/list response: %s", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, gw.URL+"/mcp/fs", nil)
+	req.Header.Set(mcp.SessionHeader, sessionID)
+	if del, err := http.DefaultClient.Do(req); err == nil {
+		del.Body.Close()
+	}
+}
+
+func TestWebSocketClientGetsStreamedReply(t *testing.T) {
+	upstream := streamingUpstream(t)
+	gw := httptest.NewServer(http.HandlerFunc(NewServer(&config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{{Name: "s1", Transport: "http", URL: upstream.URL}},
+		Routes: []config.Route{{
+			Name: "r1", Path: "/mcp", Server: "s1", ClientTransports: []string{"websocket"},
+			Policy: config.RoutePolicy{TimeoutMs: 500},
+		}},
+	}).handleRequest))
+	defer gw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(gw.URL, "http")+"/mcp", nil)
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	defer conn.Close()
+	// A lost reply fails the read instead of hanging the test.
+	time.AfterFunc(5*time.Second, func() { _ = conn.Close() })
+	for _, tc := range []struct{ req, want string }{
+		{`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, `"echo":"tools/list"`},
+		{`{"jsonrpc":"2.0","id":2,"method":"slow"}`, `"error"`},
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(tc.req)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		_, resp, err := conn.ReadMessage()
+		if err != nil || !strings.Contains(string(resp), tc.want) {
+			t.Fatalf("%s: expected %s, got %q err=%v", tc.req, tc.want, resp, err)
+		}
+	}
+}
+
+func TestWebSocketClosedWhenReloadRevokesKey(t *testing.T) {
+	cfg := &config.Config{
+		Gateway: config.Gateway{Name: "gw", ListenAddr: ":0"},
+		Servers: []config.Server{helperStdioServer(t, "local")},
+		Routes: []config.Route{{
+			Name:             "fs",
+			Path:             "/mcp/fs",
+			Server:           "local",
+			ClientTransports: []string{"websocket"},
+			Auth:             &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"k1", "k2"}},
+		}},
+	}
+	s := NewServer(cfg)
+	gw := httptest.NewServer(s.Handler())
+	defer gw.Close()
+
+	if resp, err := http.Get(gw.URL + "/metrics"); err != nil || resp.StatusCode == http.StatusOK {
+		t.Fatalf("expected /metrics to stay off the MCP port, got %v err=%v", resp, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(gw.URL, "http")+"/mcp/fs", http.Header{"X-API-Key": {"k1"}})
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	defer conn.Close()
+	time.AfterFunc(5*time.Second, func() { _ = conn.Close() })
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, resp, err := conn.ReadMessage(); err != nil || !strings.Contains(string(resp), `"method":"initialize"`) {
+		t.Fatalf("unexpected initialize response: %q err=%v", resp, err)
+	}
+
+	revoked := *cfg
+	revoked.Routes = []config.Route{cfg.Routes[0]}
+	revoked.Routes[0].Auth = &config.RouteAuth{Type: "apiKey", HeaderName: "X-API-Key", APIKeys: []string{"k2"}}
+	s.Reload(&revoked)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var ce *websocket.CloseError
+	if _, resp, err := conn.ReadMessage(); !errors.As(err, &ce) || ce.Code != websocket.ClosePolicy {
+		t.Fatalf("expected close 1008 after the key was revoked, got %q err=%v", resp, err)
+	}
+}
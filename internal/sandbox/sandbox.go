@@ -0,0 +1,32 @@
+// Package sandbox starts stdio server processes with a reduced environment,
+// another uid/gid, resource limits, Linux namespaces and a seccomp filter.
+//
+// Limits and the seccomp filter must be applied between fork and exec, which
+// os/exec cannot do, so the child first runs the gateway binary itself as a
+// small helper that applies them and then execs the server. Programs that use
+// Command must call Init at the very start of main (or TestMain).
+package sandbox
+
+import (
+	"os"
+	"strings"
+)
+
+// specEnv carries the helper's instructions; the helper strips it before
+// exec so the server never sees it.
+const specEnv = "GATEWAY_SANDBOX_SPEC"
+
+// allowedEnv returns the entries of the current environment named in allow.
+func allowedEnv(allow []string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, a := range allow {
+			if name == a {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
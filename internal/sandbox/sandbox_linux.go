@@ -0,0 +1,179 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+// rlimitNproc is RLIMIT_NPROC, which the syscall package does not export.
+const rlimitNproc = 6
+
+// spec is what the helper applies before exec.
+type spec struct {
+	Path      string   `json:"path"`
+	UID       *int     `json:"uid,omitempty"`
+	GID       *int     `json:"gid,omitempty"`
+	Rlimits   []rlimit `json:"rlimits,omitempty"`
+	MountProc bool     `json:"mountProc,omitempty"`
+	Seccomp   bool     `json:"seccomp,omitempty"`
+}
+
+type rlimit struct {
+	Resource int    `json:"resource"`
+	Value    uint64 `json:"value"`
+}
+
+// Command returns a command that runs name with args confined by sb. The
+// child's environment is sb.EnvAllowlist taken from the gateway's, plus env.
+func Command(sb config.Sandbox, env []string, name string, args ...string) (*exec.Cmd, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locate sandbox helper: %w", err)
+	}
+
+	s := spec{Path: path, UID: sb.User, GID: sb.Group, Seccomp: sb.Seccomp == "default"}
+	if s.Seccomp && !seccompSupported {
+		return nil, fmt.Errorf("seccomp filtering is not supported on %s", runtime.GOARCH)
+	}
+	if n := sb.Limits.CPUSeconds; n > 0 {
+		s.Rlimits = append(s.Rlimits, rlimit{syscall.RLIMIT_CPU, uint64(n)})
+	}
+	if n := sb.Limits.OpenFiles; n > 0 {
+		s.Rlimits = append(s.Rlimits, rlimit{syscall.RLIMIT_NOFILE, uint64(n)})
+	}
+	if n := sb.Limits.Processes; n > 0 {
+		s.Rlimits = append(s.Rlimits, rlimit{rlimitNproc, uint64(n)})
+	}
+	if sb.Limits.Memory != "" {
+		n, err := config.ParseBytes(sb.Limits.Memory)
+		if err != nil {
+			return nil, err
+		}
+		// Last, so the helper itself is not squeezed before exec.
+		s.Rlimits = append(s.Rlimits, rlimit{syscall.RLIMIT_AS, uint64(n)})
+	}
+
+	attr := &syscall.SysProcAttr{}
+	namespaces := map[string]bool{}
+	for _, ns := range sb.Namespaces {
+		namespaces[ns] = true
+	}
+	if namespaces["mount"] {
+		// As an unshare flag, os/exec also makes the new mount tree private
+		// so nothing the child mounts propagates back to the host.
+		attr.Unshareflags |= syscall.CLONE_NEWNS
+	}
+	if namespaces["pid"] {
+		attr.Cloneflags |= syscall.CLONE_NEWPID
+	}
+	if namespaces["network"] {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	s.MountProc = namespaces["mount"] && namespaces["pid"]
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	cmd := &exec.Cmd{
+		Path:        self,
+		Args:        append([]string{name}, args...),
+		Env:         append(append(allowedEnv(sb.EnvAllowlist), env...), specEnv+"="+string(b)),
+		Dir:         sb.WorkingDir,
+		SysProcAttr: attr,
+	}
+	return cmd, nil
+}
+
+// Init turns the process into the sandbox helper when it was started by
+// Command; it only returns in the normal, non-helper case.
+func Init() {
+	raw, ok := os.LookupEnv(specEnv)
+	if !ok {
+		return
+	}
+	err := confine(raw)
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// confine applies the spec and execs the server; it returns only on error.
+func confine(raw string) error {
+	var s spec
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return fmt.Errorf("decode spec: %w", err)
+	}
+	// no_new_privs and seccomp are per thread; exec from the same one.
+	runtime.LockOSThread()
+
+	if s.MountProc {
+		// The helper is pid 1 of the new pid namespace; give it a /proc
+		// that shows only that namespace.
+		if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+			return fmt.Errorf("mount /proc: %w", err)
+		}
+	}
+	for _, l := range s.Rlimits {
+		if l.Resource == syscall.RLIMIT_AS {
+			continue
+		}
+		if err := syscall.Setrlimit(l.Resource, &syscall.Rlimit{Cur: l.Value, Max: l.Value}); err != nil {
+			return fmt.Errorf("set rlimit %d: %w", l.Resource, err)
+		}
+	}
+	// A uid without a gid runs with the gid of the same number, so the
+	// child never keeps the gateway's group or supplementary groups.
+	gid := s.GID
+	if gid == nil {
+		gid = s.UID
+	}
+	if gid != nil {
+		if err := syscall.Setgroups(nil); err != nil {
+			return fmt.Errorf("drop supplementary groups: %w", err)
+		}
+		if err := syscall.Setgid(*gid); err != nil {
+			return fmt.Errorf("setgid %d: %w", *gid, err)
+		}
+	}
+	if s.UID != nil {
+		if err := syscall.Setuid(*s.UID); err != nil {
+			return fmt.Errorf("setuid %d: %w", *s.UID, err)
+		}
+	}
+	if err := setNoNewPrivs(); err != nil {
+		return err
+	}
+	if s.Seccomp {
+		if err := installSeccomp(); err != nil {
+			return err
+		}
+	}
+	for _, l := range s.Rlimits {
+		if l.Resource == syscall.RLIMIT_AS {
+			if err := syscall.Setrlimit(l.Resource, &syscall.Rlimit{Cur: l.Value, Max: l.Value}); err != nil {
+				return fmt.Errorf("set memory limit: %w", err)
+			}
+		}
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, specEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	return syscall.Exec(s.Path, os.Args, env)
+}
@@ -0,0 +1,109 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestCommandConfinesChild(t *testing.T) {
+	t.Setenv("SANDBOX_TEST_SECRET", "leak")
+	dir := t.TempDir()
+	sb := config.Sandbox{
+		EnvAllowlist: []string{"PATH"},
+		WorkingDir:   dir,
+		Limits:       config.Rlimits{OpenFiles: 64, Memory: "1Gi"},
+		Seccomp:      "default",
+	}
+	nobody := 65534
+	if os.Geteuid() == 0 {
+		sb.User, sb.Group = &nobody, &nobody
+	}
+	script := `echo "files=$(ulimit -n)"; echo "secret=$SANDBOX_TEST_SECRET"; echo "extra=$EXTRA"; echo "dir=$(pwd)"; echo "uid=$(id -u)"; grep '^Seccomp:' /proc/self/status`
+	cmd, err := Command(sb, []string{"EXTRA=1"}, "sh", "-c", script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v\n%s", err, out)
+	}
+	got := string(out)
+	for _, want := range []string{"files=64\n", "secret=\n", "extra=1\n", "dir=" + dir + "\n", "Seccomp:\t2\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output:\n%s", want, got)
+		}
+	}
+	if sb.User != nil && !strings.Contains(got, "uid=65534\n") {
+		t.Fatalf("expected the child to run as nobody:\n%s", got)
+	}
+}
+
+func TestUserWithoutGroupDropsGroups(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching user needs root")
+	}
+	nobody := 65534
+	sb := config.Sandbox{EnvAllowlist: []string{"PATH"}, User: &nobody}
+	cmd, err := Command(sb, nil, "id", "-G")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "65534" {
+		t.Fatalf("expected only gid 65534, got %q", got)
+	}
+}
+
+// TestHelperNamespaces is not a real test: when SANDBOX_TEST_NAMESPACES is
+// set it tries to create namespaces with clone and unshare and prints the
+// errors.
+func TestHelperNamespaces(t *testing.T) {
+	if os.Getenv("SANDBOX_TEST_NAMESPACES") != "1" {
+		t.Skip("helper process")
+	}
+	for name, attr := range map[string]*syscall.SysProcAttr{
+		"clone":   {Cloneflags: syscall.CLONE_NEWUTS},
+		"unshare": {Unshareflags: syscall.CLONE_NEWUTS},
+	} {
+		cmd := exec.Command("true")
+		cmd.SysProcAttr = attr
+		fmt.Printf("%s=%v\n", name, errors.Is(cmd.Run(), syscall.EPERM))
+	}
+	fmt.Printf("fork=%v\n", exec.Command("true").Run() == nil)
+	os.Exit(0)
+}
+
+func TestSeccompBlocksNamespaces(t *testing.T) {
+	if os.Geteuid() != 0 || !seccompSupported {
+		t.Skip("needs root and a seccomp filter for this architecture")
+	}
+	sb := config.Sandbox{EnvAllowlist: []string{"PATH"}, Seccomp: "default"}
+	cmd, err := Command(sb, []string{"SANDBOX_TEST_NAMESPACES=1"}, os.Args[0], "-test.run=^TestHelperNamespaces$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{"clone=true\n", "unshare=true\n", "fork=true\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
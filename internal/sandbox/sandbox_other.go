@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/djsam/mcp-gateway-envoy/internal/config"
+)
+
+// Init is a no-op outside Linux.
+func Init() {}
+
+// Command reports that sandboxing is unsupported on this platform.
+func Command(sb config.Sandbox, env []string, name string, args ...string) (*exec.Cmd, error) {
+	return nil, errors.New("stdio server sandbox requires Linux")
+}
@@ -0,0 +1,25 @@
+package sandbox
+
+const (
+	seccompSupported = true
+	auditArch        = 0xc000003e // AUDIT_ARCH_X86_64
+	// x32SyscallBit marks x32 ABI syscall numbers, which bypass a filter
+	// written for x86_64 numbers unless rejected.
+	x32SyscallBit = 0x40000000
+)
+
+// deniedSyscalls adds numbers newer than the syscall package.
+var deniedSyscalls = append(commonDenied,
+	303, // name_to_handle_at
+	304, // open_by_handle_at
+	305, // clock_adjtime
+	308, // setns
+	310, // process_vm_readv
+	311, // process_vm_writev
+	313, // finit_module
+	320, // kexec_file_load
+	321, // bpf
+	323, // userfaultfd
+	173, // ioperm
+	172, // iopl
+)
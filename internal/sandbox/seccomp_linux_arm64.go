@@ -0,0 +1,21 @@
+package sandbox
+
+const (
+	seccompSupported = true
+	auditArch        = 0xc00000b7 // AUDIT_ARCH_AARCH64
+	x32SyscallBit    = 0
+)
+
+// deniedSyscalls adds numbers newer than the syscall package.
+var deniedSyscalls = append(commonDenied,
+	264, // name_to_handle_at
+	265, // open_by_handle_at
+	266, // clock_adjtime
+	268, // setns
+	270, // process_vm_readv
+	271, // process_vm_writev
+	273, // finit_module
+	280, // bpf
+	282, // userfaultfd
+	294, // kexec_file_load
+)
@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64
+
+package sandbox
+
+const (
+	seccompSupported = false
+	auditArch        = 0
+	x32SyscallBit    = 0
+)
+
+var deniedSyscalls []int
@@ -0,0 +1,304 @@
+// Package websocket implements the subset of RFC 6455 the gateway needs to
+// carry JSON-RPC messages: text/binary messages, fragmentation, ping/pong and
+// close, with a bounded message size.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message opcodes.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Close status codes used by the gateway.
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	ClosePolicy        = 1008
+	CloseTooLarge      = 1009
+)
+
+// DefaultMaxMessageBytes bounds a reassembled message when no limit is set.
+const DefaultMaxMessageBytes = 1 << 20
+
+var (
+	// ErrMessageTooLarge is returned when a peer exceeds the read limit.
+	ErrMessageTooLarge = errors.New("websocket: message exceeds read limit")
+	// ErrKeepAliveTimeout is reported when the peer stops answering pings.
+	ErrKeepAliveTimeout = errors.New("websocket: keepalive timeout")
+)
+
+// CloseError reports a close frame received from the peer.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed by peer (%d %s)", e.Code, e.Reason)
+}
+
+// Conn is a websocket connection. Reads must come from a single goroutine;
+// writes are serialized internally.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isClient bool
+
+	readLimit int64
+	lastSeen  atomic.Int64
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(c net.Conn, br *bufio.Reader, isClient bool) *Conn {
+	wc := &Conn{
+		conn:      c,
+		br:        br,
+		isClient:  isClient,
+		readLimit: DefaultMaxMessageBytes,
+		closed:    make(chan struct{}),
+	}
+	wc.lastSeen.Store(time.Now().UnixNano())
+	return wc
+}
+
+// SetReadLimit bounds the size of a reassembled message.
+func (c *Conn) SetReadLimit(n int64) {
+	if n > 0 {
+		c.readLimit = n
+	}
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and
+// pongs recorded transparently; a close frame is echoed and reported as
+// *CloseError.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		msgType int
+		payload []byte
+	)
+	for {
+		fin, opcode, data, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		c.lastSeen.Store(time.Now().UnixNano())
+
+		switch opcode {
+		case PingMessage:
+			if err := c.writeFrame(PongMessage, data); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			ce := &CloseError{Code: 1005}
+			if len(data) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(data))
+				ce.Reason = string(data[2:])
+			}
+			_ = c.writeClose(CloseNormal, "")
+			_ = c.conn.Close()
+			return 0, nil, ce
+		case TextMessage, BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message before previous finished")
+			}
+			msgType = opcode
+		case 0:
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if int64(len(payload)+len(data)) > c.readLimit {
+			_ = c.fail(CloseTooLarge, "message too large")
+			return 0, nil, ErrMessageTooLarge
+		}
+		payload = append(payload, data...)
+		if fin {
+			return msgType, payload, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err = io.ReadFull(c.br, hdr[:]); err != nil {
+		return
+	}
+	fin = hdr[0]&0x80 != 0
+	opcode = int(hdr[0] & 0x0f)
+	masked := hdr[1]&0x80 != 0
+	length := int64(hdr[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	// RFC 6455 requires the most significant bit of a 64-bit length to be 0.
+	if length < 0 {
+		err = c.fail(CloseProtocolError, "invalid payload length")
+		return
+	}
+	if opcode >= CloseMessage && (length > 125 || !fin) {
+		err = c.fail(CloseProtocolError, "invalid control frame")
+		return
+	}
+	if length > c.readLimit {
+		_ = c.fail(CloseTooLarge, "message too large")
+		err = ErrMessageTooLarge
+		return
+	}
+	if masked == c.isClient {
+		err = c.fail(CloseProtocolError, "bad frame masking")
+		return
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// WriteMessage sends data as a single text or binary frame.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	return c.writeFrame(messageType, data)
+}
+
+// Ping sends a ping control frame.
+func (c *Conn) Ping(data []byte) error {
+	return c.writeFrame(PingMessage, data)
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.closed:
+		return net.ErrClosed
+	default:
+	}
+
+	buf := make([]byte, 0, len(data)+14)
+	buf = append(buf, 0x80|byte(opcode))
+	maskBit := byte(0)
+	if c.isClient {
+		maskBit = 0x80
+	}
+	switch n := len(data); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if c.isClient {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, data...)
+		for i := range data {
+			buf[start+i] ^= mask[i%4]
+		}
+	} else {
+		buf = append(buf, data...)
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *Conn) writeClose(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	err := c.writeFrame(CloseMessage, payload)
+	c.closeOnce.Do(func() { close(c.closed) })
+	return err
+}
+
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.writeClose(code, reason)
+	_ = c.conn.Close()
+	return fmt.Errorf("websocket: %s", reason)
+}
+
+// KeepAlive pings the peer every interval and closes the connection when no
+// frame has arrived for two intervals. It returns when the connection closes.
+func (c *Conn) KeepAlive(interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return nil
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastSeen.Load())) > 2*interval {
+				_ = c.fail(CloseGoingAway, "keepalive timeout")
+				return ErrKeepAliveTimeout
+			}
+			if err := c.Ping(nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// CloseWith sends a close frame with code and reason and closes the connection.
+func (c *Conn) CloseWith(code int, reason string) error {
+	_ = c.writeClose(code, reason)
+	return c.conn.Close()
+}
+
+// Close performs a normal closure.
+func (c *Conn) Close() error {
+	return c.CloseWith(CloseNormal, "")
+}
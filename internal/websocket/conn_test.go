@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEchoRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, "mcp")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(64)
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Ping([]byte("hi")); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte(`{"jsonrpc":"2.0"}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	typ, msg, err := conn.ReadMessage()
+	if err != nil || typ != TextMessage || string(msg) != `{"jsonrpc":"2.0"}` {
+		t.Fatalf("unexpected echo: type=%d msg=%q err=%v", typ, msg, err)
+	}
+
+	if err := conn.WriteMessage(TextMessage, []byte(strings.Repeat("x", 100))); err != nil {
+		t.Fatalf("write oversized: %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	var ce *CloseError
+	if !errors.As(err, &ce) || ce.Code != CloseTooLarge {
+		t.Fatalf("expected close %d for oversized message, got %v", CloseTooLarge, err)
+	}
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+	rr := httptest.NewRecorder()
+	if _, err := Upgrade(rr, httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Fatal("expected error for non-upgrade request")
+	}
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestReadRejectsNegativeLength(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := newConn(server, bufio.NewReader(server), false)
+
+	closeFrame := make(chan []byte, 1)
+	go func() {
+		// A masked text frame whose 64-bit length has the top bit set.
+		_, _ = client.Write([]byte{0x81, 0x80 | 127, 0x80, 0, 0, 0, 0, 0, 0, 1, 1, 2, 3, 4})
+		frame, _ := io.ReadAll(client)
+		closeFrame <- frame
+	}()
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("expected an error for a negative payload length")
+	}
+	frame := <-closeFrame
+	if len(frame) < 4 || frame[0]&0x0f != CloseMessage || binary.BigEndian.Uint16(frame[2:]) != CloseProtocolError {
+		t.Fatalf("expected close %d, got frame %x", CloseProtocolError, frame)
+	}
+}